@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"loan-service/core/auth"
+	"loan-service/core/loan"
+)
+
+// principalKey is the gin context key under which authenticate stores the caller.
+const principalKey = "auth.principal"
+
+// authenticate requires a valid bearer token on every request and remembers who sent it.
+// Without a verifier authentication is disabled: requests stay anonymous and every guard lets them through.
+func authenticate(verifier *auth.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Next()
+			return
+		}
+
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		p, err := verifier.Verify(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// principal returns the authenticated caller; ok is false when authentication is disabled.
+func principal(c *gin.Context) (p auth.Principal, ok bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	return v.(auth.Principal), true
+}
+
+// allow only lets callers holding one of the roles through. Without roles, any authenticated caller may pass.
+func allow(roles ...auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p, ok := principal(c); ok && len(roles) > 0 && !p.HasAny(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed for your role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// self stops callers with role from acting on anybody but themselves: the path parameter must be their subject.
+// Callers allowed in with another role, such as admins, are not restricted.
+func self(role auth.Role, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p, ok := principal(c); ok && p.Has(role) && c.Param(param) != p.Subject {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "you can only act on your own " + string(role) + " account"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// staffRoles are the roles of employees, who may read every loan and document.
+var staffRoles = []auth.Role{auth.FieldValidator, auth.FieldOfficer, auth.Admin}
+
+// partyTo reports whether p is the borrower of ln or one of its investors.
+func partyTo(p auth.Principal, ln *loan.Loan) bool {
+	return (p.Has(auth.Borrower) && ln.BorrowerID == p.Subject) || (p.Has(auth.Investor) && ln.HasInvestor(p.Subject))
+}
+
+// mayRead reports whether the caller may read ln and what hangs off it (schedule, payouts, history).
+// Staff and anonymous callers may read every loan; borrowers and investors the loans they are party to.
+// Investors may also read loans open for funding, since they need them, and their ETag, to invest.
+func mayRead(c *gin.Context, ln *loan.Loan) bool {
+	p, ok := principal(c)
+	return !ok || p.HasAny(staffRoles...) || partyTo(p, ln) || (p.Has(auth.Investor) && ln.State == loan.Approved)
+}
+
+// readable limits a loan search to what the caller may read, the way mayRead does for a single loan.
+// It returns nil, for no limit, to staff and anonymous callers.
+func readable(c *gin.Context) *loan.Party {
+	p, ok := principal(c)
+	if !ok || p.HasAny(staffRoles...) {
+		return nil
+	}
+	party := &loan.Party{}
+	if p.Has(auth.Borrower) {
+		party.BorrowerID = p.Subject
+	}
+	if p.Has(auth.Investor) {
+		party.InvestorID = p.Subject
+	}
+	return party
+}
+
+// actingAs records the authenticated caller as the actor of a change in the audit trail.
+func actingAs(c *gin.Context) []loan.Option {
+	if p, ok := principal(c); ok {
+		return []loan.Option{loan.WithActor(p.Subject)}
+	}
+	return nil
+}
+
+// staffID returns the employee making a change: the authenticated caller, or the ID given in the
+// request when authentication is disabled.
+func staffID(c *gin.Context, given string) string {
+	if p, ok := principal(c); ok {
+		return p.Subject
+	}
+	return given
+}
+
+// onBehalfOf returns the borrower or investor a request is made for. A caller holding role always acts for
+// themselves, whatever the request says; other callers (admins) and anonymous requests use the given ID.
+func onBehalfOf(c *gin.Context, role auth.Role, given string) string {
+	if p, ok := principal(c); ok && p.Has(role) {
+		return p.Subject
+	}
+	return given
+}
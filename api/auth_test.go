@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/agreement"
+	"loan-service/core/auth"
+	"loan-service/core/document"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func setupRouterWithAuth(t *testing.T) (*gin.Engine, *loan.LoanService) {
+	verifier, err := auth.NewHS256Verifier(testSecret)
+	require.NoError(t, err)
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender())
+	return SetupRouter(NewHandler(svc, WithAuth(verifier))), svc
+}
+
+// bearer returns an Authorization header value for a caller with the given subject and roles.
+func bearer(t *testing.T, subject string, roles ...auth.Role) string {
+	token, err := auth.SignHS256(auth.Claims{Roles: roles, RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}, testSecret)
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestAuth(t *testing.T) {
+	router, svc := setupRouterWithAuth(t)
+	send := func(method, path, authorization string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) loan.Loan {
+		var ln loan.Loan
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ln), w.Body.String())
+		return ln
+	}
+	var (
+		borrower  = bearer(t, "B100", auth.Borrower)
+		validator = bearer(t, "EMP100", auth.FieldValidator)
+		officer   = bearer(t, "FO100", auth.FieldOfficer)
+		investor  = bearer(t, "INV100", auth.Investor)
+		other     = bearer(t, "INV101", auth.Investor)
+		admin     = bearer(t, "ADM100", auth.Admin)
+	)
+	create := gin.H{"borrower_id": "B999", "principal_amount": 1000, "rate": 10, "roi": 8}
+	approve := gin.H{"photo_proof_url": "proof", "field_validator_id": "EMP999", "approval_date": time.Now().Format("2006-01-02")}
+
+	t.Run("requires a valid bearer token", func(t *testing.T) {
+		w := send("GET", "/loans", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+		w = send("GET", "/loans", "Bearer not-a-token", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "invalid_token")
+
+		assert.Equal(t, http.StatusUnauthorized, send("GET", "/loans", "Basic dXNlcjpwYXNz", nil).Code)
+		assert.Equal(t, http.StatusOK, send("GET", "/loans", "bearer "+borrower[len("Bearer "):], nil).Code)
+	})
+
+	t.Run("guards routes by role", func(t *testing.T) {
+		ln, err := svc.CreateLoan("B100", idr(1000), 10, 8)
+		require.NoError(t, err)
+		for _, tc := range []struct {
+			method, path, caller string
+		}{
+			{"POST", "/loans", investor},
+			{"POST", "/loans/" + ln.ID + "/approve", borrower},
+			{"POST", "/loans/" + ln.ID + "/approve", admin},
+			{"POST", "/loans/" + ln.ID + "/invest", validator},
+			{"POST", "/loans/" + ln.ID + "/disburse", validator},
+			{"POST", "/loans/" + ln.ID + "/reject", officer},
+			{"POST", "/loans/" + ln.ID + "/expire", validator},
+			{"GET", "/notifications", officer},
+			{"GET", "/investors/INV100/payouts", borrower},
+		} {
+			w := send(tc.method, tc.path, tc.caller, gin.H{})
+			assert.Equal(t, http.StatusForbidden, w.Code, "%s %s", tc.method, tc.path)
+		}
+		assert.Equal(t, http.StatusOK, send("GET", "/loans/"+ln.ID, borrower, nil).Code)
+		assert.Equal(t, http.StatusOK, send("GET", "/notifications", admin, nil).Code)
+	})
+
+	t.Run("reads are limited to parties to the loan", func(t *testing.T) {
+		ln, err := svc.CreateLoan("B100", idr(1000), 10, 8)
+		require.NoError(t, err)
+		reads := []string{"/loans/" + ln.ID, "/loans/" + ln.ID + "/history", "/loans/" + ln.ID + "/payouts"}
+		readable := func(caller string) bool {
+			t.Helper()
+			codes := make(map[int]bool)
+			for _, path := range reads {
+				codes[send("GET", path, caller, nil).Code] = true
+			}
+			require.Len(t, codes, 1, "every read answers the same")
+			return codes[http.StatusOK]
+		}
+
+		assert.True(t, readable(borrower), "own loan")
+		assert.False(t, readable(bearer(t, "B101", auth.Borrower)), "another borrower's loan")
+		assert.False(t, readable(investor), "not open for funding yet")
+		assert.True(t, readable(validator))
+		assert.True(t, readable(officer))
+		assert.True(t, readable(admin))
+		assert.Equal(t, http.StatusForbidden, send("GET", "/loans/"+ln.ID+"/schedule", other, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/loans/missing", investor, nil).Code)
+
+		_, err = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP100", ApprovalDate: time.Now()})
+		require.NoError(t, err)
+		assert.True(t, readable(other), "open for funding")
+
+		_, err = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV100", Amount: idr(1000)})
+		require.NoError(t, err)
+		assert.True(t, readable(investor), "invested in it")
+		assert.False(t, readable(other), "funded by others")
+	})
+
+	t.Run("takes the actor from the token", func(t *testing.T) {
+		w := send("POST", "/loans", borrower, create)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		ln := decode(t, w)
+		assert.Equal(t, "B100", ln.BorrowerID, "borrower_id in the body is ignored")
+
+		w = send("POST", "/loans/"+ln.ID+"/approve", validator, approve)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "EMP100", decode(t, w).Approval.ValidatorID)
+
+		w = send("POST", "/loans/"+ln.ID+"/invest", investor, gin.H{"investor_id": "INV999", "amount": 1000})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "INV100", decode(t, w).Investors[0].ID)
+
+		w = send("POST", "/loans/"+ln.ID+"/disburse", officer, gin.H{
+			"agreement_letter_file": "signed.pdf", "disbursement_date": time.Now().Format("2006-01-02"),
+			"agreement_letter_link": "https://docs/agreement.pdf",
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "FO100", decode(t, w).Disbursement.FieldOfficerID)
+
+		history, err := svc.GetHistory(ln.ID)
+		require.NoError(t, err)
+		var actors []string
+		for _, e := range history {
+			actors = append(actors, e.Actor)
+		}
+		assert.Equal(t, []string{"B100", "EMP100", "INV100", "FO100"}, actors)
+	})
+
+	t.Run("admins act on behalf of borrowers and investors", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/loans", admin, gin.H{"principal_amount": 1000, "rate": 10, "roi": 8}).Code)
+
+		w := send("POST", "/loans", admin, create)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		ln := decode(t, w)
+		assert.Equal(t, "B999", ln.BorrowerID)
+
+		require.Equal(t, http.StatusOK, send("POST", "/loans/"+ln.ID+"/approve", validator, approve).Code)
+		require.Equal(t, http.StatusOK, send("POST", "/loans/"+ln.ID+"/invest", investor, gin.H{"amount": 400}).Code)
+
+		assert.Equal(t, http.StatusForbidden, send("DELETE", "/loans/"+ln.ID+"/investments/INV100", other, nil).Code)
+		assert.Equal(t, http.StatusForbidden, send("GET", "/investors/INV100/payouts", other, nil).Code)
+		assert.Equal(t, http.StatusOK, send("GET", "/investors/INV100/payouts", investor, nil).Code)
+		assert.Equal(t, http.StatusOK, send("DELETE", "/loans/"+ln.ID+"/investments/INV100", admin, nil).Code)
+
+		history, err := svc.GetHistory(ln.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "ADM100", history[0].Actor)
+		assert.Equal(t, "ADM100", history[len(history)-1].Actor)
+	})
+
+	t.Run("idempotency keys belong to the caller", func(t *testing.T) {
+		post := func(caller string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(create)
+			req, _ := http.NewRequest("POST", "/loans", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", caller)
+			req.Header.Set(IdempotencyKeyHeader, "same-key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		first := post(borrower)
+		require.Equal(t, http.StatusCreated, first.Code)
+		assert.Equal(t, "true", post(borrower).Header().Get("Idempotent-Replayed"))
+
+		w := post(bearer(t, "B101", auth.Borrower))
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, "B101", decode(t, w).BorrowerID)
+	})
+}
+
+func TestAuth_ListLoans(t *testing.T) {
+	router, svc := setupRouterWithAuth(t)
+	list := func(authorization, params string) (int, []string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", "/loans"+params, nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var page struct {
+			Loans []loan.Loan `json:"loans"`
+			Total int         `json:"total"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page), w.Body.String())
+		ids := make([]string, len(page.Loans))
+		for i, ln := range page.Loans {
+			ids[i] = ln.ID
+		}
+		assert.Equal(t, len(ids), page.Total, "the total only counts what the caller may see")
+		return w.Code, ids
+	}
+	approval := loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP1", ApprovalDate: time.Now()}
+
+	own, err := svc.CreateLoan("B1", idr(1000), 10, 8)
+	require.NoError(t, err)
+	open, err := svc.CreateLoan("B1", idr(1000), 10, 8)
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(open.ID, approval)
+	require.NoError(t, err)
+	funded, err := svc.CreateLoan("B2", idr(1000), 10, 8)
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(funded.ID, approval)
+	require.NoError(t, err)
+	_, err = svc.InvestLoan(funded.ID, loan.Investor{ID: "INV1", Amount: idr(1000)})
+	require.NoError(t, err)
+
+	code, ids := list(bearer(t, "B999", auth.Borrower), "?borrower_id=B1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, ids, "another borrower's loans")
+	_, ids = list(bearer(t, "B1", auth.Borrower), "")
+	assert.Equal(t, []string{own.ID, open.ID}, ids)
+	_, ids = list(bearer(t, "B2", auth.Borrower), "?borrower_id=B1")
+	assert.Empty(t, ids, "borrower_id cannot widen the search")
+
+	_, ids = list(bearer(t, "INV1", auth.Investor), "")
+	assert.Equal(t, []string{open.ID, funded.ID}, ids, "open for funding, or invested in")
+	_, ids = list(bearer(t, "INV2", auth.Investor), "")
+	assert.Equal(t, []string{open.ID}, ids)
+
+	for _, staff := range []string{bearer(t, "EMP1", auth.FieldValidator), bearer(t, "ADM1", auth.Admin)} {
+		_, ids = list(staff, "")
+		assert.Equal(t, []string{own.ID, open.ID, funded.ID}, ids)
+	}
+}
+
+func TestAuth_Documents(t *testing.T) {
+	verifier, err := auth.NewHS256Verifier(testSecret)
+	require.NoError(t, err)
+	docs := document.NewService(document.NewInMemoryStore())
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender(), loan.WithDocumentStore(docs))
+	router := SetupRouter(NewHandler(svc, WithAuth(verifier), WithDocuments(docs)))
+
+	proof, err := docs.Upload("proof.pdf", strings.NewReader(pdf), "EMP100")
+	require.NoError(t, err)
+	unused, err := docs.Upload("other.pdf", strings.NewReader(pdf), "EMP100")
+	require.NoError(t, err)
+	ln, err := svc.CreateLoan("B100", idr(1000), 10, 8)
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: proof.ID, ValidatorID: "EMP100", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV100", Amount: idr(400)})
+	require.NoError(t, err)
+
+	get := func(path, caller string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", caller)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	for _, tc := range []struct {
+		name   string
+		caller string
+		doc    string
+		want   int
+	}{
+		{"borrower of the loan", bearer(t, "B100", auth.Borrower), proof.ID, http.StatusOK},
+		{"investor in the loan", bearer(t, "INV100", auth.Investor), proof.ID, http.StatusOK},
+		{"staff", bearer(t, "FO100", auth.FieldOfficer), proof.ID, http.StatusOK},
+		{"staff, document of no loan", bearer(t, "ADM100", auth.Admin), unused.ID, http.StatusOK},
+		{"another borrower", bearer(t, "B101", auth.Borrower), proof.ID, http.StatusForbidden},
+		{"another investor", bearer(t, "INV101", auth.Investor), proof.ID, http.StatusForbidden},
+		{"borrower, document of no loan", bearer(t, "B100", auth.Borrower), unused.ID, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, get("/documents/"+tc.doc, tc.caller))
+			assert.Equal(t, tc.want, get("/documents/"+tc.doc+"/content", tc.caller))
+		})
+	}
+}
+
+func TestAuth_AgreementLetter(t *testing.T) {
+	verifier, err := auth.NewHS256Verifier(testSecret)
+	require.NoError(t, err)
+	docs := document.NewService(document.NewInMemoryStore())
+	letters := agreement.NewGenerator(docs, agreement.WithBaseURL("https://loans.example.com"))
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender(),
+		loan.WithDocumentStore(docs), loan.WithAgreementGenerator(letters))
+	router := SetupRouter(NewHandler(svc, WithAuth(verifier), WithDocuments(docs)))
+
+	proof, err := docs.Upload("proof.pdf", strings.NewReader(pdf), "EMP100")
+	require.NoError(t, err)
+	ln, err := svc.CreateLoan("B100", idr(1000), 10, 8)
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: proof.ID, ValidatorID: "EMP100", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	ln, err = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV100", Amount: idr(1000)})
+	require.NoError(t, err)
+	path, ok := strings.CutPrefix(ln.AgreementLetterURL, "https://loans.example.com")
+	require.True(t, ok, ln.AgreementLetterURL)
+
+	for _, tc := range []struct {
+		name   string
+		caller string
+		want   int
+	}{
+		{"borrower of the loan", bearer(t, "B100", auth.Borrower), http.StatusOK},
+		{"investor in the loan", bearer(t, "INV100", auth.Investor), http.StatusOK},
+		{"another borrower", bearer(t, "B101", auth.Borrower), http.StatusForbidden},
+		{"another investor", bearer(t, "INV101", auth.Investor), http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", path, nil)
+			req.Header.Set("Authorization", tc.caller)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.want, w.Code, w.Body.String())
+			if tc.want == http.StatusOK {
+				assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"loan-service/core/auth"
+	"loan-service/core/borrower"
+	"loan-service/core/money"
+)
+
+// borrowerResponse is a borrower together with the number of their loans that are neither repaid nor closed.
+type borrowerResponse struct {
+	borrower.Borrower
+	ActiveLoans int `json:"active_loans"`
+}
+
+// RegisterBorrower handles POST /borrowers
+// A borrower registers themselves; `id` is only read from admins and anonymous requests.
+// `identity_number` can only be registered once.
+func (h *Handler) RegisterBorrower(c *gin.Context) {
+	var req struct {
+		ID             string `json:"id"`
+		IdentityNumber string `json:"identity_number" binding:"required"`
+		borrower.Profile
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	b, err := h.Borrowers.Register(onBehalfOf(c, auth.Borrower, req.ID), req.IdentityNumber, req.Profile)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusCreated, borrowerResponse{Borrower: b})
+}
+
+// ListBorrowers handles GET /borrowers
+func (h *Handler) ListBorrowers(c *gin.Context) {
+	list, err := h.Borrowers.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list borrowers"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetBorrower handles GET /borrowers/:id
+// The response includes `active_loans`, the number of the borrower's loans that are neither repaid nor closed.
+func (h *Handler) GetBorrower(c *gin.Context) {
+	b, err := h.Borrowers.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	h.respondBorrower(c, b)
+}
+
+// UpdateBorrowerProfile handles PUT /borrowers/:id/profile
+// `name` is required; `email`, `phone` and `address` are optional and cleared when left out.
+func (h *Handler) UpdateBorrowerProfile(c *gin.Context) {
+	var profile borrower.Profile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	b, err := h.Borrowers.UpdateProfile(c.Param("id"), profile)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	h.respondBorrower(c, b)
+}
+
+// SetBorrowerCreditLimit handles PUT /borrowers/:id/credit-limit
+// `amount` is the most principal the borrower may owe across active loans, in `currency` (default IDR);
+// leaving it out removes the limit.
+func (h *Handler) SetBorrowerCreditLimit(c *gin.Context) {
+	var req struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	var limit *money.Money
+	if req.Amount != "" {
+		amount, err := parseAmount(req.Amount, req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		limit = &amount
+	}
+
+	b, err := h.Borrowers.SetCreditLimit(c.Param("id"), limit)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	h.respondBorrower(c, b)
+}
+
+// respondBorrower writes the borrower with their active loan count.
+func (h *Handler) respondBorrower(c *gin.Context, b borrower.Borrower) {
+	active, err := h.Service.ActiveLoans(b.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count active loans"})
+		return
+	}
+	c.JSON(http.StatusOK, borrowerResponse{Borrower: b, ActiveLoans: len(active)})
+}
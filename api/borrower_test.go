@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/borrower"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+func setupRouterWithBorrowers() (*gin.Engine, *loan.LoanService) {
+	borrowers := borrower.NewService(borrower.NewInMemoryRepository(), borrower.WithPolicy(borrower.Policy{SingleLoanInProgress: true}))
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender(), loan.WithBorrowerRegistry(borrowers))
+	return SetupRouter(NewHandler(svc, WithBorrowers(borrowers))), svc
+}
+
+func TestBorrowerHandlers(t *testing.T) {
+	router, svc := setupRouterWithBorrowers()
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	apply := func(borrowerID string, amount int) *httptest.ResponseRecorder {
+		return send("POST", "/loans", gin.H{"borrower_id": borrowerID, "principal_amount": amount, "rate": 10, "roi": 8})
+	}
+
+	w := send("POST", "/borrowers", gin.H{"id": "B050", "identity_number": "3171000000000050", "name": "Siti", "phone": "+628111"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"profile":{"name":"Siti","phone":"+628111"}`)
+	assert.Equal(t, http.StatusConflict, send("POST", "/borrowers", gin.H{"id": "B051", "identity_number": "3171000000000050", "name": "Dewi"}).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/borrowers", gin.H{"id": "B051", "name": "Dewi"}).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/borrowers", gin.H{"id": "B051", "identity_number": "3171000000000051"}).Code)
+
+	w = apply("B999", 1000)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "borrower not found")
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/borrowers/B050/credit-limit", gin.H{"amount": 0}).Code)
+	assert.Equal(t, http.StatusNotFound, send("PUT", "/borrowers/B999/credit-limit", gin.H{"amount": 1000}).Code)
+	w = send("PUT", "/borrowers/B050/credit-limit", gin.H{"amount": "5000.00"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"credit_limit":{"amount":"5000.00","currency":"IDR"}`)
+
+	w = apply("B050", 6000)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "over their credit limit of IDR 5000.00")
+	require.Equal(t, http.StatusCreated, apply("B050", 3000).Code)
+	w = apply("B050", 1000)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "not yet disbursed")
+
+	w = send("PUT", "/borrowers/B050/profile", gin.H{"name": "Siti Aminah", "email": "siti@example.com"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"profile":{"name":"Siti Aminah","email":"siti@example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/borrowers/B050/profile", gin.H{"email": "siti@example.com"}).Code)
+
+	w = send("GET", "/borrowers/B050", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active_loans":1`)
+	assert.Contains(t, w.Body.String(), `"identity_number":"3171000000000050"`)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/borrowers/B999", nil).Code)
+
+	page, err := svc.SearchLoans(loan.LoanQuery{BorrowerID: "B050"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Total, "refused applications are not stored")
+
+	w = send("GET", "/borrowers", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"B050"`)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadDocument handles POST /documents
+// The body is multipart/form-data with the file in the `file` field. The response describes the stored
+// document; its `id` is what approvals and disbursements refer to.
+func (h *Handler) UploadDocument(c *gin.Context) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected a multipart/form-data body"})
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart body"})
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		doc, err := h.Documents.Upload(part.FileName(), part, staffID(c, ""))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		c.JSON(http.StatusCreated, doc)
+		return
+	}
+}
+
+// GetDocument handles GET /documents/:id
+// Borrowers and investors may only read documents of loans they are party to.
+func (h *Handler) GetDocument(c *gin.Context) {
+	if !h.mayReadDocument(c) {
+		return
+	}
+	doc, err := h.Documents.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+// GetDocumentContent handles GET /documents/:id/content
+// The content is sent as an attachment under its original file name, with its SHA-256 as the ETag.
+// Borrowers and investors may only read documents of loans they are party to.
+func (h *Handler) GetDocumentContent(c *gin.Context) {
+	if !h.mayReadDocument(c) {
+		return
+	}
+	doc, content, err := h.Documents.Open(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer func() { _ = content.Close() }()
+
+	headers := map[string]string{"ETag": `"` + doc.SHA256 + `"`}
+	if doc.Filename != "" {
+		headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": doc.Filename})
+	}
+	c.DataFromReader(http.StatusOK, doc.Size, doc.ContentType, content, headers)
+}
+
+// mayReadDocument checks that the caller is staff, or party to a loan referring to the document named by
+// the :id parameter. Otherwise it answers the request and returns false.
+func (h *Handler) mayReadDocument(c *gin.Context) bool {
+	p, ok := principal(c)
+	if !ok || p.HasAny(staffRoles...) {
+		return true
+	}
+	loans, err := h.Service.LoansWithDocument(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	for _, ln := range loans {
+		if partyTo(p, ln) {
+			return true
+		}
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "you can only read documents of loans you are party to"})
+	return false
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/document"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+// pdf is the start of a PDF file, enough for its type to be detected.
+const pdf = "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<<>>\nendobj\n"
+
+func setupRouterWithDocuments() (*gin.Engine, *loan.LoanService) {
+	docs := document.NewService(document.NewInMemoryStore(), document.WithMaxSize(1024))
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender(), loan.WithDocumentStore(docs))
+	return SetupRouter(NewHandler(svc, WithDocuments(docs))), svc
+}
+
+// upload sends content as the named multipart field.
+func upload(router *gin.Engine, field, filename, content string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile(field, filename)
+	_, _ = part.Write([]byte(content))
+	_ = form.Close()
+
+	req, _ := http.NewRequest("POST", "/documents", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDocumentHandlers(t *testing.T) {
+	router, svc := setupRouterWithDocuments()
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload(router, "file", "proof.pdf", pdf)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var doc document.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "proof.pdf", doc.Filename)
+	assert.Equal(t, "application/pdf", doc.ContentType)
+	assert.Equal(t, int64(len(pdf)), doc.Size)
+	assert.Len(t, doc.SHA256, 64)
+
+	t.Run("Rejected uploads", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, upload(router, "file", "notes.pdf", "plain text").Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, upload(router, "file", "big.pdf", pdf+strings.Repeat(" ", 1024)).Code)
+		assert.Equal(t, http.StatusBadRequest, upload(router, "attachment", "proof.pdf", pdf).Code)
+
+		req, _ := http.NewRequest("POST", "/documents", strings.NewReader(`{"file": "proof.pdf"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		big, _ := http.NewRequest("POST", "/documents", strings.NewReader(strings.Repeat("x", 1024+maxBufferedBody+1)))
+		big.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+		big.Header.Set(IdempotencyKeyHeader, "big-upload")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, big)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "not buffered beyond the upload limit")
+	})
+
+	t.Run("Description and content", func(t *testing.T) {
+		w := get("/documents/" + doc.ID)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), doc.SHA256)
+
+		w = get("/documents/" + doc.ID + "/content")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, pdf, w.Body.String())
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=proof.pdf`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, `"`+doc.SHA256+`"`, w.Header().Get("ETag"))
+
+		assert.Equal(t, http.StatusNotFound, get("/documents/missing").Code)
+		assert.Equal(t, http.StatusNotFound, get("/documents/missing/content").Code)
+	})
+
+	t.Run("Approval refers to an uploaded photo proof", func(t *testing.T) {
+		ln, err := svc.CreateLoan("B060", idr(1000), 10, 8)
+		require.NoError(t, err)
+		approve := func(proof string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(gin.H{"photo_proof_url": proof, "field_validator_id": "EMP060", "approval_date": "2025-08-01"})
+			req, _ := http.NewRequest("POST", "/loans/"+ln.ID+"/approve", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := approve("https://example.com/proof.jpg")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "unknown document")
+		assert.Equal(t, http.StatusOK, approve(doc.ID).Code)
+	})
+}
@@ -1,30 +1,113 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"loan-service/core/auth"
+	"loan-service/core/borrower"
+	"loan-service/core/document"
+	"loan-service/core/idempotency"
+	"loan-service/core/investor"
 	"loan-service/core/loan"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
+	"loan-service/core/webhook"
 )
 
 // Handler contains dependencies needed by the HTTP routes.
 type Handler struct {
-	Service *loan.LoanService
+	Service     *loan.LoanService
+	Webhooks    *webhook.Service     // Optional; the /webhooks routes are only served when set
+	Investors   *investor.Service    // Optional; the investor registry routes are only served when set
+	Borrowers   *borrower.Service    // Optional; the borrower registry routes are only served when set
+	Documents   *document.Service    // Optional; the /documents routes are only served when set
+	Idempotency idempotency.Store    // Remembers responses to POSTs made with an Idempotency-Key
+	Auth        *auth.Verifier       // Optional; when set, every route requires a bearer token and is guarded by role
+	Readiness   []ReadinessCheck     // Dependencies that must be usable for GET /readyz to succeed
+	Metrics     *prometheus.Registry // Optional; GET /metrics is only served, and requests only counted, when set
+
+	instruments *instruments
+}
+
+// HandlerOption configures optional dependencies of a Handler.
+type HandlerOption func(*Handler)
+
+// WithWebhooks enables the webhook subscription routes.
+func WithWebhooks(service *webhook.Service) HandlerOption {
+	return func(h *Handler) {
+		h.Webhooks = service
+	}
+}
+
+// WithInvestors enables the investor registry routes.
+func WithInvestors(service *investor.Service) HandlerOption {
+	return func(h *Handler) {
+		h.Investors = service
+	}
+}
+
+// WithBorrowers enables the borrower registry routes.
+func WithBorrowers(service *borrower.Service) HandlerOption {
+	return func(h *Handler) {
+		h.Borrowers = service
+	}
+}
+
+// WithDocuments enables the document upload routes.
+func WithDocuments(service *document.Service) HandlerOption {
+	return func(h *Handler) {
+		h.Documents = service
+	}
+}
+
+// WithAuth requires a bearer token verified by verifier on every request. The caller's roles decide which
+// routes they may use, and their subject is the borrower, investor or employee ID acting in the request.
+func WithAuth(verifier *auth.Verifier) HandlerOption {
+	return func(h *Handler) {
+		h.Auth = verifier
+	}
+}
+
+// WithIdempotencyStore sets where responses to requests with an Idempotency-Key are kept.
+// Defaults to an in-memory store.
+func WithIdempotencyStore(store idempotency.Store) HandlerOption {
+	return func(h *Handler) {
+		h.Idempotency = store
+	}
 }
 
 // NewHandler creates a new HTTP handler instance.
-func NewHandler(service *loan.LoanService) *Handler {
-	return &Handler{Service: service}
+func NewHandler(service *loan.LoanService, opts ...HandlerOption) *Handler {
+	h := &Handler{Service: service, Idempotency: idempotency.NewInMemoryStore()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // CreateLoan handles POST /loans to create a new loan.
+// The principal is a decimal number or string; `currency` is optional and defaults to IDR.
+// `repayment_method` and `tenor` are optional and default to loan.DefaultRepaymentTerms.
+// A borrower applies for themselves; `borrower_id` is only read from admins and anonymous requests.
 func (h *Handler) CreateLoan(c *gin.Context) {
 	var req struct {
-		BorrowerID      string  `json:"borrower_id" binding:"required"`
-		PrincipalAmount float64 `json:"principal_amount" binding:"required"`
-		Rate            float64 `json:"rate" binding:"required"`
-		ROI             float64 `json:"roi" binding:"required"`
+		BorrowerID      string      `json:"borrower_id"`
+		PrincipalAmount json.Number `json:"principal_amount" binding:"required"`
+		Currency        string      `json:"currency"`
+		Rate            float64     `json:"rate" binding:"required"`
+		ROI             float64     `json:"roi" binding:"required"`
+		RepaymentMethod string      `json:"repayment_method"`
+		Tenor           int         `json:"tenor"`
+		InstallmentFee  json.Number `json:"installment_fee"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -32,22 +115,66 @@ func (h *Handler) CreateLoan(c *gin.Context) {
 		return
 	}
 
-	loan, err := h.Service.CreateLoan(req.BorrowerID, req.PrincipalAmount, req.Rate, req.ROI)
+	borrowerID := onBehalfOf(c, auth.Borrower, req.BorrowerID)
+	if borrowerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "borrower_id is required"})
+		return
+	}
+
+	principal, err := parseAmount(req.PrincipalAmount, req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms := loan.DefaultRepaymentTerms
+	if req.RepaymentMethod != "" {
+		terms.Method = loan.RepaymentMethod(req.RepaymentMethod)
+	}
+	if req.Tenor != 0 {
+		terms.Tenor = req.Tenor
+	}
+	if req.InstallmentFee != "" {
+		fee, err := money.Parse(req.InstallmentFee.String(), principal.Currency())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		terms.InstallmentFee = fee
+	}
+	if err := terms.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := append(actingAs(c), loan.WithRepaymentTerms(terms))
+	ln, err := h.Service.CreateLoan(borrowerID, principal, req.Rate, req.ROI, opts...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, loan)
+	respondLoan(c, http.StatusCreated, ln)
 }
 
 // ApproveLoan handles POST /loans/:id/approve
+// `funding_deadline` is optional: either an RFC 3339 timestamp, or a date meaning funding stays open
+// through the end of that day (UTC).
+// The validator is the authenticated caller; `field_validator_id` is only read when authentication is disabled.
+// An optional If-Match header makes the approval conditional on the loan's current ETag.
 func (h *Handler) ApproveLoan(c *gin.Context) {
 	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var req struct {
-		PhotoProofURL string `json:"photo_proof_url" binding:"required"`
-		ValidatorID   string `json:"field_validator_id" binding:"required"`
-		ApprovalDate  string `json:"approval_date" binding:"required"`
+		PhotoProofURL   string `json:"photo_proof_url" binding:"required"`
+		ValidatorID     string `json:"field_validator_id"`
+		ApprovalDate    string `json:"approval_date" binding:"required"`
+		FundingDeadline string `json:"funding_deadline"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -63,54 +190,158 @@ func (h *Handler) ApproveLoan(c *gin.Context) {
 
 	approval := loan.Approval{
 		PhotoProofURL: req.PhotoProofURL,
-		ValidatorID:   req.ValidatorID,
+		ValidatorID:   staffID(c, req.ValidatorID),
 		ApprovalDate:  date,
 	}
+	if approval.ValidatorID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field_validator_id is required"})
+		return
+	}
+	opts = append(opts, actingAs(c)...)
+
+	if req.FundingDeadline != "" {
+		deadline, err := parseDeadline(req.FundingDeadline)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts = append(opts, loan.WithFundingDeadline(deadline))
+	}
 
-	ln, err := h.Service.ApproveLoan(id, approval)
+	ln, err := h.Service.ApproveLoan(id, approval, opts...)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, ln)
+	respondLoan(c, http.StatusOK, ln)
 }
 
 // InvestLoan handles POST /loans/:id/invest
+// An investor invests for themselves; `investor_id` is only read when authentication is disabled.
+// An optional If-Match header makes the investment conditional on the loan's current ETag.
 func (h *Handler) InvestLoan(c *gin.Context) {
 	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		h.investmentFailed(nil)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var req struct {
-		InvestorID string  `json:"investor_id" binding:"required"`
-		Amount     float64 `json:"amount" binding:"required"`
+		InvestorID string      `json:"investor_id"`
+		Amount     json.Number `json:"amount" binding:"required"`
+		Currency   string      `json:"currency"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
+		h.investmentFailed(nil)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
 		return
 	}
 
+	amount, err := parseAmount(req.Amount, req.Currency)
+	if err != nil {
+		h.investmentFailed(nil)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	investor := loan.Investor{
-		ID:     req.InvestorID,
-		Amount: req.Amount,
+		ID:     onBehalfOf(c, auth.Investor, req.InvestorID),
+		Amount: amount,
+	}
+	if investor.ID == "" {
+		h.investmentFailed(nil)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "investor_id is required"})
+		return
+	}
+	opts = append(opts, actingAs(c)...)
+
+	ln, err := h.Service.InvestLoan(id, investor, opts...)
+	if err != nil {
+		h.investmentFailed(err)
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
+}
+
+// WithdrawInvestment handles DELETE /loans/:id/investments/:investorId
+// It takes back the investor's whole commitment while the loan is still approved.
+// An optional If-Match header makes the withdrawal conditional on the loan's current ETag.
+func (h *Handler) WithdrawInvestment(c *gin.Context) {
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts = append(opts, actingAs(c)...)
+	ln, err := h.Service.WithdrawInvestment(c.Param("id"), c.Param("investorId"), opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
+}
+
+// ReduceInvestment handles POST /loans/:id/investments/:investorId/reduce
+// `amount` is how much to take back from the investor's commitment; `currency` is optional.
+// An optional If-Match header makes the reduction conditional on the loan's current ETag.
+func (h *Handler) ReduceInvestment(c *gin.Context) {
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	ln, err := h.Service.InvestLoan(id, investor)
+	var req struct {
+		Amount   json.Number `json:"amount" binding:"required"`
+		Currency string      `json:"currency"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	amount, err := parseAmount(req.Amount, req.Currency)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, ln)
+	opts = append(opts, actingAs(c)...)
+	ln, err := h.Service.ReduceInvestment(c.Param("id"), c.Param("investorId"), amount, opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
 }
 
 // DisburseLoan handles POST /loans/:id/disburse
+// The field officer is the authenticated caller; `field_officer_id` is only read when authentication is disabled.
+// `agreement_letter_link` is optional when the agreement letter was generated at approval.
+// An optional If-Match header makes the disbursement conditional on the loan's current ETag.
 func (h *Handler) DisburseLoan(c *gin.Context) {
 	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var req struct {
 		AgreementFile    string `json:"agreement_letter_file" binding:"required"`
-		FieldOfficerID   string `json:"field_officer_id" binding:"required"`
+		FieldOfficerID   string `json:"field_officer_id"`
 		DisbursementDate string `json:"disbursement_date" binding:"required"`
-		AgreementLink    string `json:"agreement_letter_link" binding:"required"`
+		AgreementLink    string `json:"agreement_letter_link"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -126,36 +357,474 @@ func (h *Handler) DisburseLoan(c *gin.Context) {
 
 	disb := loan.Disbursement{
 		AgreementFile:    req.AgreementFile,
-		FieldOfficerID:   req.FieldOfficerID,
+		FieldOfficerID:   staffID(c, req.FieldOfficerID),
 		DisbursementDate: date,
 	}
+	if disb.FieldOfficerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field_officer_id is required"})
+		return
+	}
+	opts = append(opts, actingAs(c)...)
+
+	ln, err := h.Service.DisburseLoan(id, disb, req.AgreementLink, opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
+}
+
+// RecordRepayment handles POST /loans/:id/repayments
+// An optional If-Match header makes the repayment conditional on the loan's current ETag.
+func (h *Handler) RecordRepayment(c *gin.Context) {
+	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	ln, err := h.Service.DisburseLoan(id, disb, req.AgreementLink)
+	var req struct {
+		Amount    json.Number `json:"amount" binding:"required"`
+		Currency  string      `json:"currency"`
+		PaidAt    string      `json:"paid_at" binding:"required"`
+		Reference string      `json:"reference"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	amount, err := parseAmount(req.Amount, req.Currency)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, ln)
+	date, err := time.Parse("2006-01-02", req.PaidAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format (expected YYYY-MM-DD)"})
+		return
+	}
+
+	repayment := loan.Repayment{
+		Amount:    amount,
+		PaidAt:    date,
+		Reference: req.Reference,
+	}
+
+	opts = append(opts, actingAs(c)...)
+	ln, err := h.Service.RecordRepayment(id, repayment, opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusCreated, ln)
+}
+
+// RejectLoan handles POST /loans/:id/reject
+// The staff member is the authenticated caller; `staff_id` is only read when authentication is disabled.
+// An optional If-Match header makes the rejection conditional on the loan's current ETag.
+func (h *Handler) RejectLoan(c *gin.Context) {
+	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Reason  string `json:"reason" binding:"required"`
+		StaffID string `json:"staff_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	closure := loan.Closure{Reason: req.Reason, StaffID: staffID(c, req.StaffID)}
+	if closure.StaffID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "staff_id is required"})
+		return
+	}
+	opts = append(opts, actingAs(c)...)
+	ln, err := h.Service.RejectLoan(id, closure, opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
+}
+
+// CancelLoan handles POST /loans/:id/cancel
+// Investments in a partially funded loan are released.
+// The staff member is the authenticated caller; `staff_id` is only read when authentication is disabled.
+// An optional If-Match header makes the cancellation conditional on the loan's current ETag.
+func (h *Handler) CancelLoan(c *gin.Context) {
+	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Reason  string `json:"reason"`
+		StaffID string `json:"staff_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	closure := loan.Closure{Reason: req.Reason, StaffID: staffID(c, req.StaffID)}
+	if closure.StaffID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "staff_id is required"})
+		return
+	}
+	opts = append(opts, actingAs(c)...)
+	ln, err := h.Service.CancelLoan(id, closure, opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
+}
+
+// ExpireLoan handles POST /loans/:id/expire
+// The body is optional; `reason` and `staff_id` are recorded when given. With authentication enabled
+// the staff member is the caller.
+// An optional If-Match header makes the expiry conditional on the loan's current ETag.
+func (h *Handler) ExpireLoan(c *gin.Context) {
+	id := c.Param("id")
+	opts, err := ifMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Reason  string `json:"reason"`
+		StaffID string `json:"staff_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	opts = append(opts, actingAs(c)...)
+	ln, err := h.Service.ExpireLoan(id, loan.Closure{Reason: req.Reason, StaffID: staffID(c, req.StaffID)}, opts...)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondLoan(c, http.StatusOK, ln)
 }
 
 // GetLoan handles GET /loans/:id
 func (h *Handler) GetLoan(c *gin.Context) {
-	id := c.Param("id")
-	ln, err := h.Service.GetLoan(id)
+	ln, err := h.Service.GetLoan(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "loan not found"})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
-	c.JSON(http.StatusOK, ln)
+	if !mayRead(c, ln) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errNotParty})
+		return
+	}
+	respondLoan(c, http.StatusOK, ln)
+}
+
+// errNotParty is the error answered to borrowers and investors reading a loan they are not party to.
+const errNotParty = "you can only read loans you are party to"
+
+// mayReadLoan checks that the caller may read the loan named by the :id parameter (see mayRead).
+// Otherwise it answers the request and returns false. Staff are let through without loading the loan.
+func (h *Handler) mayReadLoan(c *gin.Context) bool {
+	if p, ok := principal(c); !ok || p.HasAny(staffRoles...) {
+		return true
+	}
+	ln, err := h.Service.GetLoan(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	if !mayRead(c, ln) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errNotParty})
+		return false
+	}
+	return true
+}
+
+// GetSchedule handles GET /loans/:id/schedule
+func (h *Handler) GetSchedule(c *gin.Context) {
+	if !h.mayReadLoan(c) {
+		return
+	}
+	schedule, err := h.Service.GetSchedule(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// GetHistory handles GET /loans/:id/history
+func (h *Handler) GetHistory(c *gin.Context) {
+	if !h.mayReadLoan(c) {
+		return
+	}
+	events, err := h.Service.GetHistory(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// ListLoanPayouts handles GET /loans/:id/payouts
+func (h *Handler) ListLoanPayouts(c *gin.Context) {
+	if !h.mayReadLoan(c) {
+		return
+	}
+	payouts, err := h.Service.ListPayoutsByLoan(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, payouts)
+}
+
+// ListInvestorPayouts handles GET /investors/:id/payouts
+func (h *Handler) ListInvestorPayouts(c *gin.Context) {
+	payouts, err := h.Service.ListPayoutsByInvestor(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list payouts"})
+		return
+	}
+	c.JSON(http.StatusOK, payouts)
+}
+
+// ListNotifications handles GET /notifications
+// The optional `status` query parameter (pending, delivered or failed) filters the outbox messages.
+func (h *Handler) ListNotifications(c *gin.Context) {
+	status := outbox.Status(c.Query("status"))
+	switch status {
+	case "", outbox.Pending, outbox.Delivered, outbox.Failed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status (expected pending, delivered or failed)"})
+		return
+	}
+
+	messages, err := h.Service.ListNotifications(status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+// RetryNotification handles POST /notifications/:id/retry
+// It queues a failed notification for delivery again.
+func (h *Handler) RetryNotification(c *gin.Context) {
+	msg, err := h.Service.RetryNotification(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
 }
 
 // ListLoans handles GET /loans
+// Optional query parameters filter the loans: `state` (repeatable or comma-separated), `borrower_id`,
+// `investor_id`, `created_from` / `created_to` (YYYY-MM-DD, both days included, or RFC 3339) and
+// `min_amount` / `max_amount` (with `currency`, default IDR).
+// `sort` is `created_at` (default) or `principal_amount`, prefixed with `-` for descending order.
+// Results come in pages of `limit` loans; pass the returned `next_cursor` as `cursor` for the next page.
+// Borrowers only find their own loans, and investors the loans they invested in or that are open for funding.
 func (h *Handler) ListLoans(c *gin.Context) {
-	list, err := h.Service.ListLoans()
+	query, err := parseLoanQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Party = readable(c)
+	page, err := h.Service.SearchLoans(query)
+	if errors.Is(err, loan.ErrInvalidQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list loans"})
 		return
 	}
-	c.JSON(http.StatusOK, list)
+	c.JSON(http.StatusOK, page)
+}
+
+// parseLoanQuery reads the GET /loans query parameters.
+func parseLoanQuery(c *gin.Context) (loan.LoanQuery, error) {
+	query := loan.LoanQuery{
+		BorrowerID: c.Query("borrower_id"),
+		InvestorID: c.Query("investor_id"),
+		Cursor:     c.Query("cursor"),
+	}
+	for _, param := range c.QueryArray("state") {
+		for _, state := range strings.Split(param, ",") {
+			if state = strings.TrimSpace(state); state != "" {
+				query.States = append(query.States, loan.LoanState(state))
+			}
+		}
+	}
+
+	if s := c.Query("created_from"); s != "" {
+		from, err := parseDay(s, false)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_from: %w", err)
+		}
+		query.CreatedFrom = &from
+	}
+	if s := c.Query("created_to"); s != "" {
+		to, err := parseDay(s, true)
+		if err != nil {
+			return query, fmt.Errorf("invalid created_to: %w", err)
+		}
+		query.CreatedTo = &to
+	}
+
+	cur, err := money.ParseCurrency(c.DefaultQuery("currency", string(money.DefaultCurrency)))
+	if err != nil {
+		return query, err
+	}
+	for param, bound := range map[string]**money.Money{"min_amount": &query.MinAmount, "max_amount": &query.MaxAmount} {
+		if s := c.Query(param); s != "" {
+			amount, err := money.Parse(s, cur)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s: %w", param, err)
+			}
+			*bound = &amount
+		}
+	}
+
+	sortBy := c.Query("sort")
+	if strings.HasPrefix(sortBy, "-") {
+		sortBy, query.Descending = sortBy[1:], true
+	}
+	query.Sort = loan.LoanSort(sortBy)
+
+	if s := c.Query("limit"); s != "" {
+		if query.Limit, err = strconv.Atoi(s); err != nil || query.Limit < 1 {
+			return query, errors.New("invalid limit (expected a positive number)")
+		}
+	}
+	return query, nil
+}
+
+// respondLoan writes the loan as JSON and exposes its version as a strong ETag.
+func respondLoan(c *gin.Context, status int, ln *loan.Loan) {
+	c.Header("ETag", `"`+strconv.FormatInt(ln.Version, 10)+`"`)
+	c.JSON(status, ln)
+}
+
+// respondError maps service errors to HTTP status codes, falling back to the given status.
+func respondError(c *gin.Context, fallback int, err error) {
+	status := fallback
+	switch {
+	case errors.Is(err, loan.ErrLoanNotFound), errors.Is(err, loan.ErrScheduleNotAvailable),
+		errors.Is(err, loan.ErrInvestmentNotFound), errors.Is(err, outbox.ErrMessageNotFound),
+		errors.Is(err, webhook.ErrSubscriptionNotFound), errors.Is(err, webhook.ErrDeliveryNotFound),
+		errors.Is(err, investor.ErrInvestorNotFound), errors.Is(err, borrower.ErrBorrowerNotFound),
+		errors.Is(err, document.ErrDocumentNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, loan.ErrInvalidLoan):
+		status = http.StatusBadRequest
+	case errors.Is(err, loan.ErrVersionConflict), errors.Is(err, outbox.ErrNotFailed), errors.Is(err, investor.ErrInvestorExists),
+		errors.Is(err, borrower.ErrBorrowerExists):
+		status = http.StatusConflict
+	case errors.Is(err, investor.ErrNotVerified):
+		status = http.StatusForbidden
+	case errors.Is(err, investor.ErrLimitExceeded), errors.Is(err, borrower.ErrCreditLimitExceeded),
+		errors.Is(err, borrower.ErrLoanInProgress), errors.Is(err, loan.ErrUnknownDocument),
+		errors.Is(err, loan.ErrOverInvestment), errors.Is(err, money.ErrOverflow):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, document.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, document.ErrUnsupportedType):
+		status = http.StatusUnsupportedMediaType
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// parseAmount converts a JSON number into an exact positive amount without going through float64.
+func parseAmount(n json.Number, currency string) (money.Money, error) {
+	cur, err := money.ParseCurrency(currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	m, err := money.Parse(n.String(), cur)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if !m.IsPositive() {
+		return money.Money{}, errors.New("amount must be positive")
+	}
+	return m, nil
+}
+
+// parseDeadline accepts an RFC 3339 timestamp, or a date meaning the end of that day in UTC.
+func parseDeadline(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, errors.New("invalid funding deadline (expected YYYY-MM-DD or RFC 3339)")
+	}
+	return day.AddDate(0, 0, 1), nil
+}
+
+// parseDay accepts an RFC 3339 timestamp or a date. With endOfDay a date means the end of that day
+// in UTC, otherwise its start.
+func parseDay(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, errors.New("expected YYYY-MM-DD or RFC 3339")
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// ifMatch turns the If-Match request header into a service option.
+// A missing header or `*` means the request is unconditional. Weak ETags are rejected: If-Match
+// compares tags strongly (RFC 9110, section 13.1.1), so a weak one could never match.
+func ifMatch(c *gin.Context) ([]loan.Option, error) {
+	tag := strings.TrimSpace(c.GetHeader("If-Match"))
+	if tag == "" || tag == "*" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(tag, "W/") {
+		return nil, errors.New("invalid If-Match header (weak ETags cannot be used, send the strong ETag)")
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return nil, errors.New("invalid If-Match header (expected a single ETag)")
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid If-Match header (expected a single ETag)")
+	}
+	return []loan.Option{loan.IfVersion(version)}, nil
 }
@@ -12,22 +12,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/audit"
 	"loan-service/core/loan"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
+	"loan-service/email"
 )
 
-type mockEmailSender struct {
-	sentTo []string
-}
-
-func (m *mockEmailSender) SendInvestorNotification(investorID, agreementLink string) error {
-	m.sentTo = append(m.sentTo, investorID)
-	return nil
+// idr is a shorthand for whole-rupiah amounts in tests.
+func idr(major int64) money.Money {
+	return money.FromMajor(major, money.IDR)
 }
 
 func setupRouterWithMemoryService() (*gin.Engine, *loan.LoanService) {
 	repo := loan.NewInMemoryLoanRepository()
-	email := &mockEmailSender{}
-	svc := loan.NewLoanService(repo, email)
+	svc := loan.NewLoanService(repo, email.NewMockEmailSender())
 	handler := NewHandler(svc)
 	return SetupRouter(handler), svc
 }
@@ -60,6 +60,98 @@ func TestLoanHandlers_All(t *testing.T) {
 			expectCode: 201,
 			contains:   "\"borrower_id\":\"B001\"",
 		},
+		{
+			name:     "CreateLoan exact decimal amount",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B012",
+				"principal_amount": "1500000.25",
+				"rate":             10,
+				"roi":              12,
+			},
+			expectCode: 201,
+			contains:   `"principal_amount":{"amount":"1500000.25","currency":"IDR"}`,
+		},
+		{
+			name:     "CreateLoan too many decimal places",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B013",
+				"principal_amount": 1500000.255,
+				"rate":             10,
+				"roi":              12,
+			},
+			expectCode: 400,
+		},
+		{
+			name:     "CreateLoan unsupported currency",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B014",
+				"principal_amount": 1000,
+				"currency":         "XYZ",
+				"rate":             10,
+				"roi":              12,
+			},
+			expectCode: 400,
+		},
+		{
+			name:     "CreateLoan with weekly repayment terms",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B015",
+				"principal_amount": 500000,
+				"rate":             10,
+				"roi":              12,
+				"repayment_method": "weekly",
+				"tenor":            50,
+			},
+			expectCode: 201,
+			contains:   `"repayment_terms":{"repayment_method":"weekly","tenor":50,"installment_fee":{"amount":"0.00","currency":"IDR"}}`,
+		},
+		{
+			name:     "CreateLoan negative rate",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B017",
+				"principal_amount": 500000,
+				"rate":             -5,
+				"roi":              12,
+			},
+			expectCode: 400,
+			contains:   "rate must be between 0 and 1000%",
+		},
+		{
+			name:     "CreateLoan absurd rate",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B018",
+				"principal_amount": 500000,
+				"rate":             1e30,
+				"roi":              12,
+			},
+			expectCode: 400,
+			contains:   "rate must be between 0 and 1000%",
+		},
+		{
+			name:     "CreateLoan unsupported repayment method",
+			method:   "POST",
+			endpoint: "/loans",
+			payload: map[string]interface{}{
+				"borrower_id":      "B016",
+				"principal_amount": 500000,
+				"rate":             10,
+				"roi":              12,
+				"repayment_method": "balloon",
+			},
+			expectCode: 400,
+		},
 		{
 			name:       "CreateLoan invalid JSON",
 			method:     "POST",
@@ -71,7 +163,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "ApproveLoan success",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B002", 4000000, 10, 10)
+				ln, _ := svc.CreateLoan("B002", idr(4000000), 10, 10)
 				return "/loans/" + ln.ID + "/approve"
 			},
 			payload: map[string]interface{}{
@@ -86,7 +178,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "ApproveLoan missing fields",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B003", 3000, 10, 10)
+				ln, _ := svc.CreateLoan("B003", idr(3000), 10, 10)
 				return "/loans/" + ln.ID + "/approve"
 			},
 			payload: map[string]interface{}{
@@ -100,7 +192,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "ApproveLoan invalid date format",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B007", 1000, 1, 1)
+				ln, _ := svc.CreateLoan("B007", idr(1000), 1, 1)
 				return "/loans/" + ln.ID + "/approve"
 			},
 			payload: map[string]interface{}{
@@ -114,11 +206,11 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "DisburseLoan invalid date format",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B008", 2000, 10, 10)
+				ln, _ := svc.CreateLoan("B008", idr(2000), 10, 10)
 				svc.ApproveLoan(ln.ID, loan.Approval{
 					PhotoProofURL: "url", ValidatorID: "EMP008", ApprovalDate: time.Now(),
 				})
-				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV008", Amount: 2000})
+				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV008", Amount: idr(2000)})
 				return "/loans/" + ln.ID + "/disburse"
 			},
 			payload: map[string]interface{}{
@@ -140,7 +232,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "ApproveLoan bad request (missing fields)",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B111", 1000, 10, 10)
+				ln, _ := svc.CreateLoan("B111", idr(1000), 10, 10)
 				return "/loans/" + ln.ID + "/approve"
 			},
 			payload: map[string]interface{}{
@@ -154,7 +246,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "InvestLoan malformed JSON",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B009", 1000, 1, 1)
+				ln, _ := svc.CreateLoan("B009", idr(1000), 1, 1)
 				svc.ApproveLoan(ln.ID, loan.Approval{
 					PhotoProofURL: "proof", ValidatorID: "EMP009", ApprovalDate: time.Now(),
 				})
@@ -167,11 +259,11 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "DisburseLoan bad request (missing fields)",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B333", 3000, 10, 10)
+				ln, _ := svc.CreateLoan("B333", idr(3000), 10, 10)
 				svc.ApproveLoan(ln.ID, loan.Approval{
 					PhotoProofURL: "img", ValidatorID: "VAL2", ApprovalDate: time.Now(),
 				})
-				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV3", Amount: 3000})
+				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV3", Amount: idr(3000)})
 				return "/loans/" + ln.ID + "/disburse"
 			},
 			payload: map[string]interface{}{
@@ -186,7 +278,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "InvestLoan success",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B004", 3000000, 10, 10)
+				ln, _ := svc.CreateLoan("B004", idr(3000000), 10, 10)
 				svc.ApproveLoan(ln.ID, loan.Approval{
 					PhotoProofURL: "proof", ValidatorID: "EMPX", ApprovalDate: time.Now(),
 				})
@@ -199,15 +291,33 @@ func TestLoanHandlers_All(t *testing.T) {
 			expectCode: 200,
 			contains:   "\"state\":\"invested\"",
 		},
+		{
+			name:   "InvestLoan beyond the principal left",
+			method: "POST",
+			setup: func() string {
+				ln, _ := svc.CreateLoan("B004", idr(3000000), 10, 10)
+				svc.ApproveLoan(ln.ID, loan.Approval{
+					PhotoProofURL: "proof", ValidatorID: "EMPX", ApprovalDate: time.Now(),
+				})
+				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV123", Amount: idr(1000)})
+				return "/loans/" + ln.ID + "/invest"
+			},
+			payload: map[string]interface{}{
+				"investor_id": "INV124",
+				"amount":      json.Number("92233720368547758.07"),
+			},
+			expectCode: 422,
+			contains:   loan.ErrOverInvestment.Error(),
+		},
 		{
 			name:   "DisburseLoan success",
 			method: "POST",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B005", 2000000, 10, 10)
+				ln, _ := svc.CreateLoan("B005", idr(2000000), 10, 10)
 				svc.ApproveLoan(ln.ID, loan.Approval{
 					PhotoProofURL: "proof", ValidatorID: "EMPY", ApprovalDate: time.Now(),
 				})
-				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV123", Amount: 2000000})
+				svc.InvestLoan(ln.ID, loan.Investor{ID: "INV123", Amount: idr(2000000)})
 				return "/loans/" + ln.ID + "/disburse"
 			},
 			payload: map[string]interface{}{
@@ -224,7 +334,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			method:   "GET",
 			endpoint: "/loans/",
 			setup: func() string {
-				ln, _ := svc.CreateLoan("B000", 1000000, 10, 10)
+				ln, _ := svc.CreateLoan("B000", idr(1000000), 10, 10)
 				return "/loans/" + ln.ID
 			},
 			expectCode: 200,
@@ -239,7 +349,7 @@ func TestLoanHandlers_All(t *testing.T) {
 			name:   "ListLoans success",
 			method: "GET",
 			setup: func() string {
-				svc.CreateLoan("B006", 10000, 10, 10)
+				svc.CreateLoan("B006", idr(10000), 10, 10)
 				return "/loans"
 			},
 			expectCode: 200,
@@ -279,14 +389,26 @@ func TestLoanHandlers_All(t *testing.T) {
 
 type brokenRepoList struct{}
 
-func (r *brokenRepoList) Create(*loan.Loan) error            { return nil }
-func (r *brokenRepoList) GetByID(string) (*loan.Loan, error) { return nil, nil }
-func (r *brokenRepoList) Update(*loan.Loan) error            { return nil }
-func (r *brokenRepoList) List() ([]*loan.Loan, error)        { return nil, errors.New("fail list") }
+func (r *brokenRepoList) Create(*loan.Loan, *audit.Event) error { return nil }
+func (r *brokenRepoList) GetByID(string) (*loan.Loan, error)    { return nil, nil }
+func (r *brokenRepoList) Update(*loan.Loan, *audit.Event, ...outbox.Message) error {
+	return nil
+}
+func (r *brokenRepoList) List() ([]*loan.Loan, error) { return nil, errors.New("fail list") }
+func (r *brokenRepoList) Search(loan.LoanQuery) (*loan.LoanPage, error) {
+	return nil, errors.New("fail search")
+}
+func (r *brokenRepoList) ListFundingOverdue(time.Time) ([]*loan.Loan, error) {
+	return nil, nil
+}
+func (r *brokenRepoList) Totals() ([]loan.LoanTotals, error) {
+	return nil, errors.New("fail totals")
+}
+func (r *brokenRepoList) Outbox() outbox.Store    { return outbox.NewInMemoryStore() }
+func (r *brokenRepoList) Audit() audit.Repository { return audit.NewInMemoryRepository() }
 
 func TestListLoansInternalError(t *testing.T) {
-	email := &mockEmailSender{}
-	svc := loan.NewLoanService(&brokenRepoList{}, email)
+	svc := loan.NewLoanService(&brokenRepoList{}, email.NewMockEmailSender())
 	router := SetupRouter(NewHandler(svc))
 
 	req, _ := http.NewRequest("GET", "/loans", nil)
@@ -295,3 +417,374 @@ func TestListLoansInternalError(t *testing.T) {
 
 	assert.Equal(t, 500, w.Code)
 }
+
+func TestListLoans_Query(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+	small, _ := svc.CreateLoan("B020", idr(1000), 10, 8)
+	time.Sleep(2 * time.Millisecond)
+	large, _ := svc.CreateLoan("B020", idr(5000), 10, 8)
+	time.Sleep(2 * time.Millisecond)
+	other, _ := svc.CreateLoan("B021", idr(3000), 10, 8)
+	_, err := svc.ApproveLoan(other.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP020", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = svc.InvestLoan(other.ID, loan.Investor{ID: "INV020", Amount: idr(1000)})
+	require.NoError(t, err)
+
+	today := time.Now().UTC().Format("2006-01-02")
+	list := func(query string) (int, loan.LoanPage, string) {
+		req, _ := http.NewRequest("GET", "/loans?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var page loan.LoanPage
+		_ = json.Unmarshal(w.Body.Bytes(), &page)
+		return w.Code, page, w.Body.String()
+	}
+	ids := func(page loan.LoanPage) []string {
+		var out []string
+		for _, ln := range page.Loans {
+			out = append(out, ln.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "all loans", query: "", want: []string{small.ID, large.ID, other.ID}},
+		{name: "by state", query: "state=proposed,rejected", want: []string{small.ID, large.ID}},
+		{name: "by repeated state", query: "state=approved&state=rejected", want: []string{other.ID}},
+		{name: "by borrower", query: "borrower_id=B021", want: []string{other.ID}},
+		{name: "by investor", query: "investor_id=INV020", want: []string{other.ID}},
+		{name: "by amount", query: "min_amount=2000&max_amount=5000.00", want: []string{large.ID, other.ID}},
+		{name: "by creation date", query: "created_from=" + today + "&created_to=" + today, want: []string{small.ID, large.ID, other.ID}},
+		{name: "before creation", query: "created_to=2000-01-01", want: nil},
+		{name: "largest first", query: "sort=-principal_amount", want: []string{large.ID, other.ID, small.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, page, body := list(tt.query)
+			require.Equal(t, http.StatusOK, code, body)
+			assert.Equal(t, tt.want, ids(page))
+			assert.Equal(t, len(tt.want), page.Total)
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		code, first, _ := list("limit=2&sort=-created_at")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{other.ID, large.ID}, ids(first))
+		assert.Equal(t, 3, first.Total)
+		require.NotEmpty(t, first.NextCursor)
+
+		code, second, _ := list("limit=2&sort=-created_at&cursor=" + first.NextCursor)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{small.ID}, ids(second))
+		assert.Empty(t, second.NextCursor)
+	})
+
+	_, byCreation, _ := list("limit=1")
+	for _, query := range []string{
+		"state=pending", "sort=rate", "limit=0", "limit=101", "limit=abc", "created_from=yesterday",
+		"min_amount=abc", "currency=XXX&min_amount=1", "cursor=bogus", "sort=principal_amount&cursor=" + byCreation.NextCursor,
+	} {
+		t.Run("rejects "+query, func(t *testing.T) {
+			code, _, body := list(query)
+			assert.Equal(t, http.StatusBadRequest, code, body)
+		})
+	}
+}
+
+func TestLoanHandlers_ETag(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+	ln, _ := svc.CreateLoan("B010", idr(1000), 10, 10)
+
+	approve := func(ifMatch string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"photo_proof_url":    "proof",
+			"field_validator_id": "EMP010",
+			"approval_date":      time.Now().Format("2006-01-02"),
+		})
+		req, _ := http.NewRequest("POST", "/loans/"+ln.ID+"/approve", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	req, _ := http.NewRequest("GET", "/loans/"+ln.ID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	assert.Equal(t, 400, approve("not-an-etag").Code)
+	assert.Equal(t, 400, approve(`W/"1"`).Code, "If-Match compares ETags strongly")
+	assert.Equal(t, 409, approve(`"7"`).Code)
+
+	w = approve(`"1"`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+}
+
+func TestGetScheduleHandler(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	ln, _ := svc.CreateLoan("B011", idr(1200000), 12, 10, loan.WithRepaymentTerms(loan.RepaymentTerms{Method: loan.FlatMethod, Tenor: 12}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, 404, get("/loans/"+ln.ID+"/schedule").Code, "no schedule before disbursement")
+	assert.Equal(t, 404, get("/loans/missing/schedule").Code)
+
+	_, _ = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP011", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV011", Amount: idr(1200000)})
+	_, _ = svc.DisburseLoan(ln.ID, loan.Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO011", DisbursementDate: time.Now()}, "https://link.pdf")
+
+	w := get("/loans/" + ln.ID + "/schedule")
+	assert.Equal(t, 200, w.Code)
+
+	var schedule loan.Schedule
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &schedule))
+	assert.Len(t, schedule.Installments, 12)
+	assert.Equal(t, idr(1200000), schedule.TotalPrincipal)
+	assert.Equal(t, idr(144000), schedule.TotalInterest)
+}
+
+func TestRecordRepaymentHandler(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	ln, _ := svc.CreateLoan("B012", idr(1000), 12, 10, loan.WithRepaymentTerms(loan.RepaymentTerms{Method: loan.FlatMethod, Tenor: 1}))
+	_, _ = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP012", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV012", Amount: idr(1000)})
+
+	repay := func(payload map[string]interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", "/loans/"+ln.ID+"/repayments", bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	today := time.Now().Format("2006-01-02")
+
+	assert.Equal(t, 400, repay(map[string]interface{}{"amount": 10, "paid_at": today}).Code, "not disbursed yet")
+
+	_, _ = svc.DisburseLoan(ln.ID, loan.Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO012", DisbursementDate: time.Now()}, "https://link.pdf")
+
+	assert.Equal(t, 400, repay(map[string]interface{}{"amount": 10, "paid_at": "yesterday"}).Code)
+	assert.Equal(t, 400, repay(map[string]interface{}{"amount": -10, "paid_at": today}).Code)
+
+	w := repay(map[string]interface{}{"amount": "1010", "paid_at": today, "reference": "TRX"})
+	assert.Equal(t, 201, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"repaid"`)
+	assert.Contains(t, w.Body.String(), `"reference":"TRX"`)
+}
+
+func TestPayoutHandlers(t *testing.T) {
+	repo := loan.NewInMemoryLoanRepository()
+	svc := loan.NewLoanService(repo, email.NewMockEmailSender())
+	router := SetupRouter(NewHandler(svc))
+
+	ln, _ := svc.CreateLoan("B013", idr(1000), 12, 6, loan.WithRepaymentTerms(loan.RepaymentTerms{Method: loan.FlatMethod, Tenor: 1}))
+	_, _ = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP013", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV013", Amount: idr(1000)})
+	_, _ = svc.DisburseLoan(ln.ID, loan.Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO013", DisbursementDate: time.Now()}, "https://link.pdf")
+	_, _ = svc.RecordRepayment(ln.ID, loan.Repayment{Amount: idr(500), PaidAt: time.Now()})
+	_, err := outbox.NewDispatcher(repo.Outbox(), svc.DeliverNotification, time.Second).DispatchDue()
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/loans/" + ln.ID + "/payouts")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"investor_id":"INV013"`)
+
+	w = get("/investors/INV013/payouts")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"loan_id":"`+ln.ID+`"`)
+
+	w = get("/investors/nobody/payouts")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+
+	assert.Equal(t, 404, get("/loans/missing/payouts").Code)
+}
+
+func TestCloseLoanHandlers(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	post := func(path string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	proposed, _ := svc.CreateLoan("B014", idr(1000), 10, 8)
+	assert.Equal(t, 400, post("/loans/"+proposed.ID+"/reject", `{"staff_id":"EMP014"}`).Code)
+	w := post("/loans/"+proposed.ID+"/reject", `{"reason":"fake photo","staff_id":"EMP014"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"rejected"`)
+	assert.Contains(t, w.Body.String(), `"reason":"fake photo"`)
+	assert.Equal(t, 400, post("/loans/"+proposed.ID+"/cancel", `{"staff_id":"EMP014"}`).Code, "already closed")
+
+	approved, _ := svc.CreateLoan("B015", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(approved.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP015", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(approved.ID, loan.Investor{ID: "INV015", Amount: idr(400)})
+	assert.Equal(t, 400, post("/loans/"+approved.ID+"/cancel", `{}`).Code)
+	w = post("/loans/"+approved.ID+"/cancel", `{"staff_id":"EMP015"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"cancelled"`)
+	assert.Contains(t, w.Body.String(), `"status":"released"`)
+
+	expiring, _ := svc.CreateLoan("B016", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(expiring.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP016", ApprovalDate: time.Now()})
+	w = post("/loans/"+expiring.ID+"/expire", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"expired"`)
+
+	assert.Equal(t, 404, post("/loans/missing/expire", "").Code)
+	assert.Equal(t, 400, post("/loans/"+expiring.ID+"/expire", "{").Code)
+}
+
+func TestApproveLoanHandler_FundingDeadline(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	approve := func(id, deadline string) *httptest.ResponseRecorder {
+		body := `{"photo_proof_url":"proof","field_validator_id":"EMP017","approval_date":"2025-07-21","funding_deadline":"` + deadline + `"}`
+		req, _ := http.NewRequest("POST", "/loans/"+id+"/approve", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	ln, _ := svc.CreateLoan("B017", idr(1000), 10, 8)
+	assert.Equal(t, 400, approve(ln.ID, "next week").Code)
+	assert.Equal(t, 400, approve(ln.ID, "2001-01-01").Code, "deadline in the past")
+
+	day := time.Now().AddDate(0, 0, 7).UTC().Format("2006-01-02")
+	w := approve(ln.ID, day)
+	assert.Equal(t, 200, w.Code)
+
+	stored, _ := svc.GetLoan(ln.ID)
+	end, _ := time.Parse("2006-01-02", day)
+	assert.True(t, end.AddDate(0, 0, 1).Equal(*stored.FundingDeadline), "open through the whole day")
+
+	other, _ := svc.CreateLoan("B018", idr(1000), 10, 8)
+	deadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	assert.Equal(t, 200, approve(other.ID, deadline.Format(time.RFC3339)).Code)
+	stored, _ = svc.GetLoan(other.ID)
+	assert.True(t, deadline.Equal(*stored.FundingDeadline))
+}
+
+func TestInvestmentWithdrawalHandlers(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	ln, _ := svc.CreateLoan("B019", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP019", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV019", Amount: idr(600)})
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV020", Amount: idr(300)})
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	base := "/loans/" + ln.ID + "/investments/"
+
+	assert.Equal(t, 400, send("POST", base+"INV019/reduce", `{"amount":-1}`).Code)
+	assert.Equal(t, 400, send("POST", base+"INV019/reduce", `{"amount":601}`).Code)
+	w := send("POST", base+"INV019/reduce", `{"amount":"100"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_invested":{"amount":"800.00","currency":"IDR"}`)
+
+	w = send("DELETE", base+"INV020", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"total_invested":{"amount":"500.00","currency":"IDR"}`)
+
+	assert.Equal(t, 404, send("DELETE", base+"INV020", "").Code)
+	assert.Equal(t, 404, send("DELETE", "/loans/missing/investments/INV019", "").Code)
+
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV021", Amount: idr(500)})
+	assert.Equal(t, 400, send("DELETE", base+"INV019", "").Code, "fully funded")
+}
+
+func TestGetHistoryHandler(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	ln, _ := svc.CreateLoan("B021", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP021", ApprovalDate: time.Now()})
+
+	req, _ := http.NewRequest("GET", "/loans/"+ln.ID+"/history", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+
+	var events []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "created", events[0]["action"])
+		assert.Equal(t, "approved", events[1]["action"])
+		assert.Equal(t, "EMP021", events[1]["actor"])
+		assert.Equal(t, "proposed", events[1]["previous_state"])
+		assert.Equal(t, "approved", events[1]["new_state"])
+	}
+
+	req, _ = http.NewRequest("GET", "/loans/missing/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestNotificationHandlers(t *testing.T) {
+	router, svc := setupRouterWithMemoryService()
+
+	ln, _ := svc.CreateLoan("B022", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP022", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, loan.Investor{ID: "INV022", Amount: idr(1000)})
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/notifications?status=pending")
+	assert.Equal(t, 200, w.Code)
+	var pending []outbox.Message
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	var kinds []string
+	for _, msg := range pending {
+		kinds = append(kinds, msg.Kind)
+	}
+	assert.Equal(t, []string{loan.ApprovedNotification, loan.InvestmentNotification, loan.FundedNotification, loan.FundedNotification}, kinds)
+	if assert.Len(t, pending, 4) {
+		assert.Contains(t, string(pending[3].Payload), `"recipient":{"role":"investor","id":"INV022"}`)
+
+		assert.Equal(t, 409, send("POST", "/notifications/"+pending[3].ID+"/retry").Code, "not failed")
+	}
+
+	assert.Equal(t, "[]", send("GET", "/notifications?status=failed").Body.String())
+	assert.Equal(t, 400, send("GET", "/notifications?status=lost").Code)
+	assert.Equal(t, 404, send("POST", "/notifications/missing/retry").Code)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each readiness check, so a hung dependency cannot hang the probe.
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck is a dependency GET /readyz checks, such as the database or the mail server.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error // Returns an error while the dependency cannot be used
+}
+
+// WithReadinessCheck makes GET /readyz report the service as not ready while check fails.
+func WithReadinessCheck(name string, check func(ctx context.Context) error) HandlerOption {
+	return func(h *Handler) {
+		h.Readiness = append(h.Readiness, ReadinessCheck{Name: name, Check: check})
+	}
+}
+
+// Healthz handles GET /healthz. It answers as long as the process is serving requests,
+// whatever the state of its dependencies.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz handles GET /readyz. It runs the readiness checks concurrently and answers 200 if they
+// all pass and 503 otherwise, with the outcome of each check by name.
+func (h *Handler) Readyz(c *gin.Context) {
+	results := make([]error, len(h.Readiness))
+	var wg sync.WaitGroup
+	for i, check := range h.Readiness {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+			defer cancel()
+			results[i] = check.Check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	checks := gin.H{}
+	for i, check := range h.Readiness {
+		checks[check.Name] = "ok"
+		if err := results[i]; err != nil {
+			checks[check.Name] = err.Error()
+			status, code = "not ready", http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
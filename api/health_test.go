@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/auth"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+func setupRouterWithReadiness(t *testing.T, opts ...HandlerOption) *gin.Engine {
+	verifier, err := auth.NewHS256Verifier(testSecret)
+	require.NoError(t, err)
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender())
+	return SetupRouter(NewHandler(svc, append(opts, WithAuth(verifier))...))
+}
+
+func probe(router *gin.Engine, path string) (int, map[string]any) {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var body map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestHealthz(t *testing.T) {
+	router := setupRouterWithReadiness(t, WithReadinessCheck("database", func(context.Context) error {
+		return errors.New("connection refused")
+	}))
+
+	code, body := probe(router, "/healthz")
+	assert.Equal(t, http.StatusOK, code, "probes need no token, and liveness ignores dependencies")
+	assert.Equal(t, "ok", body["status"])
+}
+
+func TestReadyz(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connect to SMTP server: connection refused") }
+	hung := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	cases := []struct {
+		name   string
+		opts   []HandlerOption
+		code   int
+		status string
+		checks map[string]any
+	}{
+		{"no checks", nil, http.StatusOK, "ready", map[string]any{}},
+		{"all pass", []HandlerOption{WithReadinessCheck("database", ok), WithReadinessCheck("mail", ok)},
+			http.StatusOK, "ready", map[string]any{"database": "ok", "mail": "ok"}},
+		{"one fails", []HandlerOption{WithReadinessCheck("database", ok), WithReadinessCheck("mail", failing)},
+			http.StatusServiceUnavailable, "not ready", map[string]any{"database": "ok", "mail": "connect to SMTP server: connection refused"}},
+		{"one hangs", []HandlerOption{WithReadinessCheck("database", hung)},
+			http.StatusServiceUnavailable, "not ready", map[string]any{"database": "context deadline exceeded"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, body := probe(setupRouterWithReadiness(t, tc.opts...), "/readyz")
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.status, body["status"])
+			assert.Equal(t, tc.checks, body["checks"])
+		})
+	}
+}
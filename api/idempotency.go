@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"loan-service/core/idempotency"
+)
+
+// IdempotencyKeyHeader lets clients retry a POST safely: a request repeating the key of an earlier
+// one to the same URL gets the earlier response instead of being executed again.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayHeader marks responses that were replayed from an earlier request.
+const idempotentReplayHeader = "Idempotent-Replayed"
+
+// replayedHeaders are the response headers stored with an idempotent response.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// maxBufferedBody is the largest JSON body buffered to fingerprint a request. Uploads may be as large as
+// the documents limit, plus this much for their multipart framing (see Handler.maxIdempotentBody).
+const maxBufferedBody = 1 << 20
+
+// idempotent makes POST requests carrying an Idempotency-Key header run at most once per key and URL.
+// Authenticated callers each have their own keys.
+//
+//   - A retry with the same body gets the stored response, marked with an Idempotent-Replayed header.
+//   - Reusing the key with a different body is rejected with 422.
+//   - A retry while the first request is still running is rejected with 409.
+//
+// Server errors (5xx) are not stored, so the request can be retried with the same key.
+// Bodies longer than maxBody bytes are rejected with 413 instead of being buffered.
+func idempotent(store idempotency.Store, maxBody int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body is over %d bytes", maxBody)})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.Request.Method + " " + c.Request.URL.Path
+		if p, ok := principal(c); ok {
+			scope += " " + p.Subject
+		}
+		rec := idempotency.Record{
+			Scope:       scope,
+			Key:         key,
+			Fingerprint: fingerprint(string(body)),
+			CreatedAt:   time.Now(),
+		}
+		existing, claimed, err := store.Begin(rec)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check Idempotency-Key"})
+			return
+		}
+		if !claimed {
+			switch {
+			case existing.Fingerprint != rec.Fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case existing.InProgress():
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still in progress"})
+			default:
+				for name, value := range existing.Header {
+					c.Header(name, value)
+				}
+				c.Header(idempotentReplayHeader, "true")
+				c.Data(existing.Status, existing.Header["Content-Type"], existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		// Give the key back if the handler panics or fails, so the client can retry with it.
+		completed := false
+		defer func() {
+			if !completed {
+				if err := store.Release(rec.Scope, rec.Key); err != nil {
+					log.Printf("[IDEMPOTENCY] %v", err)
+				}
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+		rec.Status = recorder.Status()
+		rec.Body = recorder.body.Bytes()
+		rec.Header = make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				rec.Header[name] = value
+			}
+		}
+		if err := store.Complete(rec); err != nil {
+			log.Printf("[IDEMPOTENCY] %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// fingerprint identifies a request body, so a reused key can be told apart from a retry.
+func fingerprint(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder keeps a copy of the response body while writing it to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// maxIdempotentBody returns the largest body the idempotency middleware buffers: room for a document
+// upload when the /documents routes are served, and maxBufferedBody otherwise.
+func (h *Handler) maxIdempotentBody() int64 {
+	if h.Documents == nil {
+		return maxBufferedBody
+	}
+	return h.Documents.MaxSize() + maxBufferedBody
+}
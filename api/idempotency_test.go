@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/idempotency"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	store := idempotency.NewInMemoryStore()
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender())
+	router := SetupRouter(NewHandler(svc, WithIdempotencyStore(store)))
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	count := func() int {
+		loans, err := svc.ListLoans()
+		require.NoError(t, err)
+		return len(loans)
+	}
+	create := `{"borrower_id":"B040","principal_amount":1000,"rate":10,"roi":8}`
+
+	t.Run("retry replays the first response", func(t *testing.T) {
+		first := send("/loans", "create-1", create)
+		require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		retry := send("/loans", "create-1", create)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+		assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("reused key with a different body", func(t *testing.T) {
+		w := send("/loans", "create-1", `{"borrower_id":"B041","principal_amount":1000,"rate":10,"roi":8}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "different request")
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("requests without a key are not deduplicated", func(t *testing.T) {
+		before := count()
+		assert.Equal(t, http.StatusCreated, send("/loans", "", create).Code)
+		assert.Equal(t, http.StatusCreated, send("/loans", "", create).Code)
+		assert.Equal(t, before+2, count())
+	})
+
+	t.Run("request still in progress", func(t *testing.T) {
+		_, claimed, err := store.Begin(idempotency.Record{Scope: "POST /loans", Key: "create-2", Fingerprint: fingerprint(create), CreatedAt: time.Now()})
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		assert.Equal(t, http.StatusConflict, send("/loans", "create-2", create).Code)
+	})
+
+	t.Run("key is too long", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("/loans", strings.Repeat("k", 256), create).Code)
+	})
+
+	t.Run("body is too large to buffer", func(t *testing.T) {
+		before := count()
+		w := send("/loans", "create-3", `{"borrower_id":"B043","principal_amount":1000,"rate":10,"roi":8,"padding":"`+strings.Repeat("x", maxBufferedBody)+`"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Equal(t, before, count())
+	})
+
+	t.Run("keys are scoped to the URL", func(t *testing.T) {
+		ln, err := svc.CreateLoan("B042", idr(1000), 10, 8)
+		require.NoError(t, err)
+		approve := `{"photo_proof_url":"proof","field_validator_id":"EMP040","approval_date":"` + time.Now().Format("2006-01-02") + `"}`
+		require.Equal(t, http.StatusOK, send("/loans/"+ln.ID+"/approve", "shared", approve).Code)
+
+		invest := `{"investor_id":"INV040","amount":400}`
+		w := send("/loans/"+ln.ID+"/invest", "shared", invest)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+
+		w = send("/loans/"+ln.ID+"/invest", "shared", invest)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+
+		got, err := svc.GetLoan(ln.ID)
+		require.NoError(t, err)
+		assert.Len(t, got.Investors, 1)
+	})
+
+	t.Run("failed requests are not kept", func(t *testing.T) {
+		failing := gin.New()
+		failing.Use(idempotent(store, maxBufferedBody))
+		calls := 0
+		failing.POST("/flaky", func(c *gin.Context) {
+			if calls++; calls == 1 {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "try again"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"calls": calls})
+		})
+		post := func() *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("POST", "/flaky", strings.NewReader("{}"))
+			req.Header.Set(IdempotencyKeyHeader, "flaky")
+			w := httptest.NewRecorder()
+			failing.ServeHTTP(w, req)
+			return w
+		}
+
+		assert.Equal(t, http.StatusServiceUnavailable, post().Code)
+		assert.Equal(t, http.StatusOK, post().Code)
+		w := post()
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"calls":2}`, w.Body.String())
+		assert.Equal(t, 2, calls)
+	})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"loan-service/core/auth"
+	"loan-service/core/investor"
+	"loan-service/core/money"
+)
+
+// RegisterInvestor handles POST /investors
+// An investor registers themselves; `id` is only read from admins and anonymous requests.
+// New investors have a pending KYC status and cannot invest until it is verified.
+func (h *Handler) RegisterInvestor(c *gin.Context) {
+	var req struct {
+		ID    string `json:"id"`
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	inv, err := h.Investors.Register(onBehalfOf(c, auth.Investor, req.ID), req.Name, req.Email)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusCreated, inv)
+}
+
+// ListInvestors handles GET /investors
+func (h *Handler) ListInvestors(c *gin.Context) {
+	list, err := h.Investors.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list investors"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetInvestor handles GET /investors/:id
+func (h *Handler) GetInvestor(c *gin.Context) {
+	inv, err := h.Investors.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
+
+// SetInvestorKYC handles POST /investors/:id/kyc
+// `status` is pending, verified or rejected.
+func (h *Handler) SetInvestorKYC(c *gin.Context) {
+	var req struct {
+		Status investor.KYCStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	inv, err := h.Investors.SetKYCStatus(c.Param("id"), req.Status)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
+
+// SetInvestorLimits handles PUT /investors/:id/limits
+// `exposure_cap`, `min_ticket` and `max_ticket` are optional amounts in `currency` (default IDR);
+// a limit that is left out no longer applies.
+func (h *Handler) SetInvestorLimits(c *gin.Context) {
+	var req struct {
+		Currency    string      `json:"currency"`
+		ExposureCap json.Number `json:"exposure_cap"`
+		MinTicket   json.Number `json:"min_ticket"`
+		MaxTicket   json.Number `json:"max_ticket"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	var limits investor.Limits
+	for _, field := range []struct {
+		value json.Number
+		limit **money.Money
+	}{
+		{req.ExposureCap, &limits.ExposureCap},
+		{req.MinTicket, &limits.MinTicket},
+		{req.MaxTicket, &limits.MaxTicket},
+	} {
+		if field.value == "" {
+			continue
+		}
+		amount, err := parseAmount(field.value, req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		*field.limit = &amount
+	}
+
+	inv, err := h.Investors.SetLimits(c.Param("id"), limits)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
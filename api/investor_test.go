@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/investor"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+func setupRouterWithInvestors() (*gin.Engine, *loan.LoanService) {
+	investors := investor.NewService(investor.NewInMemoryRepository())
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender(), loan.WithInvestorRegistry(investors))
+	return SetupRouter(NewHandler(svc, WithInvestors(investors))), svc
+}
+
+func TestInvestorHandlers(t *testing.T) {
+	router, svc := setupRouterWithInvestors()
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/investors", gin.H{"id": "INV050", "name": "Ani", "email": "ani@example.com"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"kyc_status":"pending"`)
+	assert.Equal(t, http.StatusConflict, send("POST", "/investors", gin.H{"id": "INV050", "name": "Ani"}).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/investors", gin.H{"name": "Nobody"}).Code)
+
+	ln, err := svc.CreateLoan("B050", idr(5000), 10, 8)
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP050", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	invest := func(investorID string, amount int) *httptest.ResponseRecorder {
+		return send("POST", "/loans/"+ln.ID+"/invest", gin.H{"investor_id": investorID, "amount": amount})
+	}
+
+	w = invest("INV999", 1000)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "investor not found")
+	w = invest("INV050", 1000)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "not KYC verified")
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/investors/INV050/kyc", gin.H{"status": "approved"}).Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", "/investors/INV999/kyc", gin.H{"status": "verified"}).Code)
+	w = send("POST", "/investors/INV050/kyc", gin.H{"status": "verified"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"kyc_status":"verified"`)
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/investors/INV050/limits", gin.H{"min_ticket": 3000, "max_ticket": 2000}).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/investors/INV050/limits", gin.H{"max_ticket": "abc"}).Code)
+	w = send("PUT", "/investors/INV050/limits", gin.H{"min_ticket": 500, "max_ticket": "2000.00", "exposure_cap": 4000})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"max_ticket":{"amount":"2000.00","currency":"IDR"}`)
+
+	w = invest("INV050", 2500)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "at most IDR 2000.00")
+	assert.Equal(t, http.StatusOK, invest("INV050", 2000).Code)
+
+	w = send("GET", "/investors/INV050", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Ani"`)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/investors/INV999", nil).Code)
+
+	w = send("GET", "/investors", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"INV050"`)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"loan-service/core/investor"
+	"loan-service/core/loan"
+	"loan-service/core/money"
+)
+
+// instruments are the metrics the API records to.
+type instruments struct {
+	requests          *prometheus.CounterVec
+	latency           *prometheus.HistogramVec
+	failedInvestments *prometheus.CounterVec
+}
+
+// Reasons an investment attempt fails, as counted in loan_investment_failures_total.
+const (
+	invalidRequest        = "invalid_request"         // The request could not be read
+	loanNotFound          = "loan_not_found"          // No loan has the ID
+	versionConflict       = "version_conflict"        // If-Match named a stale version
+	notOpenForInvestment  = "not_open"                // The loan is not Approved or Invested
+	overInvestment        = "exceeds_principal"       // The amount is more than the loan still needs
+	investorNotRegistered = "investor_not_registered" // The investor registry does not know the investor
+	investorNotVerified   = "investor_not_verified"   // The investor has not passed KYC
+	investorLimitExceeded = "investor_limit_exceeded" // The amount is over the investor's limits
+	otherFailure          = "other"
+)
+
+// WithMetrics serves GET /metrics from registry, after adding to it:
+//   - http_requests_total and http_request_duration_seconds, per method, route and status
+//   - loans, by state, and the principal outstanding and being raised, by currency, read at each scrape
+//   - loan_investment_failures_total, by reason
+func WithMetrics(registry *prometheus.Registry) HandlerOption {
+	return func(h *Handler) {
+		h.Metrics = registry
+		factory := promauto.With(registry)
+		h.instruments = &instruments{
+			requests: factory.NewCounterVec(prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "HTTP requests served, by method, route and status.",
+			}, []string{"method", "route", "status"}),
+			latency: factory.NewHistogramVec(prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Time taken to serve HTTP requests, by method and route.",
+				Buckets: prometheus.DefBuckets,
+			}, []string{"method", "route"}),
+			failedInvestments: factory.NewCounterVec(prometheus.CounterOpts{
+				Name: "loan_investment_failures_total",
+				Help: "Investment attempts that were turned down, by reason.",
+			}, []string{"reason"}),
+		}
+		for _, reason := range []string{invalidRequest, loanNotFound, versionConflict, notOpenForInvestment, overInvestment,
+			investorNotRegistered, investorNotVerified, investorLimitExceeded, otherFailure} {
+			h.instruments.failedInvestments.WithLabelValues(reason)
+		}
+		registry.MustRegister(newLoanCollector(h.Service))
+	}
+}
+
+// loanCollector reports gauges describing the loan book, read from the service at each scrape.
+type loanCollector struct {
+	service     *loan.LoanService
+	loans       *prometheus.Desc
+	outstanding *prometheus.Desc
+	raising     *prometheus.Desc
+	committed   *prometheus.Desc
+	progress    *prometheus.Desc
+}
+
+func newLoanCollector(service *loan.LoanService) *loanCollector {
+	byCurrency := []string{"currency"}
+	return &loanCollector{
+		service:     service,
+		loans:       prometheus.NewDesc("loans", "Loans in each state.", []string{"state"}, nil),
+		outstanding: prometheus.NewDesc("loan_principal_outstanding", "Principal still owed on disbursed loans.", byCurrency, nil),
+		raising:     prometheus.NewDesc("loan_funding_principal", "Principal of approved loans raising money from investors.", byCurrency, nil),
+		committed:   prometheus.NewDesc("loan_funding_committed", "Money committed by investors to approved loans.", byCurrency, nil),
+		progress:    prometheus.NewDesc("loan_funding_progress_ratio", "Share of the principal of approved loans that is committed.", byCurrency, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *loanCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{c.loans, c.outstanding, c.raising, c.committed, c.progress} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector. If the stats cannot be read, the scrape reports the error
+// and goes without the loan gauges.
+func (c *loanCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.service.Stats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.loans, err)
+		return
+	}
+	gauge := func(desc *prometheus.Desc, v float64, label string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, label)
+	}
+	for state, n := range stats.Loans {
+		gauge(c.loans, float64(n), string(state))
+	}
+	for currency, amount := range stats.Outstanding {
+		gauge(c.outstanding, majorUnits(amount), string(currency))
+	}
+	for currency, f := range stats.Funding {
+		gauge(c.raising, majorUnits(f.Principal), string(currency))
+		gauge(c.committed, majorUnits(f.Committed), string(currency))
+		if f.Principal.IsPositive() {
+			gauge(c.progress, majorUnits(f.Committed)/majorUnits(f.Principal), string(currency))
+		}
+	}
+}
+
+// serveMetrics serves the metrics in registry for scraping. Metrics that fail to be collected are
+// logged and left out, so the others are still scraped.
+func serveMetrics(registry *prometheus.Registry) gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog:      log.New(log.Writer(), "[METRICS] ", log.LstdFlags),
+		ErrorHandling: promhttp.ContinueOnError,
+	}))
+}
+
+// majorUnits converts an amount to a number of major units. Precision is lost, which is fine for monitoring.
+func majorUnits(m money.Money) float64 {
+	f, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return f
+}
+
+// instrument is middleware counting requests and timing them. Requests that match no route
+// are counted under the route "unmatched", so scanners cannot create a series per path.
+func instrument(in *instruments) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if in == nil {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		in.requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		in.latency.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// investmentFailed counts a turned-down investment, if metrics are enabled.
+func (h *Handler) investmentFailed(err error) {
+	if h.instruments != nil {
+		h.instruments.failedInvestments.WithLabelValues(investmentFailure(err)).Inc()
+	}
+}
+
+// investmentFailure returns the reason an investment attempt failed with err; nil means the request could not be read.
+func investmentFailure(err error) string {
+	switch {
+	case err == nil:
+		return invalidRequest
+	case errors.Is(err, loan.ErrLoanNotFound):
+		return loanNotFound
+	case errors.Is(err, loan.ErrVersionConflict):
+		return versionConflict
+	case errors.Is(err, loan.ErrNotOpenForInvestment):
+		return notOpenForInvestment
+	case errors.Is(err, loan.ErrOverInvestment):
+		return overInvestment
+	case errors.Is(err, investor.ErrInvestorNotFound):
+		return investorNotRegistered
+	case errors.Is(err, investor.ErrNotVerified):
+		return investorNotVerified
+	case errors.Is(err, investor.ErrLimitExceeded):
+		return investorLimitExceeded
+	default:
+		return otherFailure
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/investor"
+	"loan-service/core/loan"
+	"loan-service/email"
+)
+
+func setupRouterWithMetrics() (*gin.Engine, *loan.LoanService) {
+	investors := investor.NewService(investor.NewInMemoryRepository())
+	svc := loan.NewLoanService(loan.NewInMemoryLoanRepository(), email.NewMockEmailSender(), loan.WithInvestorRegistry(investors))
+	return SetupRouter(NewHandler(svc, WithInvestors(investors), WithMetrics(prometheus.NewRegistry()))), svc
+}
+
+func TestMetrics(t *testing.T) {
+	router, svc := setupRouterWithMetrics()
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	ln, err := svc.CreateLoan("B001", idr(1000000), 10, 8)
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP001", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = svc.CreateLoan("B002", idr(500000), 10, 8)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, send("POST", "/investors", map[string]any{"id": "INV001", "name": "Ayu", "email": "ayu@example.com"}).Code)
+	require.Equal(t, http.StatusOK, send("POST", "/investors/INV001/kyc", map[string]any{"status": "verified"}).Code)
+
+	invest := func(loanID string, body map[string]any) int {
+		return send("POST", "/loans/"+loanID+"/invest", body).Code
+	}
+	assert.Equal(t, http.StatusOK, invest(ln.ID, map[string]any{"investor_id": "INV001", "amount": 250000}))
+	assert.Equal(t, http.StatusUnprocessableEntity, invest(ln.ID, map[string]any{"investor_id": "INV001", "amount": 2000000}))
+	assert.Equal(t, http.StatusNotFound, invest(ln.ID, map[string]any{"investor_id": "INV404", "amount": 1000}))
+	assert.Equal(t, http.StatusNotFound, invest("missing", map[string]any{"investor_id": "INV001", "amount": 1000}))
+	assert.Equal(t, http.StatusBadRequest, invest(ln.ID, map[string]any{"amount": "lots"}))
+	send("GET", "/loans/"+ln.ID, nil)
+	send("GET", "/wp-admin/install.php", nil)
+
+	w := send("GET", "/metrics", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	body := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="POST",route="/loans/:id/invest",status="200"} 1`,
+		`http_requests_total{method="POST",route="/loans/:id/invest",status="400"} 1`,
+		`http_requests_total{method="POST",route="/loans/:id/invest",status="422"} 1`,
+		`http_requests_total{method="POST",route="/loans/:id/invest",status="404"} 2`,
+		`http_requests_total{method="GET",route="/loans/:id",status="200"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/loans/:id/invest"} 5`,
+		`loan_investment_failures_total{reason="exceeds_principal"} 1`,
+		`loan_investment_failures_total{reason="investor_not_registered"} 1`,
+		`loan_investment_failures_total{reason="loan_not_found"} 1`,
+		`loan_investment_failures_total{reason="invalid_request"} 1`,
+		`loan_investment_failures_total{reason="investor_not_verified"} 0`,
+		`loans{state="proposed"} 1`,
+		`loans{state="approved"} 1`,
+		`loans{state="repaid"} 0`,
+		`loan_funding_principal{currency="IDR"} 1e+06`,
+		`loan_funding_committed{currency="IDR"} 250000`,
+		`loan_funding_progress_ratio{currency="IDR"} 0.25`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "wp-admin")
+}
+
+func TestMetrics_StatsFailure(t *testing.T) {
+	svc := loan.NewLoanService(&brokenRepoList{}, email.NewMockEmailSender())
+	router := SetupRouter(NewHandler(svc, WithMetrics(prometheus.NewRegistry())))
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, "the other metrics are still served")
+	assert.Contains(t, w.Body.String(), `loan_investment_failures_total{reason="other"} 0`+"\n")
+	assert.NotContains(t, w.Body.String(), "loans{")
+}
+
+func TestMetrics_NoToken(t *testing.T) {
+	router := setupRouterWithReadiness(t, WithMetrics(prometheus.NewRegistry()))
+	code, _ := probe(router, "/metrics")
+	assert.Equal(t, http.StatusOK, code, "the scraper has no token")
+}
+
+func TestInvestmentFailure(t *testing.T) {
+	cases := []struct {
+		err    error
+		reason string
+	}{
+		{nil, "invalid_request"},
+		{loan.ErrLoanNotFound, "loan_not_found"},
+		{&loan.ConflictError{LoanID: "L1", Expected: 1, Actual: 2}, "version_conflict"},
+		{loan.ErrNotOpenForInvestment, "not_open"},
+		{loan.ErrOverInvestment, "exceeds_principal"},
+		{investor.ErrInvestorNotFound, "investor_not_registered"},
+		{investor.ErrNotVerified, "investor_not_verified"},
+		{&investor.LimitError{InvestorID: "INV001"}, "investor_limit_exceeded"},
+		{errors.New("investment amount must be positive"), "other"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.reason, investmentFailure(tc.err), "%v", tc.err)
+	}
+}
+
+func TestRouterRoutes_Metrics(t *testing.T) {
+	router, _ := setupRouterWithMetrics()
+	var registered []string
+	for _, r := range router.Routes() {
+		registered = append(registered, r.Method+" "+r.Path)
+	}
+	assert.Contains(t, registered, "GET /metrics")
+
+	router, _ = setupRouterWithMemoryService()
+	for _, r := range router.Routes() {
+		assert.NotEqual(t, "/metrics", r.Path)
+	}
+}
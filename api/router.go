@@ -2,18 +2,75 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"loan-service/core/auth"
 )
 
 // SetupRouter initializes all HTTP routes.
+// With authentication enabled (see WithAuth) each route is limited to the roles that may use it.
+// Reads are open to every authenticated caller, but the handlers, GET /loans included, only show
+// borrowers and investors the loans and documents they are party to, and investors the loans open
+// for funding. The health probes and metrics come first so that they
+// stay open to the orchestrator and the metrics scraper, which have no token.
 func SetupRouter(handler *Handler) *gin.Engine {
 	r := gin.Default()
+	r.Use(instrument(handler.instruments))
+	r.GET("/healthz", handler.Healthz)
+	r.GET("/readyz", handler.Readyz)
+	if handler.Metrics != nil {
+		r.GET("/metrics", serveMetrics(handler.Metrics))
+	}
 
-	r.GET("/loans", handler.ListLoans)
-	r.GET("/loans/:id", handler.GetLoan)
-	r.POST("/loans", handler.CreateLoan)
-	r.POST("/loans/:id/approve", handler.ApproveLoan)
-	r.POST("/loans/:id/invest", handler.InvestLoan)
-	r.POST("/loans/:id/disburse", handler.DisburseLoan)
+	r.Use(authenticate(handler.Auth), idempotent(handler.Idempotency, handler.maxIdempotentBody()))
+
+	r.GET("/loans", allow(), handler.ListLoans)
+	r.GET("/loans/:id", allow(), handler.GetLoan)
+	r.GET("/loans/:id/schedule", allow(), handler.GetSchedule)
+	r.GET("/loans/:id/payouts", allow(), handler.ListLoanPayouts)
+	r.GET("/loans/:id/history", allow(), handler.GetHistory)
+	r.GET("/investors/:id/payouts", allow(auth.Investor, auth.Admin), self(auth.Investor, "id"), handler.ListInvestorPayouts)
+	r.GET("/notifications", allow(auth.Admin), handler.ListNotifications)
+	r.POST("/loans", allow(auth.Borrower, auth.Admin), handler.CreateLoan)
+	r.POST("/loans/:id/approve", allow(auth.FieldValidator), handler.ApproveLoan)
+	r.POST("/loans/:id/invest", allow(auth.Investor), handler.InvestLoan)
+	r.POST("/loans/:id/investments/:investorId/reduce", allow(auth.Investor, auth.Admin), self(auth.Investor, "investorId"), handler.ReduceInvestment)
+	r.DELETE("/loans/:id/investments/:investorId", allow(auth.Investor, auth.Admin), self(auth.Investor, "investorId"), handler.WithdrawInvestment)
+	r.POST("/loans/:id/disburse", allow(auth.FieldOfficer), handler.DisburseLoan)
+	r.POST("/loans/:id/repayments", allow(auth.FieldOfficer, auth.Admin), handler.RecordRepayment)
+	r.POST("/loans/:id/reject", allow(auth.FieldValidator, auth.Admin), handler.RejectLoan)
+	r.POST("/loans/:id/cancel", allow(auth.FieldValidator, auth.Admin), handler.CancelLoan)
+	r.POST("/loans/:id/expire", allow(auth.Admin), handler.ExpireLoan)
+	r.POST("/notifications/:id/retry", allow(auth.Admin), handler.RetryNotification)
+
+	if handler.Investors != nil {
+		r.GET("/investors", allow(auth.Admin), handler.ListInvestors)
+		r.GET("/investors/:id", allow(auth.Investor, auth.Admin), self(auth.Investor, "id"), handler.GetInvestor)
+		r.POST("/investors", allow(auth.Investor, auth.Admin), handler.RegisterInvestor)
+		r.POST("/investors/:id/kyc", allow(auth.Admin), handler.SetInvestorKYC)
+		r.PUT("/investors/:id/limits", allow(auth.Admin), handler.SetInvestorLimits)
+	}
+
+	if handler.Borrowers != nil {
+		r.GET("/borrowers", allow(auth.Admin), handler.ListBorrowers)
+		r.GET("/borrowers/:id", allow(auth.Borrower, auth.Admin), self(auth.Borrower, "id"), handler.GetBorrower)
+		r.POST("/borrowers", allow(auth.Borrower, auth.Admin), handler.RegisterBorrower)
+		r.PUT("/borrowers/:id/profile", allow(auth.Borrower, auth.Admin), self(auth.Borrower, "id"), handler.UpdateBorrowerProfile)
+		r.PUT("/borrowers/:id/credit-limit", allow(auth.Admin), handler.SetBorrowerCreditLimit)
+	}
+
+	if handler.Documents != nil {
+		r.GET("/documents/:id", allow(), handler.GetDocument)
+		r.GET("/documents/:id/content", allow(), handler.GetDocumentContent)
+		r.POST("/documents", allow(auth.FieldValidator, auth.FieldOfficer, auth.Admin), handler.UploadDocument)
+	}
+
+	if handler.Webhooks != nil {
+		admin := r.Group("/webhooks", allow(auth.Admin))
+		admin.GET("", handler.ListWebhooks)
+		admin.GET("/:id/deliveries", handler.ListWebhookDeliveries)
+		admin.POST("", handler.CreateWebhook)
+		admin.POST("/:id/deliveries/:deliveryId/replay", handler.ReplayWebhookDelivery)
+		admin.DELETE("/:id", handler.DeleteWebhook)
+	}
 
 	return r
 }
@@ -10,12 +10,26 @@ func TestRouterRoutes(t *testing.T) {
 	routes := router.Routes()
 
 	expected := []string{
+		"GET /healthz",
+		"GET /readyz",
 		"GET /loans",
 		"GET /loans/:id",
+		"GET /loans/:id/schedule",
+		"GET /loans/:id/payouts",
+		"GET /loans/:id/history",
+		"GET /investors/:id/payouts",
+		"GET /notifications",
 		"POST /loans",
 		"POST /loans/:id/approve",
 		"POST /loans/:id/invest",
+		"POST /loans/:id/investments/:investorId/reduce",
+		"DELETE /loans/:id/investments/:investorId",
 		"POST /loans/:id/disburse",
+		"POST /loans/:id/repayments",
+		"POST /loans/:id/reject",
+		"POST /loans/:id/cancel",
+		"POST /loans/:id/expire",
+		"POST /notifications/:id/retry",
 	}
 
 	for _, route := range expected {
@@ -29,3 +43,73 @@ func TestRouterRoutes(t *testing.T) {
 		assert.True(t, found, "Route missing: "+route)
 	}
 }
+
+func TestRouterRoutes_Webhooks(t *testing.T) {
+	router, _, _ := setupRouterWithWebhooks()
+	var registered []string
+	for _, r := range router.Routes() {
+		registered = append(registered, r.Method+" "+r.Path)
+	}
+
+	for _, route := range []string{
+		"GET /webhooks",
+		"GET /webhooks/:id/deliveries",
+		"POST /webhooks",
+		"POST /webhooks/:id/deliveries/:deliveryId/replay",
+		"DELETE /webhooks/:id",
+	} {
+		assert.Contains(t, registered, route)
+	}
+}
+
+func TestRouterRoutes_Investors(t *testing.T) {
+	router, _ := setupRouterWithInvestors()
+	var registered []string
+	for _, r := range router.Routes() {
+		registered = append(registered, r.Method+" "+r.Path)
+	}
+
+	for _, route := range []string{
+		"GET /investors",
+		"GET /investors/:id",
+		"POST /investors",
+		"POST /investors/:id/kyc",
+		"PUT /investors/:id/limits",
+	} {
+		assert.Contains(t, registered, route)
+	}
+}
+
+func TestRouterRoutes_Borrowers(t *testing.T) {
+	router, _ := setupRouterWithBorrowers()
+	var registered []string
+	for _, r := range router.Routes() {
+		registered = append(registered, r.Method+" "+r.Path)
+	}
+
+	for _, route := range []string{
+		"GET /borrowers",
+		"GET /borrowers/:id",
+		"POST /borrowers",
+		"PUT /borrowers/:id/profile",
+		"PUT /borrowers/:id/credit-limit",
+	} {
+		assert.Contains(t, registered, route)
+	}
+}
+
+func TestRouterRoutes_Documents(t *testing.T) {
+	router, _ := setupRouterWithDocuments()
+	var registered []string
+	for _, r := range router.Routes() {
+		registered = append(registered, r.Method+" "+r.Path)
+	}
+
+	for _, route := range []string{
+		"GET /documents/:id",
+		"GET /documents/:id/content",
+		"POST /documents",
+	} {
+		assert.Contains(t, registered, route)
+	}
+}
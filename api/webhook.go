@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"loan-service/core/loan"
+)
+
+// CreateWebhook handles POST /webhooks
+// It subscribes `url` to the listed `events`. The response holds the signing secret, which is not shown again.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req struct {
+		URL    string           `json:"url" binding:"required"`
+		Events []loan.EventType `json:"events" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	sub, err := h.Webhooks.Subscribe(req.URL, req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	subs, err := h.Webhooks.ListSubscriptions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	if err := h.Webhooks.Unsubscribe(c.Param("id")); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries handles GET /webhooks/:id/deliveries
+// It returns the delivery log of a subscription, oldest first.
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	deliveries, err := h.Webhooks.ListDeliveries(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayWebhookDelivery handles POST /webhooks/:id/deliveries/:deliveryId/replay
+// It queues the delivery's event to be sent again and returns the new delivery.
+func (h *Handler) ReplayWebhookDelivery(c *gin.Context) {
+	delivery, err := h.Webhooks.Replay(c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, delivery)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+	"loan-service/core/webhook"
+	"loan-service/email"
+)
+
+func setupRouterWithWebhooks() (*gin.Engine, *loan.LoanService, loan.LoanRepository) {
+	repo := loan.NewInMemoryLoanRepository()
+	webhooks := webhook.NewService(webhook.NewInMemoryRepository())
+	svc := loan.NewLoanService(repo, email.NewMockEmailSender(), loan.WithEventPublisher(webhooks))
+	return SetupRouter(NewHandler(svc, WithWebhooks(webhooks))), svc, repo
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	router, svc, repo := setupRouterWithWebhooks()
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/webhooks", gin.H{"url": "https://partner.example.com/hook", "events": []string{"loan.approved", "loan.funded"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var sub webhook.Subscription
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sub))
+	assert.NotEmpty(t, sub.Secret)
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/webhooks", gin.H{"url": "https://partner.example.com/hook"}).Code)
+	w = send("POST", "/webhooks", gin.H{"url": "https://partner.example.com/hook", "events": []string{"loan.repaid"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown event type`)
+
+	w = send("GET", "/webhooks", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), sub.ID)
+	assert.NotContains(t, w.Body.String(), sub.Secret)
+
+	// Approving a loan publishes loan.approved once the outbox is dispatched.
+	ln, _ := svc.CreateLoan("B030", idr(1000), 10, 8)
+	_, err := svc.ApproveLoan(ln.ID, loan.Approval{PhotoProofURL: "proof", ValidatorID: "EMP030", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = outbox.NewDispatcher(repo.Outbox(), svc.DeliverNotification, time.Second).DispatchDue()
+	require.NoError(t, err)
+
+	w = send("GET", "/webhooks/"+sub.ID+"/deliveries", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var deliveries []webhook.Delivery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, loan.ApprovedEvent, deliveries[0].EventType)
+	assert.Equal(t, outbox.Pending, deliveries[0].Status)
+
+	w = send("POST", "/webhooks/"+sub.ID+"/deliveries/"+deliveries[0].ID+"/replay", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"replay_of":"`+deliveries[0].ID+`"`)
+
+	assert.Equal(t, http.StatusNotFound, send("POST", "/webhooks/"+sub.ID+"/deliveries/missing/replay", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/webhooks/missing/deliveries", nil).Code)
+
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/webhooks/"+sub.ID, nil).Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/webhooks/"+sub.ID, nil).Code)
+}
+
+func TestWebhookRoutesNeedWebhookService(t *testing.T) {
+	router, _ := setupRouterWithMemoryService()
+	req, _ := http.NewRequest("GET", "/webhooks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
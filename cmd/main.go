@@ -1,23 +1,331 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"loan-service/api"
+	"loan-service/config"
+	"loan-service/core/agreement"
+	"loan-service/core/auth"
+	"loan-service/core/borrower"
+	"loan-service/core/document"
+	"loan-service/core/idempotency"
+	"loan-service/core/investor"
 	"loan-service/core/loan"
+	"loan-service/core/outbox"
+	"loan-service/core/webhook"
+	"loan-service/database"
 	"loan-service/email"
 )
 
 func main() {
-	// Setup repository, email mock, and service
-	repo := loan.NewInMemoryLoanRepository()
-	mailer := email.NewMockEmailSender()
-	service := loan.NewLoanService(repo, mailer)
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves the API and runs the background jobs until SIGINT (Ctrl-C) or SIGTERM (deploys),
+// then shuts down gracefully. A second signal kills the process straight away.
+func run(cfg config.Config) error {
+	log.Printf("starting in %s", cfg.Environment)
+	if cfg.Environment != config.Development {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Workers get their own context: they keep running while the last requests finish
+	// and are only stopped once the server is shut down.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Setup repositories, mailer, and services
+	store, err := newStorage(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.close()
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		return err
+	}
+	documentStore, err := newDocumentStore(cfg.Documents.Dir)
+	if err != nil {
+		return err
+	}
+	verifier, err := newVerifier(cfg.Auth)
+	if err != nil {
+		return err
+	}
+	documents := document.NewService(documentStore, document.WithMaxSize(cfg.Documents.MaxSize))
+	options := store.options
+	handlerOptions := []api.HandlerOption{
+		api.WithDocuments(documents),
+		api.WithIdempotencyStore(store.idempotency),
+		api.WithAuth(verifier),
+	}
+	if store.ping != nil {
+		handlerOptions = append(handlerOptions, api.WithReadinessCheck("database", store.ping))
+	}
+	if pinger, ok := mailer.(interface{ Ping(context.Context) error }); ok {
+		handlerOptions = append(handlerOptions, api.WithReadinessCheck("mail", pinger.Ping))
+	}
+	if cfg.Features.Metrics {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		mailer = email.CountFailures(mailer, registry)
+		handlerOptions = append(handlerOptions, api.WithMetrics(registry))
+	}
+
+	if cfg.Features.Webhooks {
+		webhooks := webhook.NewService(store.webhooks)
+		options = append(options, loan.WithEventPublisher(webhooks))
+		handlerOptions = append(handlerOptions, api.WithWebhooks(webhooks))
+
+		// Send loan events to webhook subscribers, retrying failures with backoff
+		startWorker(webhook.NewDispatcher(store.webhooks, cfg.Workers.WebhookInterval).Run)
+	}
+	if cfg.Features.InvestorRegistry {
+		investors := investor.NewService(store.investors)
+		options = append(options, loan.WithInvestorRegistry(investors))
+		handlerOptions = append(handlerOptions, api.WithInvestors(investors))
+	}
+	if cfg.Features.BorrowerRegistry {
+		borrowers := borrower.NewService(store.borrowers, borrower.WithPolicy(borrower.Policy{
+			SingleLoanInProgress: cfg.Features.SingleLoanInProgress,
+		}))
+		options = append(options, loan.WithBorrowerRegistry(borrowers))
+		handlerOptions = append(handlerOptions, api.WithBorrowers(borrowers))
+	}
+	if cfg.Features.RequireDocuments {
+		options = append(options, loan.WithDocumentStore(documents))
+	}
+	if cfg.Features.AgreementLetters {
+		// Agreement letters link to GET /documents/:id/content under the public URL
+		options = append(options, loan.WithAgreementGenerator(agreement.NewGenerator(documents, agreement.WithBaseURL(cfg.Server.PublicURL))))
+	}
+	service := loan.NewLoanService(store.loans, mailer, options...)
+
+	// Expire approved loans that miss their funding deadline
+	startWorker(loan.NewExpiryScheduler(service, cfg.Workers.ExpiryInterval).Run)
+
+	// Deliver queued notifications, retrying failures with backoff
+	startWorker(outbox.NewDispatcher(store.loans.Outbox(), service.DeliverNotification, cfg.Workers.OutboxInterval).Run)
 
 	// Setup HTTP handler and routes
-	handler := api.NewHandler(service)
-	router := api.SetupRouter(handler)
+	router := api.SetupRouter(api.NewHandler(service, handlerOptions...))
 
 	// Start the server
-	if err := router.Run(":8080"); err != nil {
-		panic("failed to start server: " + err.Error())
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", cfg.Server.Addr)
+		served <- server.ListenAndServe()
+	}()
+
+	var serveErr error
+	select {
+	case err := <-served:
+		serveErr = fmt.Errorf("failed to start server: %w", err)
+	case <-signals.Done():
+		stop()
+		log.Printf("shutting down: finishing in-flight requests and background jobs")
+	}
+	shutdown(server, stopWorkers, &workers, cfg.Server.ShutdownTimeout)
+	return serveErr
+}
+
+// shutdown stops accepting connections and waits for in-flight requests to finish. It then stops the
+// workers, which return once their current pass is over. Whatever is still running after timeout is abandoned.
+func shutdown(server *http.Server, stopWorkers context.CancelFunc, workers *sync.WaitGroup, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: requests still in flight were cut off: %v", err)
+	}
+
+	stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Printf("shutdown complete")
+	case <-ctx.Done():
+		log.Printf("shutdown: background jobs still running were abandoned")
+	}
+}
+
+// storage holds the repositories of the chosen backend.
+type storage struct {
+	loans       loan.LoanRepository
+	webhooks    webhook.Repository
+	investors   investor.Repository
+	borrowers   borrower.Repository
+	idempotency idempotency.Store
+	options     []loan.ServiceOption            // Sets the loan service's other repositories
+	ping        func(ctx context.Context) error // Checks the database can be reached; nil for memory
+	close       func()                          // Releases the database connections
+}
+
+// newStorage opens the configured storage backend. The memory backend keeps data until the process exits.
+func newStorage(cfg config.Database) (storage, error) {
+	if cfg.Driver == config.MemoryDriver {
+		return storage{
+			loans:       loan.NewInMemoryLoanRepository(),
+			webhooks:    webhook.NewInMemoryRepository(),
+			investors:   investor.NewInMemoryRepository(),
+			borrowers:   borrower.NewInMemoryRepository(),
+			idempotency: idempotency.NewInMemoryStore(),
+			close:       func() {},
+		}, nil
+	}
+
+	db, err := database.Open(cfg.Driver, cfg.URL)
+	if err != nil {
+		return storage{}, fmt.Errorf("failed to open database: %w", err)
+	}
+	log.Printf("using %s loan repository", db.Dialect)
+	return storage{
+		loans:       loan.NewSQLLoanRepository(db),
+		webhooks:    webhook.NewSQLRepository(db),
+		investors:   investor.NewSQLRepository(db),
+		borrowers:   borrower.NewSQLRepository(db),
+		idempotency: idempotency.NewSQLStore(db),
+		options: []loan.ServiceOption{
+			loan.WithPayoutRepository(loan.NewSQLPayoutRepository(db)),
+		},
+		ping: db.PingContext,
+		close: func() {
+			if err := db.Close(); err != nil {
+				log.Printf("close database: %v", err)
+			}
+		},
+	}, nil
+}
+
+// newDocumentStore keeps uploaded documents in dir, or in memory (lost on restart) when dir is empty.
+func newDocumentStore(dir string) (document.Store, error) {
+	if dir == "" {
+		return document.NewInMemoryStore(), nil
+	}
+	store, err := document.NewLocalStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document store: %w", err)
+	}
+	log.Printf("storing documents in %s", dir)
+	return store, nil
+}
+
+// newMailer returns the configured sender: SMTP, or the mock that logs emails.
+func newMailer(cfg config.Mail) (loan.EmailSender, error) {
+	if cfg.Sender != config.SMTPSender {
+		return email.NewMockEmailSender(), nil
+	}
+
+	smtp := cfg.SMTP
+	addr := net.JoinHostPort(smtp.Host, strconv.Itoa(smtp.Port))
+	sender, err := email.NewSMTPSender(email.SMTPConfig{
+		Addr:       addr,
+		Username:   smtp.Username,
+		Password:   smtp.Password,
+		From:       smtp.From,
+		RequireTLS: smtp.Username != "",
+	}, email.AddressFormats{
+		loan.BorrowerRecipient: smtp.BorrowerAddress,
+		loan.InvestorRecipient: smtp.InvestorAddress,
+		loan.StaffRecipient:    smtp.StaffAddress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure SMTP: %w", err)
+	}
+	log.Printf("sending email through %s", addr)
+	return sender, nil
+}
+
+// newVerifier returns the verifier for bearer tokens: HS256 with a shared secret or RS256 with a PEM public key,
+// optionally restricted to an issuer and audience. It returns nil when authentication is disabled.
+func newVerifier(cfg config.Auth) (*auth.Verifier, error) {
+	if cfg.Disabled {
+		log.Printf("authentication is disabled: every route is open to anonymous callers")
+		return nil, nil
+	}
+
+	var opts []auth.VerifierOption
+	if cfg.Issuer != "" {
+		opts = append(opts, auth.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, auth.WithAudience(cfg.Audience))
+	}
+
+	var (
+		verifier *auth.Verifier
+		err      error
+	)
+	if cfg.HS256Secret != "" {
+		verifier, err = auth.NewHS256Verifier([]byte(cfg.HS256Secret), opts...)
+	} else {
+		verifier, err = newRS256Verifier(cfg.RS256PublicKeyFile, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %w", err)
+	}
+	return verifier, nil
+}
+
+// newRS256Verifier reads the PEM public key in keyFile and returns a verifier for tokens signed with it.
+func newRS256Verifier(keyFile string, opts []auth.VerifierOption) (*auth.Verifier, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, err
 	}
+	return auth.NewRS256Verifier(key, opts...)
 }
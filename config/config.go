@@ -0,0 +1,231 @@
+// Package config holds the settings of the server binary. They are read from an optional YAML file,
+// environment variables and command-line flags, in increasing order of precedence, and validated
+// before anything is started.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Environments the binary can run in. Production refuses settings that are only fit for development.
+const (
+	Development = "development"
+	Staging     = "staging"
+	Production  = "production"
+)
+
+// Storage backends for loans and the other repositories.
+const (
+	MemoryDriver   = "memory"
+	SQLiteDriver   = "sqlite3"
+	PostgresDriver = "postgres"
+)
+
+// Mail senders.
+const (
+	LogSender  = "log"  // Logs emails instead of sending them
+	SMTPSender = "smtp" // Sends emails through an SMTP server
+)
+
+// Config is the complete configuration of the server.
+type Config struct {
+	Environment string    `yaml:"environment"` // development, staging or production
+	Server      Server    `yaml:"server"`
+	Database    Database  `yaml:"database"`
+	Mail        Mail      `yaml:"mail"`
+	Auth        Auth      `yaml:"auth"`
+	Documents   Documents `yaml:"documents"`
+	Workers     Workers   `yaml:"workers"`
+	Features    Features  `yaml:"features"`
+}
+
+// Server configures the HTTP server.
+type Server struct {
+	Addr              string        `yaml:"addr"`                // Listen address, e.g. ":8080"
+	PublicURL         string        `yaml:"public_url"`          // URL clients reach the API at; links in emails and letters start with it
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // Longest time to read request headers
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // Longest time to read a whole request, including uploads
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // Longest time to write a response
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // How long keep-alive connections may stay idle
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`    // How long requests and workers get to finish on shutdown
+}
+
+// Database chooses where data is stored.
+type Database struct {
+	Driver string `yaml:"driver"` // memory, sqlite3 or postgres
+	URL    string `yaml:"url"`    // Data source name; required unless Driver is memory
+}
+
+// Mail chooses how notification emails are sent.
+type Mail struct {
+	Sender string `yaml:"sender"` // log or smtp
+	SMTP   SMTP   `yaml:"smtp"`
+}
+
+// SMTP configures the SMTP sender.
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"` // When set, STARTTLS is required
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+
+	// Formats turning a recipient ID into an address, e.g. "%s@investors.example.com"
+	BorrowerAddress string `yaml:"borrower_address"`
+	InvestorAddress string `yaml:"investor_address"`
+	StaffAddress    string `yaml:"staff_address"`
+}
+
+// Auth configures bearer token verification.
+type Auth struct {
+	Disabled           bool   `yaml:"disabled"`              // Serve every route to anonymous callers
+	HS256Secret        string `yaml:"hs256_secret"`          // Shared secret of at least 32 bytes
+	RS256PublicKeyFile string `yaml:"rs256_public_key_file"` // PEM file of the public key
+	Issuer             string `yaml:"issuer"`                // Required "iss" claim, if set
+	Audience           string `yaml:"audience"`              // Required "aud" claim, if set
+}
+
+// Documents configures where uploaded documents are kept.
+type Documents struct {
+	Dir     string `yaml:"dir"`      // Directory to store documents in; in memory when empty
+	MaxSize int64  `yaml:"max_size"` // Largest upload accepted, in bytes
+}
+
+// Workers sets how often the background jobs run.
+type Workers struct {
+	ExpiryInterval  time.Duration `yaml:"expiry_interval"`  // How often overdue loans are expired
+	OutboxInterval  time.Duration `yaml:"outbox_interval"`  // How often queued notifications are delivered
+	WebhookInterval time.Duration `yaml:"webhook_interval"` // How often webhook deliveries are sent
+}
+
+// Features switches optional behaviour on and off.
+type Features struct {
+	Webhooks             bool `yaml:"webhooks"`                // Serve /webhooks and send loan events to subscribers
+	InvestorRegistry     bool `yaml:"investor_registry"`       // Only accept investments from registered, verified investors
+	BorrowerRegistry     bool `yaml:"borrower_registry"`       // Only accept loans from registered borrowers within their limit
+	SingleLoanInProgress bool `yaml:"single_loan_in_progress"` // Refuse a new loan while the borrower has one not yet disbursed
+	RequireDocuments     bool `yaml:"require_documents"`       // Approvals and disbursements must refer to uploaded documents
+	AgreementLetters     bool `yaml:"agreement_letters"`       // Generate agreement letters at approval and full funding
+	Metrics              bool `yaml:"metrics"`                 // Serve Prometheus metrics at /metrics
+}
+
+// Default returns the configuration used for settings that are not given: an in-memory development server
+// on :8080 that logs emails, serving webhooks and metrics. The features that change what a loan request
+// needs (the registries, uploaded documents and agreement letters) are off until switched on. There is
+// no verification key, so a key or auth.disabled must still be given.
+func Default() Config {
+	return Config{
+		Environment: Development,
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database:  Database{Driver: MemoryDriver},
+		Mail:      Mail{Sender: LogSender, SMTP: SMTP{Port: 587}},
+		Documents: Documents{MaxSize: 10 << 20},
+		Workers: Workers{
+			ExpiryInterval:  time.Minute,
+			OutboxInterval:  5 * time.Second,
+			WebhookInterval: 5 * time.Second,
+		},
+		Features: Features{
+			Webhooks: true,
+			Metrics:  true,
+		},
+	}
+}
+
+// Validate reports every problem with the configuration at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Environment == Development || c.Environment == Staging || c.Environment == Production,
+		"environment must be %s, %s or %s, not %q", Development, Staging, Production, c.Environment)
+
+	_, _, err := net.SplitHostPort(c.Server.Addr)
+	check(err == nil, "server.addr %q is not a host:port address", c.Server.Addr)
+	if c.Server.PublicURL != "" {
+		u, err := url.Parse(c.Server.PublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"server.public_url %q is not an absolute http(s) URL", c.Server.PublicURL)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"workers.expiry_interval", c.Workers.ExpiryInterval},
+		{"workers.outbox_interval", c.Workers.OutboxInterval},
+		{"workers.webhook_interval", c.Workers.WebhookInterval},
+	} {
+		check(d.value > 0, "%s must be positive", d.name)
+	}
+
+	switch c.Database.Driver {
+	case MemoryDriver:
+	case SQLiteDriver, PostgresDriver:
+		check(c.Database.URL != "", "database.url is required for the %s driver", c.Database.Driver)
+	default:
+		check(false, "database.driver must be %s, %s or %s, not %q", MemoryDriver, SQLiteDriver, PostgresDriver, c.Database.Driver)
+	}
+
+	switch c.Mail.Sender {
+	case LogSender:
+		check(c.Mail.SMTP.Host == "", "mail.smtp.host is set but mail.sender is %s; set it to %s to send emails", LogSender, SMTPSender)
+	case SMTPSender:
+		smtp := c.Mail.SMTP
+		check(smtp.Host != "", "mail.smtp.host is required for the smtp sender")
+		check(smtp.Port > 0 && smtp.Port < 1<<16, "mail.smtp.port %d is not a valid port", smtp.Port)
+		for _, f := range []struct{ name, format string }{
+			{"mail.smtp.borrower_address", smtp.BorrowerAddress},
+			{"mail.smtp.investor_address", smtp.InvestorAddress},
+			{"mail.smtp.staff_address", smtp.StaffAddress},
+		} {
+			check(strings.Count(f.format, "%s") == 1, "%s must contain %%s once, e.g. \"%%s@example.com\"", f.name)
+		}
+	default:
+		check(false, "mail.sender must be %s or %s, not %q", LogSender, SMTPSender, c.Mail.Sender)
+	}
+
+	keys := 0
+	for _, key := range []string{c.Auth.HS256Secret, c.Auth.RS256PublicKeyFile} {
+		if key != "" {
+			keys++
+		}
+	}
+	if c.Auth.Disabled {
+		check(keys == 0, "auth.disabled cannot be combined with a verification key")
+	} else {
+		check(keys == 1, "exactly one of auth.hs256_secret and auth.rs256_public_key_file is required (or auth.disabled)")
+	}
+
+	check(c.Documents.MaxSize > 0, "documents.max_size must be positive")
+	check(!c.Features.SingleLoanInProgress || c.Features.BorrowerRegistry,
+		"features.single_loan_in_progress needs features.borrower_registry")
+
+	if c.Environment == Production {
+		check(!c.Auth.Disabled, "auth cannot be disabled in production")
+		check(c.Database.Driver != MemoryDriver, "production needs a SQL database, not the %s driver", MemoryDriver)
+		check(c.Documents.Dir != "", "production needs documents.dir, not in-memory documents")
+	}
+
+	return errors.Join(errs...)
+}
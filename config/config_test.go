@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault_IsValid(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.Disabled = true
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(c *Config)
+		errors []string // Empty when the configuration is valid
+	}{
+		{"hs256 secret", func(c *Config) { c.Auth = Auth{HS256Secret: "secret"} }, nil},
+		{"no verification key", func(c *Config) { c.Auth = Auth{} }, []string{"exactly one of auth.hs256_secret and auth.rs256_public_key_file is required"}},
+		{"two verification keys", func(c *Config) { c.Auth = Auth{HS256Secret: "s", RS256PublicKeyFile: "key.pem"} }, []string{"exactly one of"}},
+		{"disabled with key", func(c *Config) { c.Auth.HS256Secret = "s" }, []string{"auth.disabled cannot be combined"}},
+		{"unknown environment", func(c *Config) { c.Environment = "test" }, []string{`environment must be development, staging or production, not "test"`}},
+		{"bad address", func(c *Config) { c.Server.Addr = "8080" }, []string{`server.addr "8080" is not a host:port address`}},
+		{"relative public url", func(c *Config) { c.Server.PublicURL = "loans.example.com" }, []string{"server.public_url"}},
+		{"https public url", func(c *Config) { c.Server.PublicURL = "https://loans.example.com" }, nil},
+		{"zero timeouts", func(c *Config) {
+			c.Server.WriteTimeout, c.Server.ShutdownTimeout, c.Workers.OutboxInterval = 0, 0, -time.Second
+		}, []string{
+			"server.write_timeout must be positive", "server.shutdown_timeout must be positive", "workers.outbox_interval must be positive",
+		}},
+		{"sql without url", func(c *Config) { c.Database.Driver = PostgresDriver }, []string{"database.url is required for the postgres driver"}},
+		{"sqlite", func(c *Config) { c.Database = Database{Driver: SQLiteDriver, URL: "loans.db"} }, nil},
+		{"unknown driver", func(c *Config) { c.Database.Driver = "mysql" }, []string{`database.driver must be memory, sqlite3 or postgres, not "mysql"`}},
+		{"smtp", func(c *Config) {
+			c.Mail = Mail{Sender: SMTPSender, SMTP: SMTP{Host: "smtp.example.com", Port: 587, BorrowerAddress: "%s@b.example.com", InvestorAddress: "%s@i.example.com", StaffAddress: "%s@example.com"}}
+		}, nil},
+		{"incomplete smtp", func(c *Config) {
+			c.Mail = Mail{Sender: SMTPSender, SMTP: SMTP{Port: 70000, StaffAddress: "staff@example.com"}}
+		}, []string{
+			"mail.smtp.host is required", "mail.smtp.port 70000 is not a valid port",
+			"mail.smtp.borrower_address must contain %s once", "mail.smtp.staff_address must contain %s once",
+		}},
+		{"smtp host with log sender", func(c *Config) { c.Mail.SMTP.Host = "smtp.example.com" }, []string{"mail.smtp.host is set but mail.sender is log"}},
+		{"unknown sender", func(c *Config) { c.Mail.Sender = "sendgrid" }, []string{`mail.sender must be log or smtp, not "sendgrid"`}},
+		{"no document size", func(c *Config) { c.Documents.MaxSize = 0 }, []string{"documents.max_size must be positive"}},
+		{"single loan without registry", func(c *Config) { c.Features.SingleLoanInProgress, c.Features.BorrowerRegistry = true, false }, []string{
+			"features.single_loan_in_progress needs features.borrower_registry",
+		}},
+		{"production defaults", func(c *Config) { c.Environment = Production }, []string{
+			"auth cannot be disabled in production", "production needs a SQL database", "production needs documents.dir",
+		}},
+		{"production", func(c *Config) {
+			c.Environment = Production
+			c.Auth = Auth{RS256PublicKeyFile: "key.pem"}
+			c.Database = Database{Driver: PostgresDriver, URL: "postgres://loans"}
+			c.Documents.Dir = "/var/lib/loans/documents"
+		}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Auth.Disabled = true
+			tc.change(&cfg)
+
+			err := cfg.Validate()
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, msg := range tc.errors {
+				assert.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the path of the YAML file, when -config is not given.
+const FileEnv = "CONFIG_FILE"
+
+// setting is one value that can be given in the environment and on the command line.
+type setting struct {
+	flag    string // Name of the command-line flag; empty for secrets, which are kept off the command line
+	env     string // Name of the environment variable
+	usage   string
+	boolean bool // Whether the flag may be given without a value
+	set     func(c *Config, value string) error
+}
+
+// settings lists every value that can be overridden outside the YAML file.
+var settings = []setting{
+	text("env", "APP_ENV", "environment: development, staging or production", func(c *Config) *string { return &c.Environment }),
+
+	text("addr", "LISTEN_ADDR", "address to listen on", func(c *Config) *string { return &c.Server.Addr }),
+	text("public-url", "PUBLIC_URL", "URL clients reach the API at, e.g. https://loans.example.com", func(c *Config) *string { return &c.Server.PublicURL }),
+	duration("read-header-timeout", "HTTP_READ_HEADER_TIMEOUT", "longest time to read request headers", func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout }),
+	duration("read-timeout", "HTTP_READ_TIMEOUT", "longest time to read a request", func(c *Config) *time.Duration { return &c.Server.ReadTimeout }),
+	duration("write-timeout", "HTTP_WRITE_TIMEOUT", "longest time to write a response", func(c *Config) *time.Duration { return &c.Server.WriteTimeout }),
+	duration("idle-timeout", "HTTP_IDLE_TIMEOUT", "how long idle keep-alive connections are kept", func(c *Config) *time.Duration { return &c.Server.IdleTimeout }),
+	duration("shutdown-timeout", "HTTP_SHUTDOWN_TIMEOUT", "how long requests and workers get to finish on shutdown", func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout }),
+
+	text("database-driver", "DATABASE_DRIVER", "storage backend: memory, sqlite3 or postgres", func(c *Config) *string { return &c.Database.Driver }),
+	text("", "DATABASE_URL", "data source name of the database", func(c *Config) *string { return &c.Database.URL }),
+
+	text("mail-sender", "MAIL_SENDER", "how emails are sent: log or smtp", func(c *Config) *string { return &c.Mail.Sender }),
+	text("smtp-host", "SMTP_HOST", "SMTP server host", func(c *Config) *string { return &c.Mail.SMTP.Host }),
+	integer("smtp-port", "SMTP_PORT", "SMTP server port", func(c *Config) *int { return &c.Mail.SMTP.Port }),
+	text("smtp-username", "SMTP_USERNAME", "SMTP user name; STARTTLS is required when set", func(c *Config) *string { return &c.Mail.SMTP.Username }),
+	text("", "SMTP_PASSWORD", "SMTP password", func(c *Config) *string { return &c.Mail.SMTP.Password }),
+	text("smtp-from", "SMTP_FROM", "sender address of emails", func(c *Config) *string { return &c.Mail.SMTP.From }),
+	text("smtp-borrower-address", "SMTP_BORROWER_ADDRESS", "format of borrower addresses, e.g. %s@borrowers.example.com", func(c *Config) *string { return &c.Mail.SMTP.BorrowerAddress }),
+	text("smtp-investor-address", "SMTP_INVESTOR_ADDRESS", "format of investor addresses, e.g. %s@investors.example.com", func(c *Config) *string { return &c.Mail.SMTP.InvestorAddress }),
+	text("smtp-staff-address", "SMTP_STAFF_ADDRESS", "format of staff addresses, e.g. %s@example.com", func(c *Config) *string { return &c.Mail.SMTP.StaffAddress }),
+
+	boolean("auth-disabled", "AUTH_DISABLED", "serve every route to anonymous callers", func(c *Config) *bool { return &c.Auth.Disabled }),
+	text("", "JWT_HS256_SECRET", "secret verifying HS256 tokens", func(c *Config) *string { return &c.Auth.HS256Secret }),
+	text("jwt-public-key-file", "JWT_RS256_PUBLIC_KEY_FILE", "PEM public key verifying RS256 tokens", func(c *Config) *string { return &c.Auth.RS256PublicKeyFile }),
+	text("jwt-issuer", "JWT_ISSUER", "required token issuer", func(c *Config) *string { return &c.Auth.Issuer }),
+	text("jwt-audience", "JWT_AUDIENCE", "required token audience", func(c *Config) *string { return &c.Auth.Audience }),
+
+	text("document-dir", "DOCUMENT_DIR", "directory to store uploaded documents in; in memory when empty", func(c *Config) *string { return &c.Documents.Dir }),
+	integer64("document-max-size", "DOCUMENT_MAX_SIZE", "largest upload accepted, in bytes", func(c *Config) *int64 { return &c.Documents.MaxSize }),
+
+	duration("expiry-interval", "EXPIRY_INTERVAL", "how often overdue loans are expired", func(c *Config) *time.Duration { return &c.Workers.ExpiryInterval }),
+	duration("outbox-interval", "OUTBOX_INTERVAL", "how often queued notifications are delivered", func(c *Config) *time.Duration { return &c.Workers.OutboxInterval }),
+	duration("webhook-interval", "WEBHOOK_INTERVAL", "how often webhook deliveries are sent", func(c *Config) *time.Duration { return &c.Workers.WebhookInterval }),
+
+	boolean("webhooks", "FEATURE_WEBHOOKS", "serve webhooks and send loan events", func(c *Config) *bool { return &c.Features.Webhooks }),
+	boolean("investor-registry", "FEATURE_INVESTOR_REGISTRY", "only accept investments from verified investors", func(c *Config) *bool { return &c.Features.InvestorRegistry }),
+	boolean("borrower-registry", "FEATURE_BORROWER_REGISTRY", "only accept loans from registered borrowers", func(c *Config) *bool { return &c.Features.BorrowerRegistry }),
+	boolean("single-loan-in-progress", "SINGLE_LOAN_IN_PROGRESS", "refuse a new loan while the previous one awaits disbursement", func(c *Config) *bool { return &c.Features.SingleLoanInProgress }),
+	boolean("require-documents", "FEATURE_REQUIRE_DOCUMENTS", "approvals and disbursements must refer to uploaded documents", func(c *Config) *bool { return &c.Features.RequireDocuments }),
+	boolean("agreement-letters", "FEATURE_AGREEMENT_LETTERS", "generate agreement letters", func(c *Config) *bool { return &c.Features.AgreementLetters }),
+	boolean("metrics", "FEATURE_METRICS", "serve Prometheus metrics at /metrics", func(c *Config) *bool { return &c.Features.Metrics }),
+}
+
+// Load builds the configuration from, in increasing order of precedence: the defaults, the YAML file named
+// by the -config flag or the CONFIG_FILE variable, environment variables and the command-line flags in args.
+// lookupEnv is usually os.LookupEnv; variables set to the empty string are ignored.
+//
+// The result is validated. Load returns flag.ErrHelp if args ask for the usage, which it then prints to stderr.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flags []flagValue
+
+	fs := flag.NewFlagSet("loan-service", flag.ContinueOnError)
+	file := fs.String("config", "", "YAML configuration file (env "+FileEnv+")")
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		record := func(value string) error {
+			flags = append(flags, flagValue{s, value})
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		if s.boolean {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	cfg := Default()
+	if *file == "" {
+		*file, _ = lookupEnv(FileEnv)
+	}
+	if *file != "" {
+		if err := readFile(&cfg, *file); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok && value != "" {
+			if err := s.set(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, f := range flags {
+		if err := f.setting.set(&cfg, f.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", f.setting.flag, err))
+		}
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// readFile overlays the YAML file at path on cfg. Keys the file leaves out keep their value;
+// unknown keys are an error, so misspellings do not go unnoticed.
+func readFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read configuration: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse configuration %s: %w", path, err)
+	}
+	return nil
+}
+
+func text(name, env, usage string, field func(*Config) *string) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func boolean(name, env, usage string, field func(*Config) *bool) setting {
+	return setting{flag: name, env: env, usage: usage, boolean: true, set: func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*field(c) = b
+		return nil
+	}}
+}
+
+func integer(name, env, usage string, field func(*Config) *int) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func integer64(name, env, usage string, field func(*Config) *int64) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(c *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func duration(name, env, usage string, field func(*Config) *time.Duration) setting {
+	return setting{flag: name, env: env, usage: usage, set: func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration, e.g. 30s or 5m", value)
+		}
+		*field(c) = d
+		return nil
+	}}
+}
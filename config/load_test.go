@@ -0,0 +1,144 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a lookup function serving vars, standing in for os.LookupEnv.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, env(map[string]string{"AUTH_DISABLED": "true"}))
+	require.NoError(t, err)
+
+	want := Default()
+	want.Auth.Disabled = true
+	assert.Equal(t, want, cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, `
+environment: staging
+server:
+  addr: ":9000"
+  public_url: https://file.example.com
+  write_timeout: 1m
+database:
+  driver: sqlite3
+  url: file.db
+auth:
+  hs256_secret: from-the-file
+features:
+  webhooks: false
+  borrower_registry: true
+  single_loan_in_progress: true
+`)
+	cfg, err := Load(
+		[]string{"-config", file, "-addr", ":7000", "-webhooks", "-expiry-interval=30s"},
+		env(map[string]string{
+			"LISTEN_ADDR":  ":8000",
+			"PUBLIC_URL":   "https://env.example.com",
+			"DATABASE_URL": "env.db",
+			"SMTP_PORT":    "2525",
+			"JWT_ISSUER":   "",
+		}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, Staging, cfg.Environment)                        // File
+	assert.Equal(t, ":7000", cfg.Server.Addr)                        // Flag over environment over file
+	assert.Equal(t, "https://env.example.com", cfg.Server.PublicURL) // Environment over file
+	assert.Equal(t, time.Minute, cfg.Server.WriteTimeout)            // File
+	assert.Equal(t, 30*time.Second, cfg.Server.ReadTimeout)          // Default
+	assert.Equal(t, Database{Driver: SQLiteDriver, URL: "env.db"}, cfg.Database)
+	assert.Equal(t, 2525, cfg.Mail.SMTP.Port)
+	assert.Equal(t, Auth{HS256Secret: "from-the-file"}, cfg.Auth) // Empty variables are ignored
+	assert.Equal(t, 30*time.Second, cfg.Workers.ExpiryInterval)
+	assert.True(t, cfg.Features.Webhooks) // A boolean flag without a value
+	assert.True(t, cfg.Features.SingleLoanInProgress)
+	assert.False(t, cfg.Features.InvestorRegistry) // Default
+}
+
+func TestLoad_FileFromEnvironment(t *testing.T) {
+	file := writeFile(t, "auth:\n  disabled: true\nworkers:\n  outbox_interval: 2s\n")
+	cfg, err := Load(nil, env(map[string]string{FileEnv: file}))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.Workers.OutboxInterval)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   []string
+		vars   map[string]string
+		file   string
+		errors []string
+	}{
+		{name: "unknown flag", args: []string{"-port", "80"}, errors: []string{"flag provided but not defined: -port"}},
+		{name: "positional argument", args: []string{"serve"}, errors: []string{"unexpected arguments: [serve]"}},
+		{name: "missing file", args: []string{"-config", "/does/not/exist.yaml"}, errors: []string{"read configuration"}},
+		{name: "unknown key", file: "auth:\n  disabled: true\nserver:\n  port: 80\n", errors: []string{"field port not found"}},
+		{name: "bad values", args: []string{"-read-timeout", "soon"}, vars: map[string]string{"SMTP_PORT": "smtp", "AUTH_DISABLED": "yes please"}, errors: []string{
+			`SMTP_PORT: "smtp" is not an integer`, `AUTH_DISABLED: "yes please" is not a boolean`, `-read-timeout: "soon" is not a duration`,
+		}},
+		{name: "invalid", vars: map[string]string{"DATABASE_DRIVER": "postgres"}, errors: []string{
+			"database.url is required", "exactly one of auth.hs256_secret",
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := tc.args
+			if tc.file != "" {
+				args = append([]string{"-config", writeFile(t, tc.file)}, args...)
+			}
+
+			_, err := Load(args, env(tc.vars))
+			require.Error(t, err)
+			for _, msg := range tc.errors {
+				assert.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	_, err := Load([]string{"-h"}, env(nil))
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
+
+func TestLoad_ExampleFile(t *testing.T) {
+	cfg, err := Load([]string{"-config", "../config.example.yaml"}, env(nil))
+	require.NoError(t, err)
+
+	want := Default()
+	want.Server.PublicURL = "http://localhost:8080"
+	want.Mail.SMTP = SMTP{
+		Port:            587,
+		From:            "loans@example.com",
+		BorrowerAddress: "%s@borrowers.example.com",
+		InvestorAddress: "%s@investors.example.com",
+		StaffAddress:    "%s@example.com",
+	}
+	want.Auth.Disabled = true
+	assert.Equal(t, want, cfg)
+}
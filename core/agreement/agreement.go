@@ -0,0 +1,95 @@
+// Package agreement generates the agreement letters of loans as PDF files and keeps them in the document store.
+package agreement
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"loan-service/core/document"
+	"loan-service/core/loan"
+)
+
+//go:embed templates/letter.txt
+var letterTemplate string
+
+// letter is the text/template rendering the letter. Lines starting with "# " become headings.
+var letter = template.Must(template.New("letter").Parse(letterTemplate))
+
+// Generator renders agreement letters and uploads them to a document service.
+// It implements loan.AgreementGenerator.
+type Generator struct {
+	documents *document.Service
+	baseURL   string
+	now       func() time.Time
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithBaseURL sets the public URL of the API, e.g. "https://loans.example.com", which letter links start with.
+// Without it links are relative paths.
+func WithBaseURL(url string) Option {
+	return func(g *Generator) {
+		g.baseURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithClock replaces the generator's source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(g *Generator) {
+		g.now = now
+	}
+}
+
+// NewGenerator creates a generator storing letters in documents.
+func NewGenerator(documents *document.Service, opts ...Option) *Generator {
+	g := &Generator{documents: documents, now: time.Now}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Generate renders the agreement letter of ln as it currently stands, stores it as a new document and
+// returns the link to download it.
+func (g *Generator) Generate(ln *loan.Loan) (string, error) {
+	pdf, err := g.Render(ln)
+	if err != nil {
+		return "", err
+	}
+	doc, err := g.documents.Upload("agreement-"+ln.ID+".pdf", bytes.NewReader(pdf), "")
+	if err != nil {
+		return "", fmt.Errorf("store agreement letter: %w", err)
+	}
+	return loan.LetterLink(g.baseURL, doc.ID), nil
+}
+
+// Discard deletes the document behind a link returned by Generate.
+func (g *Generator) Discard(link string) error {
+	id, ok := loan.LetterDocument(link)
+	if !ok || link != loan.LetterLink(g.baseURL, id) {
+		return fmt.Errorf("%q is not an agreement letter link", link)
+	}
+	if err := g.documents.Delete(id); err != nil {
+		return fmt.Errorf("delete agreement letter: %w", err)
+	}
+	return nil
+}
+
+// Render returns the agreement letter of ln as a PDF file: its borrower, principal, rate, ROI and
+// the investors committed to it so far.
+func (g *Generator) Render(ln *loan.Loan) ([]byte, error) {
+	var text bytes.Buffer
+	if err := letter.Execute(&text, struct {
+		Loan      *loan.Loan
+		Investors []loan.Investor
+		IssuedAt  time.Time
+	}{ln, ln.Commitments(), g.now().UTC()}); err != nil {
+		return nil, fmt.Errorf("render agreement letter: %w", err)
+	}
+	return renderPDF(text.String()), nil
+}
@@ -0,0 +1,104 @@
+package agreement
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/document"
+	"loan-service/core/loan"
+	"loan-service/core/money"
+)
+
+func idr(major int64) money.Money {
+	return money.FromMajor(major, money.IDR)
+}
+
+func approvedLoan() *loan.Loan {
+	return &loan.Loan{
+		ID:              "LOAN001",
+		BorrowerID:      "B001",
+		PrincipalAmount: idr(5000000),
+		Rate:            12.5,
+		ROI:             10,
+		State:           loan.Approved,
+		Terms:           loan.RepaymentTerms{Method: loan.FlatMethod, Tenor: 12, InstallmentFee: idr(1000)},
+		Approval:        &loan.Approval{ValidatorID: "EMP001", ApprovalDate: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)},
+		TotalInvested:   idr(0),
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	docs := document.NewService(document.NewInMemoryStore())
+	now := time.Date(2025, 8, 2, 9, 0, 0, 0, time.UTC)
+	gen := NewGenerator(docs, WithBaseURL("https://loans.example.com/"), WithClock(func() time.Time { return now }))
+
+	link, err := gen.Generate(approvedLoan())
+	require.NoError(t, err)
+	id, ok := strings.CutPrefix(link, "https://loans.example.com/documents/")
+	require.True(t, ok, link)
+	id, ok = strings.CutSuffix(id, "/content")
+	require.True(t, ok, link)
+
+	doc, content, err := docs.Open(id)
+	require.NoError(t, err)
+	defer func() { _ = content.Close() }()
+	pdf, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", doc.ContentType)
+	assert.Equal(t, "agreement-LOAN001.pdf", doc.Filename)
+
+	for _, text := range []string{
+		"(Loan Agreement Letter) Tj",
+		"(Issued: 2 August 2025) Tj",
+		"(Borrower ID: B001) Tj",
+		"(Principal: IDR 5000000.00) Tj",
+		"(Interest rate paid by the borrower: 12.5%) Tj",
+		"(Return on investment for investors: 10%) Tj",
+		"(Repayment: flat, 12 installments with a fee of IDR 1000.00 each) Tj",
+		"(Approved by field validator EMP001 on 1 August 2025) Tj",
+		"(No investments yet.",
+	} {
+		assert.Contains(t, string(pdf), text)
+	}
+}
+
+func TestGenerator_Discard(t *testing.T) {
+	docs := document.NewService(document.NewInMemoryStore())
+	gen := NewGenerator(docs, WithBaseURL("https://loans.example.com"))
+
+	link, err := gen.Generate(approvedLoan())
+	require.NoError(t, err)
+	id := strings.TrimSuffix(strings.TrimPrefix(link, "https://loans.example.com/documents/"), "/content")
+	require.NoError(t, gen.Discard(link))
+	_, err = docs.Get(id)
+	assert.ErrorIs(t, err, document.ErrDocumentNotFound)
+
+	for _, link := range []string{"https://elsewhere.example.com/documents/x/content", "https://loans.example.com/documents//content", "letter-1"} {
+		assert.ErrorContains(t, gen.Discard(link), "is not an agreement letter link", link)
+	}
+}
+
+func TestGenerator_RenderInvestors(t *testing.T) {
+	gen := NewGenerator(document.NewService(document.NewInMemoryStore()))
+	ln := approvedLoan()
+	ln.State = loan.Invested
+	ln.Investors = []loan.Investor{
+		{ID: "INV001", Amount: idr(2000000), Status: loan.Committed},
+		{ID: "INV002", Amount: idr(1000000), Status: loan.Withdrawn},
+		{ID: "INV003", Amount: idr(2000000), Status: loan.Committed},
+		{ID: "INV001", Amount: idr(1000000), Status: loan.Committed},
+	}
+	ln.TotalInvested = idr(5000000)
+
+	pdf, err := gen.Render(ln)
+	require.NoError(t, err)
+	assert.Contains(t, string(pdf), "(INV001: IDR 3000000.00) Tj")
+	assert.Contains(t, string(pdf), "(INV003: IDR 2000000.00) Tj")
+	assert.NotContains(t, string(pdf), "INV002", "withdrawn investors are not party to the agreement")
+	assert.Contains(t, string(pdf), "(Total invested: IDR 5000000.00 of IDR 5000000.00) Tj")
+	assert.NotContains(t, string(pdf), "No investments yet")
+}
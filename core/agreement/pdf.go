@@ -0,0 +1,129 @@
+package agreement
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page layout of rendered PDFs, in points: A4 with 2 cm margins.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	margin       = 56
+	fontSize     = 11
+	headingSize  = 14
+	leading      = 16
+	maxLineRunes = 88 // What fits between the margins in 11 pt Helvetica
+)
+
+// linesPerPage is how many lines fit between the top and bottom margins.
+const linesPerPage = (pageHeight - 2*margin) / leading
+
+// renderPDF lays text out on as many A4 pages as needed and returns the PDF file.
+// Lines starting with "# " are headings, set in bold; other lines are wrapped at word boundaries.
+// Only Latin-1 characters can be shown; others are replaced with '?'.
+func renderPDF(text string) []byte {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, wrap(line, maxLineRunes)...)
+	}
+
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects 1-4 are the catalog, page tree and fonts; each page then takes two: itself and its content.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // Page tree, filled in below once the page objects are numbered
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for _, page := range pages {
+		pageObj := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+		content := pageContent(page)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pageContent returns the content stream drawing lines from the top of a page.
+func pageContent(lines []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT\n%d TL\n%d %d Td\n", leading, margin, pageHeight-margin-fontSize)
+	for _, line := range lines {
+		if heading, ok := strings.CutPrefix(line, "# "); ok {
+			fmt.Fprintf(&b, "/F2 %d Tf\n(%s) Tj T*\n", headingSize, escape(heading))
+			continue
+		}
+		fmt.Fprintf(&b, "/F1 %d Tf\n(%s) Tj T*\n", fontSize, escape(line))
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+// escape encodes s as the body of a PDF literal string in WinAnsi encoding.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// wrap breaks line into lines of at most width runes, at spaces where possible.
+func wrap(line string, width int) []string {
+	var lines []string
+	for len([]rune(line)) > width {
+		runes := []rune(line)
+		cut := strings.LastIndex(string(runes[:width+1]), " ")
+		if cut <= 0 {
+			cut = len(string(runes[:width]))
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+			continue
+		}
+		lines = append(lines, line[:cut])
+		line = strings.TrimLeft(line[cut:], " ")
+	}
+	return append(lines, line)
+}
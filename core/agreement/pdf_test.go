@@ -0,0 +1,73 @@
+package agreement
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPDF(t *testing.T) {
+	pdf := renderPDF("# Title\nHello (world) \\ café – ok\n")
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "/F2 14 Tf\n(Title) Tj")
+	assert.Contains(t, string(pdf), "(Hello \\(world\\) \\\\ caf\xe9 ? ok) Tj", "escaped, in WinAnsi, with unknown characters replaced")
+	assert.Contains(t, string(pdf), "/Count 1")
+
+	t.Run("Cross-reference table points at every object", func(t *testing.T) {
+		startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+		require.NotNil(t, startxref)
+		xref, _ := strconv.Atoi(string(startxref[1]))
+		require.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n")))
+
+		entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+		require.Len(t, entries, 6) // Catalog, page tree, two fonts, one page and its content
+		for i, entry := range entries {
+			offset, _ := strconv.Atoi(string(entry[1]))
+			assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+		}
+	})
+
+	t.Run("Content lengths match the streams", func(t *testing.T) {
+		for _, m := range regexp.MustCompile(`(?s)/Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(pdf, -1) {
+			n, _ := strconv.Atoi(string(m[1]))
+			assert.Equal(t, n, len(m[2]))
+		}
+	})
+}
+
+func TestRenderPDF_Pages(t *testing.T) {
+	lines := make([]string, linesPerPage*2+1)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	pdf := string(renderPDF(strings.Join(lines, "\n")))
+
+	assert.Contains(t, pdf, "/Count 3")
+	assert.Equal(t, 3, strings.Count(pdf, "/Type /Page "))
+	assert.Contains(t, pdf, fmt.Sprintf("(line %d) Tj", len(lines)-1))
+}
+
+func TestWrap(t *testing.T) {
+	cases := []struct {
+		line string
+		want []string
+	}{
+		{line: "", want: []string{""}},
+		{line: "short line", want: []string{"short line"}},
+		{line: "one two three four", want: []string{"one two", "three four"}},
+		{line: "one two three fours", want: []string{"one two", "three", "fours"}},
+		{line: "abcdefghijkl", want: []string{"abcdefghij", "kl"}},
+		{line: "café crème brûlée", want: []string{"café crème", "brûlée"}},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, wrap(tc.line, 10), tc.line)
+	}
+}
@@ -0,0 +1,76 @@
+// Package audit keeps an append-only trail of everything that happens to a loan.
+package audit
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Action names what happened to a loan.
+type Action string
+
+const (
+	Created             Action = "created"
+	Approved            Action = "approved"
+	Invested            Action = "invested"
+	InvestmentReduced   Action = "investment_reduced"
+	InvestmentWithdrawn Action = "investment_withdrawn"
+	Disbursed           Action = "disbursed"
+	RepaymentRecorded   Action = "repayment_recorded"
+	Rejected            Action = "rejected"
+	Cancelled           Action = "cancelled"
+	Expired             Action = "expired"
+)
+
+// Event is an immutable record of one change to a loan.
+type Event struct {
+	ID            string          `json:"id"`                       // Unique identifier of the event
+	LoanID        string          `json:"loan_id"`                  // Loan that changed
+	LoanVersion   int64           `json:"loan_version"`             // Version of the loan after the change; orders a loan's events
+	Action        Action          `json:"action"`                   // What happened
+	Actor         string          `json:"actor"`                    // Who did it ("system" for automatic changes)
+	PreviousState string          `json:"previous_state,omitempty"` // Loan state before the change (empty on creation)
+	NewState      string          `json:"new_state"`                // Loan state after the change
+	Payload       json.RawMessage `json:"payload,omitempty"`        // The input of the change, as JSON
+	OccurredAt    time.Time       `json:"occurred_at"`              // When the change was made
+}
+
+// Repository stores audit events. There is deliberately no way to change or delete an event.
+// ListByLoan returns the events of a loan oldest first.
+type Repository interface {
+	Append(event Event) error
+	ListByLoan(loanID string) ([]Event, error)
+}
+
+// InMemoryRepository keeps events in memory. Useful for development and tests.
+type InMemoryRepository struct {
+	mu     sync.RWMutex
+	events map[string][]Event
+}
+
+// NewInMemoryRepository creates an empty in-memory audit repository.
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{events: make(map[string][]Event)}
+}
+
+// Append adds an event to the trail of its loan.
+func (r *InMemoryRepository) Append(event Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.Payload = append(json.RawMessage(nil), event.Payload...)
+	r.events[event.LoanID] = append(r.events[event.LoanID], event)
+	return nil
+}
+
+// ListByLoan returns the events of a loan oldest first.
+func (r *InMemoryRepository) ListByLoan(loanID string) ([]Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]Event, len(r.events[loanID]))
+	copy(result, r.events[loanID])
+	sort.SliceStable(result, func(i, j int) bool { return result[i].LoanVersion < result[j].LoanVersion })
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/database"
+)
+
+func TestInMemoryRepository(t *testing.T) {
+	testRepository(t, NewInMemoryRepository())
+}
+
+func TestSQLRepository(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "audit.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testRepository(t, NewSQLRepository(db))
+}
+
+// testRepository is the behaviour every Repository implementation must satisfy.
+func testRepository(t *testing.T, repo Repository) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		{ID: "E2", LoanID: "L1", LoanVersion: 2, Action: Approved, Actor: "EMP1", PreviousState: "proposed", NewState: "approved",
+			Payload: json.RawMessage(`{"field_validator_id":"EMP1"}`), OccurredAt: at.Add(time.Hour)},
+		{ID: "E1", LoanID: "L1", LoanVersion: 1, Action: Created, Actor: "B1", NewState: "proposed", OccurredAt: at},
+		{ID: "E3", LoanID: "L2", LoanVersion: 1, Action: Created, Actor: "B2", NewState: "proposed", OccurredAt: at},
+	}
+	for _, e := range events {
+		require.NoError(t, repo.Append(e))
+	}
+
+	trail, err := repo.ListByLoan("L1")
+	require.NoError(t, err)
+	require.Len(t, trail, 2)
+	assert.Equal(t, "E1", trail[0].ID, "ordered by loan version")
+	assert.Empty(t, trail[0].Payload)
+
+	second := trail[1]
+	assert.Equal(t, Approved, second.Action)
+	assert.Equal(t, "EMP1", second.Actor)
+	assert.Equal(t, "proposed", second.PreviousState)
+	assert.Equal(t, "approved", second.NewState)
+	assert.JSONEq(t, `{"field_validator_id":"EMP1"}`, string(second.Payload))
+	assert.True(t, at.Add(time.Hour).Equal(second.OccurredAt))
+
+	none, err := repo.ListByLoan("missing")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	assert.NotNil(t, none)
+}
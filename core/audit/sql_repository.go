@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"database/sql"
+	"fmt"
+
+	"loan-service/database"
+)
+
+// SQLRepository stores audit events in the loan_events table.
+type SQLRepository struct {
+	db *database.DB
+}
+
+// NewSQLRepository creates an audit repository on top of an already migrated database.
+func NewSQLRepository(db *database.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// InsertTx writes an event as part of an existing transaction.
+// The loan repository uses it to store the event in the same unit of work as the change it records.
+func InsertTx(tx *sql.Tx, dialect database.Dialect, event Event) error {
+	return insert(tx, dialect, event)
+}
+
+// Append inserts an event.
+func (r *SQLRepository) Append(event Event) error {
+	return insert(r.db, r.db.Dialect, event)
+}
+
+// execer is what insert needs of a *sql.DB or *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insert(db execer, dialect database.Dialect, event Event) error {
+	if _, err := db.Exec(dialect.Rebind(`INSERT INTO loan_events
+		(id, loan_id, loan_version, action, actor, previous_state, new_state, payload, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		event.ID, event.LoanID, event.LoanVersion, string(event.Action), event.Actor,
+		event.PreviousState, event.NewState, string(event.Payload), event.OccurredAt.UTC()); err != nil {
+		return fmt.Errorf("insert audit event: %w", err)
+	}
+	return nil
+}
+
+// ListByLoan returns the events of a loan oldest first.
+func (r *SQLRepository) ListByLoan(loanID string) ([]Event, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT id, loan_id, loan_version, action, actor,
+		previous_state, new_state, payload, occurred_at
+		FROM loan_events WHERE loan_id = ? ORDER BY loan_version, occurred_at`), loanID)
+	if err != nil {
+		return nil, fmt.Errorf("query audit events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	events := []Event{}
+	for rows.Next() {
+		var (
+			e       Event
+			action  string
+			payload string
+		)
+		if err := rows.Scan(&e.ID, &e.LoanID, &e.LoanVersion, &action, &e.Actor,
+			&e.PreviousState, &e.NewState, &payload, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("scan audit event: %w", err)
+		}
+		e.Action = Action(action)
+		if payload != "" {
+			e.Payload = []byte(payload)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
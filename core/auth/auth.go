@@ -0,0 +1,57 @@
+// Package auth authenticates API callers from JWT bearer tokens and describes what they may do.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Role is a job a caller can hold. Routes are guarded by the roles allowed to use them.
+type Role string
+
+const (
+	// Borrower applies for loans; their subject is the borrower ID.
+	Borrower Role = "borrower"
+
+	// FieldValidator visits borrowers and approves or rejects their loans; their subject is the employee ID.
+	FieldValidator Role = "field_validator"
+
+	// FieldOfficer hands the money over to borrowers and collects repayments; their subject is the employee ID.
+	FieldOfficer Role = "field_officer"
+
+	// Investor funds loans; their subject is the investor ID.
+	Investor Role = "investor"
+
+	// Admin runs the service: notifications, webhooks and acting on behalf of others.
+	Admin Role = "admin"
+)
+
+var (
+	// ErrInvalidToken is matched by every error about a token that cannot be trusted.
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrTokenExpired is returned for a token whose exp claim has passed. It matches ErrInvalidToken.
+	ErrTokenExpired = fmt.Errorf("%w: token has expired", ErrInvalidToken)
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string // Who the caller is: a borrower, employee or investor ID depending on their role
+	Roles   []Role
+}
+
+// Has reports whether the principal holds the role.
+func (p Principal) Has(role Role) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasAny reports whether the principal holds at least one of the roles.
+func (p Principal) HasAny(roles ...Role) bool {
+	for _, role := range roles {
+		if p.Has(role) {
+			return true
+		}
+	}
+	return false
+}
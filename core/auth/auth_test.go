@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipal_Has(t *testing.T) {
+	p := Principal{Subject: "EMP001", Roles: []Role{FieldValidator, FieldOfficer}}
+
+	assert.True(t, p.Has(FieldValidator))
+	assert.False(t, p.Has(Admin))
+	assert.True(t, p.HasAny(Admin, FieldOfficer))
+	assert.False(t, p.HasAny(Borrower, Investor))
+	assert.False(t, p.HasAny())
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms a Verifier can be set up for.
+const (
+	HS256 = "HS256" // HMAC with SHA-256 and a shared secret
+	RS256 = "RS256" // RSA PKCS #1 v1.5 signatures with SHA-256
+)
+
+// minSecretLength is the shortest HS256 secret accepted: as many bytes as the hash produces.
+const minSecretLength = sha256.Size
+
+// Claims are the JWT claims the service understands: the registered ones and the caller's roles.
+type Claims struct {
+	Roles []Role `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// Verifier checks bearer tokens signed with one local key.
+// It only accepts the algorithm it was created for, so an RS256 public key can never be used as an HS256 secret.
+type Verifier struct {
+	algorithm string
+	key       any // The HS256 secret or the RS256 public key
+	issuer    string
+	audience  string
+	leeway    time.Duration
+	now       func() time.Time
+	parser    *jwt.Parser
+}
+
+// VerifierOption configures optional checks of a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithIssuer only accepts tokens whose iss claim is issuer.
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience only accepts tokens whose aud claim includes audience.
+func WithAudience(audience string) VerifierOption {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway tolerates clocks that differ by up to d when checking exp and nbf. Defaults to a minute.
+func WithLeeway(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = d
+	}
+}
+
+// WithClock makes the verifier use now instead of time.Now, e.g. in tests.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewHS256Verifier returns a verifier for tokens signed with secret, which must be at least 32 bytes long.
+func NewHS256Verifier(secret []byte, opts ...VerifierOption) (*Verifier, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minSecretLength)
+	}
+	return newVerifier(&Verifier{algorithm: HS256, key: slices.Clone(secret)}, opts), nil
+}
+
+// NewRS256Verifier returns a verifier for tokens signed with the private half of key.
+// PEM encoded keys can be read with jwt.ParseRSAPublicKeyFromPEM.
+func NewRS256Verifier(key *rsa.PublicKey, opts ...VerifierOption) (*Verifier, error) {
+	if key == nil {
+		return nil, errors.New("RS256 public key is required")
+	}
+	if key.Size()*8 < 2048 {
+		return nil, errors.New("RS256 public key must be at least 2048 bits")
+	}
+	return newVerifier(&Verifier{algorithm: RS256, key: key}, opts), nil
+}
+
+func newVerifier(v *Verifier, opts []VerifierOption) *Verifier {
+	v.leeway = time.Minute
+	v.now = time.Now
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{v.algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(v.now),
+	}
+	if v.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(v.audience))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+	return v
+}
+
+// Verify checks the signature and claims of a compact JWT and returns the caller it names.
+// Every failure matches ErrInvalidToken.
+func (v *Verifier) Verify(token string) (Principal, error) {
+	var claims Claims
+	if _, err := v.parser.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) { return v.key, nil }); err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return Principal{}, ErrTokenExpired
+		}
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	return Principal{Subject: claims.Subject, Roles: claims.Roles}, nil
+}
+
+// SignHS256 issues a token for claims signed with secret. The service only verifies tokens;
+// this is for tests and tooling.
+func SignHS256(claims Claims, secret []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// SignRS256 issues a token for claims signed with key. The service only verifies tokens;
+// this is for tests and tooling.
+func SignRS256(claims Claims, key *rsa.PrivateKey) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	secret = []byte("0123456789abcdef0123456789abcdef")
+	now    = time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func validClaims() Claims {
+	return Claims{Roles: []Role{FieldValidator}, RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "EMP001",
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	v, err := NewHS256Verifier(secret, WithClock(func() time.Time { return now }),
+		WithIssuer("https://id.example.com"), WithAudience("loan-service"))
+	require.NoError(t, err)
+
+	issue := func(t *testing.T, change func(*Claims)) string {
+		claims := validClaims()
+		claims.Issuer = "https://id.example.com"
+		claims.Audience = jwt.ClaimStrings{"loan-service"}
+		if change != nil {
+			change(&claims)
+		}
+		token, err := SignHS256(claims, secret)
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		p, err := v.Verify(issue(t, nil))
+		require.NoError(t, err)
+		assert.Equal(t, Principal{Subject: "EMP001", Roles: []Role{FieldValidator}}, p)
+	})
+
+	cases := []struct {
+		name   string
+		token  func(t *testing.T) string
+		reason string
+	}{
+		{"not a JWT", func(*testing.T) string { return "abc" }, "token is malformed"},
+		{"wrong secret", func(t *testing.T) string {
+			token, err := SignHS256(validClaims(), []byte(strings.Repeat("x", 32)))
+			require.NoError(t, err)
+			return token
+		}, "signature is invalid"},
+		{"tampered claims", func(t *testing.T) string {
+			parts := strings.Split(issue(t, nil), ".")
+			forged, _ := json.Marshal(Claims{Roles: []Role{Admin}, RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "EMP001",
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			}})
+			parts[1] = base64.RawURLEncoding.EncodeToString(forged)
+			return strings.Join(parts, ".")
+		}, "signature is invalid"},
+		{"unsigned", func(t *testing.T) string {
+			parts := strings.Split(issue(t, nil), ".")
+			parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+			return parts[0] + "." + parts[1] + "."
+		}, "signing method none is invalid"},
+		{"expired", func(t *testing.T) string {
+			return issue(t, func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * time.Minute)) })
+		}, "token has expired"},
+		{"without exp", func(t *testing.T) string { return issue(t, func(c *Claims) { c.ExpiresAt = nil }) }, "exp claim is required"},
+		{"without sub", func(t *testing.T) string { return issue(t, func(c *Claims) { c.Subject = "" }) }, "missing sub claim"},
+		{"not valid yet", func(t *testing.T) string {
+			return issue(t, func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(5 * time.Minute)) })
+		}, "not valid yet"},
+		{"other issuer", func(t *testing.T) string { return issue(t, func(c *Claims) { c.Issuer = "evil" }) }, "invalid issuer"},
+		{"other audience", func(t *testing.T) string {
+			return issue(t, func(c *Claims) { c.Audience = jwt.ClaimStrings{"billing", "reports"} })
+		}, "invalid audience"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.Verify(tc.token(t))
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			assert.ErrorContains(t, err, tc.reason)
+		})
+	}
+
+	t.Run("tolerates clock skew within the leeway", func(t *testing.T) {
+		_, err := v.Verify(issue(t, func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-30 * time.Second)) }))
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects short secrets", func(t *testing.T) {
+		_, err := NewHS256Verifier([]byte("too-short"))
+		assert.ErrorContains(t, err, "at least 32 bytes")
+	})
+}
+
+func TestVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	v, err := NewRS256Verifier(&key.PublicKey, WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	token, err := SignRS256(validClaims(), key)
+	require.NoError(t, err)
+	p, err := v.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "EMP001", p.Subject)
+
+	t.Run("rejects HS256 tokens signed with the public key", func(t *testing.T) {
+		der := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+		forged, err := SignHS256(validClaims(), pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: der}))
+		require.NoError(t, err)
+		_, err = v.Verify(forged)
+		assert.ErrorContains(t, err, "signing method HS256 is invalid")
+	})
+
+	t.Run("rejects tokens signed with another key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token, err := SignRS256(validClaims(), other)
+		require.NoError(t, err)
+		_, err = v.Verify(token)
+		assert.ErrorContains(t, err, "verification error")
+	})
+}
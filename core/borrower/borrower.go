@@ -0,0 +1,140 @@
+// Package borrower keeps the registry of borrowers: who they are, how much credit they have and whether
+// they are eligible for another loan.
+package borrower
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"loan-service/core/money"
+)
+
+var (
+	// ErrBorrowerNotFound is returned when no borrower is registered under an ID.
+	ErrBorrowerNotFound = errors.New("borrower not found")
+
+	// ErrBorrowerExists is returned when registering an ID or identity number that is already taken.
+	ErrBorrowerExists = errors.New("borrower is already registered")
+
+	// ErrCreditLimitExceeded is matched by every CreditLimitError and by loans in a currency the limit does not cover.
+	ErrCreditLimitExceeded = errors.New("credit limit exceeded")
+
+	// ErrLoanInProgress is returned when the policy allows a single loan in progress and the borrower
+	// already has one that is not yet disbursed.
+	ErrLoanInProgress = errors.New("borrower already has a loan that is not yet disbursed")
+)
+
+// Profile is how to reach a borrower.
+type Profile struct {
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// Borrower is a registered borrower. Their ID is the borrower ID used in loans and in bearer tokens.
+type Borrower struct {
+	ID             string       `json:"id"`
+	IdentityNumber string       `json:"identity_number"` // National identity number; a person can only register once
+	Profile        Profile      `json:"profile"`
+	CreditLimit    *money.Money `json:"credit_limit,omitempty"` // Most principal the borrower may owe across active loans; nil for no limit
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// CreditLimitError is returned when a new loan would take a borrower over their credit limit.
+type CreditLimitError struct {
+	BorrowerID  string
+	Limit       money.Money // The borrower's credit limit
+	Outstanding money.Money // The principal they would owe with the new loan
+}
+
+// Error implements the error interface.
+func (e *CreditLimitError) Error() string {
+	return fmt.Sprintf("loan would raise the principal owed by borrower %s to %s, over their credit limit of %s",
+		e.BorrowerID, e.Outstanding, e.Limit)
+}
+
+// Is reports whether target is ErrCreditLimitExceeded.
+func (e *CreditLimitError) Is(target error) bool {
+	return target == ErrCreditLimitExceeded
+}
+
+// Repository stores borrowers.
+//
+// Create fails with ErrBorrowerExists if the ID or identity number is taken; Get and Update fail with
+// ErrBorrowerNotFound for unknown IDs. List is ordered by registration time.
+type Repository interface {
+	Create(b Borrower) error
+	Get(id string) (Borrower, error)
+	List() ([]Borrower, error)
+	Update(b Borrower) error
+}
+
+// InMemoryRepository is a Repository for tests and development.
+type InMemoryRepository struct {
+	mu        sync.Mutex
+	borrowers map[string]Borrower
+}
+
+// NewInMemoryRepository creates an empty repository.
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{borrowers: make(map[string]Borrower)}
+}
+
+// Create stores a new borrower.
+func (r *InMemoryRepository) Create(b Borrower) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.borrowers {
+		if existing.ID == b.ID || existing.IdentityNumber == b.IdentityNumber {
+			return ErrBorrowerExists
+		}
+	}
+	r.borrowers[b.ID] = b
+	return nil
+}
+
+// Get returns a borrower by ID.
+func (r *InMemoryRepository) Get(id string) (Borrower, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.borrowers[id]
+	if !ok {
+		return Borrower{}, ErrBorrowerNotFound
+	}
+	return b, nil
+}
+
+// List returns every borrower.
+func (r *InMemoryRepository) List() ([]Borrower, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Borrower, 0, len(r.borrowers))
+	for _, b := range r.borrowers {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// Update replaces a stored borrower. The identity number cannot change.
+func (r *InMemoryRepository) Update(b Borrower) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.borrowers[b.ID]
+	if !ok {
+		return ErrBorrowerNotFound
+	}
+	b.IdentityNumber = existing.IdentityNumber
+	r.borrowers[b.ID] = b
+	return nil
+}
@@ -0,0 +1,99 @@
+package borrower
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+	"loan-service/database"
+)
+
+func idr(major int64) *money.Money {
+	m := money.FromMajor(major, money.IDR)
+	return &m
+}
+
+func TestCreditLimitError(t *testing.T) {
+	var err error = &CreditLimitError{BorrowerID: "B001", Limit: *idr(1000), Outstanding: *idr(1200)}
+	assert.ErrorIs(t, err, ErrCreditLimitExceeded)
+	assert.EqualError(t, err, "loan would raise the principal owed by borrower B001 to IDR 1200.00, over their credit limit of IDR 1000.00")
+}
+
+func TestInMemoryRepository(t *testing.T) {
+	testRepository(t, NewInMemoryRepository())
+}
+
+func TestSQLRepository(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "borrowers.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testRepository(t, NewSQLRepository(db))
+}
+
+func testRepository(t *testing.T, repo Repository) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	first := Borrower{ID: "B001", IdentityNumber: "3171000000000001", Profile: Profile{
+		Name: "Siti", Email: "siti@example.com", Phone: "+628111", Address: "Jl. Melati 1, Bogor",
+	}, CreatedAt: at, UpdatedAt: at}
+	second := Borrower{ID: "B002", IdentityNumber: "3171000000000002", Profile: Profile{Name: "Dewi"},
+		CreatedAt: at.Add(time.Minute), UpdatedAt: at.Add(time.Minute)}
+
+	t.Run("Create and Get", func(t *testing.T) {
+		require.NoError(t, repo.Create(second))
+		require.NoError(t, repo.Create(first))
+
+		got, err := repo.Get("B001")
+		require.NoError(t, err)
+		assert.Equal(t, first, got)
+	})
+
+	t.Run("Create rejects a taken ID or identity number", func(t *testing.T) {
+		other := Borrower{ID: "B001", IdentityNumber: "3171000000000009", Profile: Profile{Name: "Other"}, CreatedAt: at, UpdatedAt: at}
+		assert.ErrorIs(t, repo.Create(other), ErrBorrowerExists)
+
+		other.ID, other.IdentityNumber = "B009", first.IdentityNumber
+		assert.ErrorIs(t, repo.Create(other), ErrBorrowerExists)
+	})
+
+	t.Run("Get unknown borrower", func(t *testing.T) {
+		_, err := repo.Get("missing")
+		assert.ErrorIs(t, err, ErrBorrowerNotFound)
+	})
+
+	t.Run("List in registration order", func(t *testing.T) {
+		list, err := repo.List()
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, "B001", list[0].ID)
+		assert.Equal(t, "B002", list[1].ID)
+	})
+
+	t.Run("Update round-trips profile and credit limit", func(t *testing.T) {
+		updated := first
+		updated.Profile.Phone = "+628222"
+		updated.CreditLimit = idr(10000000)
+		updated.UpdatedAt = at.Add(time.Hour)
+		require.NoError(t, repo.Update(updated))
+
+		got, err := repo.Get("B001")
+		require.NoError(t, err)
+		assert.Equal(t, updated, got)
+
+		updated.CreditLimit = nil
+		updated.IdentityNumber = "changed"
+		require.NoError(t, repo.Update(updated))
+		got, err = repo.Get("B001")
+		require.NoError(t, err)
+		assert.Nil(t, got.CreditLimit)
+		assert.Equal(t, first.IdentityNumber, got.IdentityNumber)
+	})
+
+	t.Run("Update unknown borrower", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Update(Borrower{ID: "missing"}), ErrBorrowerNotFound)
+	})
+}
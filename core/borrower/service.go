@@ -0,0 +1,166 @@
+package borrower
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"loan-service/core/money"
+)
+
+// Policy holds the product rules that decide whether a borrower may apply for another loan.
+type Policy struct {
+	// SingleLoanInProgress refuses a new loan while the borrower has another one that is not yet disbursed.
+	SingleLoanInProgress bool
+}
+
+// Service registers borrowers, keeps their profile and credit limit, and vets their loan applications.
+type Service struct {
+	repo   Repository
+	policy Policy
+	now    func() time.Time
+	mu     sync.Mutex // Serialises read-modify-write updates of borrowers
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithPolicy sets the product rules applied by CheckLoan. Defaults to the zero Policy.
+func WithPolicy(policy Policy) Option {
+	return func(s *Service) {
+		s.policy = policy
+	}
+}
+
+// WithClock replaces the service's source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// NewService creates a service on top of repo.
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds a new borrower without a credit limit.
+func (s *Service) Register(id, identityNumber string, profile Profile) (Borrower, error) {
+	if id == "" {
+		return Borrower{}, errors.New("borrower id is required")
+	}
+	identityNumber = strings.TrimSpace(identityNumber)
+	if identityNumber == "" {
+		return Borrower{}, errors.New("identity number is required")
+	}
+	if err := validateProfile(profile); err != nil {
+		return Borrower{}, err
+	}
+
+	now := s.timestamp()
+	b := Borrower{ID: id, IdentityNumber: identityNumber, Profile: profile, CreatedAt: now, UpdatedAt: now}
+	if err := s.repo.Create(b); err != nil {
+		return Borrower{}, err
+	}
+	return b, nil
+}
+
+// Get returns a borrower by ID.
+func (s *Service) Get(id string) (Borrower, error) {
+	return s.repo.Get(id)
+}
+
+// List returns every borrower, oldest registration first.
+func (s *Service) List() ([]Borrower, error) {
+	return s.repo.List()
+}
+
+// UpdateProfile replaces a borrower's profile.
+func (s *Service) UpdateProfile(id string, profile Profile) (Borrower, error) {
+	if err := validateProfile(profile); err != nil {
+		return Borrower{}, err
+	}
+	return s.update(id, func(b *Borrower) {
+		b.Profile = profile
+	})
+}
+
+// SetCreditLimit replaces a borrower's credit limit; nil removes it.
+func (s *Service) SetCreditLimit(id string, limit *money.Money) (Borrower, error) {
+	if limit != nil && !limit.IsPositive() {
+		return Borrower{}, errors.New("credit limit must be positive")
+	}
+	return s.update(id, func(b *Borrower) {
+		b.CreditLimit = limit
+	})
+}
+
+// CheckLoan vets a loan application before it is accepted. outstanding is the principal the borrower would
+// then owe across their active loans, including the new one, and pending the number of their other loans
+// that are not yet disbursed.
+//
+// It fails with ErrBorrowerNotFound for unknown borrowers, ErrLoanInProgress if the policy allows a single
+// loan in progress and pending is not zero, and a *CreditLimitError if outstanding is over their credit limit.
+func (s *Service) CheckLoan(borrowerID string, principal, outstanding money.Money, pending int) error {
+	b, err := s.repo.Get(borrowerID)
+	if err != nil {
+		return err
+	}
+	if s.policy.SingleLoanInProgress && pending > 0 {
+		return fmt.Errorf("%w (borrower %s has %d)", ErrLoanInProgress, b.ID, pending)
+	}
+
+	limit := b.CreditLimit
+	if limit == nil {
+		return nil
+	}
+	if limit.Currency() != principal.Currency() {
+		return fmt.Errorf("%w: borrower %s may only borrow in %s", ErrCreditLimitExceeded, b.ID, limit.Currency())
+	}
+	if outstanding.Cmp(*limit) > 0 {
+		return &CreditLimitError{BorrowerID: b.ID, Limit: *limit, Outstanding: outstanding}
+	}
+	return nil
+}
+
+// update applies change to a stored borrower and saves it.
+func (s *Service) update(id string, change func(*Borrower)) (Borrower, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := s.repo.Get(id)
+	if err != nil {
+		return Borrower{}, err
+	}
+	change(&b)
+	b.UpdatedAt = s.timestamp()
+	if err := s.repo.Update(b); err != nil {
+		return Borrower{}, err
+	}
+	return b, nil
+}
+
+// timestamp returns the current time as every supported database stores it.
+func (s *Service) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
+}
+
+// validateProfile checks that the profile names the borrower and has a valid email address, if any.
+func validateProfile(profile Profile) error {
+	if profile.Name == "" {
+		return errors.New("borrower name is required")
+	}
+	if profile.Email != "" {
+		if _, err := mail.ParseAddress(profile.Email); err != nil {
+			return fmt.Errorf("invalid email address: %w", err)
+		}
+	}
+	return nil
+}
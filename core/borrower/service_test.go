@@ -0,0 +1,107 @@
+package borrower
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+)
+
+func TestService_Register(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	svc := NewService(NewInMemoryRepository(), WithClock(func() time.Time { return now }))
+	profile := Profile{Name: "Siti", Email: "siti@example.com"}
+
+	b, err := svc.Register("B001", " 3171000000000001 ", profile)
+	require.NoError(t, err)
+	assert.Equal(t, Borrower{ID: "B001", IdentityNumber: "3171000000000001", Profile: profile, CreatedAt: now, UpdatedAt: now}, b)
+
+	_, err = svc.Register("B002", "3171000000000001", profile)
+	assert.ErrorIs(t, err, ErrBorrowerExists)
+	_, err = svc.Register("", "3171000000000002", profile)
+	assert.ErrorContains(t, err, "id is required")
+	_, err = svc.Register("B002", " ", profile)
+	assert.ErrorContains(t, err, "identity number is required")
+	_, err = svc.Register("B002", "3171000000000002", Profile{})
+	assert.ErrorContains(t, err, "name is required")
+	_, err = svc.Register("B002", "3171000000000002", Profile{Name: "Dewi", Email: "not-an-address"})
+	assert.ErrorContains(t, err, "invalid email address")
+}
+
+func TestService_UpdateProfileAndCreditLimit(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	clock := now
+	svc := NewService(NewInMemoryRepository(), WithClock(func() time.Time { return clock }))
+	_, err := svc.Register("B001", "3171000000000001", Profile{Name: "Siti"})
+	require.NoError(t, err)
+
+	clock = now.Add(time.Hour)
+	b, err := svc.UpdateProfile("B001", Profile{Name: "Siti Aminah", Phone: "+628111"})
+	require.NoError(t, err)
+	assert.Equal(t, Profile{Name: "Siti Aminah", Phone: "+628111"}, b.Profile)
+	assert.Equal(t, clock, b.UpdatedAt)
+	_, err = svc.UpdateProfile("B001", Profile{})
+	assert.ErrorContains(t, err, "name is required")
+	_, err = svc.UpdateProfile("missing", Profile{Name: "X"})
+	assert.ErrorIs(t, err, ErrBorrowerNotFound)
+
+	b, err = svc.SetCreditLimit("B001", idr(5000000))
+	require.NoError(t, err)
+	assert.Equal(t, idr(5000000), b.CreditLimit)
+	_, err = svc.SetCreditLimit("B001", idr(0))
+	assert.ErrorContains(t, err, "must be positive")
+
+	stored, err := svc.Get("B001")
+	require.NoError(t, err)
+	assert.Equal(t, b, stored)
+
+	b, err = svc.SetCreditLimit("B001", nil)
+	require.NoError(t, err)
+	assert.Nil(t, b.CreditLimit)
+}
+
+func TestService_CheckLoan(t *testing.T) {
+	repo := NewInMemoryRepository()
+	svc := NewService(repo)
+	strict := NewService(repo, WithPolicy(Policy{SingleLoanInProgress: true}))
+	_, err := svc.Register("open", "1", Profile{Name: "Open"})
+	require.NoError(t, err)
+	_, err = svc.Register("limited", "2", Profile{Name: "Limited"})
+	require.NoError(t, err)
+	_, err = svc.SetCreditLimit("limited", idr(5000))
+	require.NoError(t, err)
+	usd := money.FromMajor(500, money.USD)
+
+	cases := []struct {
+		name        string
+		svc         *Service
+		borrower    string
+		principal   money.Money
+		outstanding money.Money
+		pending     int
+		is          error
+		err         string
+	}{
+		{name: "no limit", svc: svc, borrower: "open", principal: *idr(1000000), outstanding: *idr(9000000), pending: 2},
+		{name: "within limit", svc: svc, borrower: "limited", principal: *idr(2000), outstanding: *idr(5000)},
+		{name: "unknown", svc: svc, borrower: "missing", principal: *idr(100), outstanding: *idr(100), is: ErrBorrowerNotFound},
+		{name: "over limit", svc: svc, borrower: "limited", principal: *idr(2000), outstanding: *idr(5001), is: ErrCreditLimitExceeded, err: "over their credit limit of IDR 5000.00"},
+		{name: "other currency", svc: svc, borrower: "limited", principal: usd, outstanding: usd, is: ErrCreditLimitExceeded, err: "may only borrow in IDR"},
+		{name: "loan in progress allowed", svc: svc, borrower: "limited", principal: *idr(100), outstanding: *idr(200), pending: 1},
+		{name: "loan in progress refused", svc: strict, borrower: "open", principal: *idr(100), outstanding: *idr(200), pending: 1, is: ErrLoanInProgress, err: "borrower open has 1"},
+		{name: "no loan in progress", svc: strict, borrower: "open", principal: *idr(100), outstanding: *idr(200)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.svc.CheckLoan(tc.borrower, tc.principal, tc.outstanding, tc.pending)
+			if tc.is == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.is)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package borrower
+
+import (
+	"database/sql"
+	"fmt"
+
+	"loan-service/core/money"
+	"loan-service/database"
+)
+
+// SQLRepository stores borrowers in the borrowers table.
+type SQLRepository struct {
+	db *database.DB
+}
+
+// NewSQLRepository creates a repository on top of an already migrated database.
+func NewSQLRepository(db *database.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// borrowerSelect lists the columns read by query, in order.
+const borrowerSelect = `id, identity_number, name, email, phone, address,
+	credit_limit_minor, credit_limit_currency, created_at, updated_at`
+
+// Create stores a new borrower.
+func (r *SQLRepository) Create(b Borrower) error {
+	limit, currency := creditLimit(b.CreditLimit)
+	res, err := r.db.Exec(r.db.Dialect.Rebind(`INSERT INTO borrowers (`+borrowerSelect+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`),
+		b.ID, b.IdentityNumber, b.Profile.Name, b.Profile.Email, b.Profile.Phone, b.Profile.Address,
+		limit, currency, b.CreatedAt.UTC(), b.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("insert borrower: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrBorrowerExists
+	}
+	return nil
+}
+
+// Get returns a borrower by ID.
+func (r *SQLRepository) Get(id string) (Borrower, error) {
+	list, err := r.query(`WHERE id = ?`, id)
+	if err != nil {
+		return Borrower{}, err
+	}
+	if len(list) == 0 {
+		return Borrower{}, ErrBorrowerNotFound
+	}
+	return list[0], nil
+}
+
+// List returns every borrower.
+func (r *SQLRepository) List() ([]Borrower, error) {
+	return r.query(`ORDER BY created_at, id`)
+}
+
+// Update replaces a stored borrower. The identity number cannot change.
+func (r *SQLRepository) Update(b Borrower) error {
+	limit, currency := creditLimit(b.CreditLimit)
+	res, err := r.db.Exec(r.db.Dialect.Rebind(`UPDATE borrowers SET name = ?, email = ?, phone = ?, address = ?,
+		credit_limit_minor = ?, credit_limit_currency = ?, updated_at = ? WHERE id = ?`),
+		b.Profile.Name, b.Profile.Email, b.Profile.Phone, b.Profile.Address, limit, currency, b.UpdatedAt.UTC(), b.ID)
+	if err != nil {
+		return fmt.Errorf("update borrower: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrBorrowerNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) query(clause string, args ...any) ([]Borrower, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT `+borrowerSelect+` FROM borrowers `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query borrowers: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	list := []Borrower{}
+	for rows.Next() {
+		var (
+			b        Borrower
+			limit    sql.NullInt64
+			currency string
+		)
+		if err := rows.Scan(&b.ID, &b.IdentityNumber, &b.Profile.Name, &b.Profile.Email, &b.Profile.Phone, &b.Profile.Address,
+			&limit, &currency, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan borrower: %w", err)
+		}
+		if limit.Valid {
+			m := money.New(limit.Int64, money.Currency(currency))
+			b.CreditLimit = &m
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+// creditLimit returns the column values of an optional credit limit.
+func creditLimit(m *money.Money) (minor any, currency string) {
+	if m == nil {
+		return nil, ""
+	}
+	return m.MinorUnits(), string(m.Currency())
+}
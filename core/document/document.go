@@ -0,0 +1,103 @@
+// Package document stores uploaded files, such as the photo proof taken at approval and the signed
+// agreement handed over at disbursement, together with what is known about their content.
+package document
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDocumentNotFound is returned when no document is stored under an ID.
+	ErrDocumentNotFound = errors.New("document not found")
+
+	// ErrTooLarge is returned when an upload is over the size limit.
+	ErrTooLarge = errors.New("document is too large")
+
+	// ErrUnsupportedType is returned when the content of an upload is not of an accepted type.
+	ErrUnsupportedType = errors.New("unsupported document type")
+)
+
+// Document describes a stored file.
+type Document struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`     // Name of the uploaded file, without directories
+	ContentType string    `json:"content_type"` // MIME type detected from the content
+	Size        int64     `json:"size"`         // Length of the content in bytes
+	SHA256      string    `json:"sha256"`       // Hex SHA-256 of the content
+	UploadedBy  string    `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store keeps documents and their content. Implementations decide where the bytes live.
+//
+// Put stores the content read from r under doc.ID, along with doc; a document is only visible once
+// both are stored. Get and Open fail with ErrDocumentNotFound for unknown IDs. Delete removes a document
+// and its content; deleting an unknown ID is not an error.
+type Store interface {
+	Put(doc Document, r io.Reader) error
+	Get(id string) (Document, error)
+	Open(id string) (io.ReadCloser, error)
+	Delete(id string) error
+}
+
+type storedDocument struct {
+	doc     Document
+	content []byte
+}
+
+// InMemoryStore is a Store for tests and development.
+type InMemoryStore struct {
+	mu        sync.Mutex
+	documents map[string]storedDocument
+}
+
+// NewInMemoryStore creates an empty store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{documents: make(map[string]storedDocument)}
+}
+
+// Put implements Store.
+func (s *InMemoryStore) Put(doc Document, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.documents[doc.ID] = storedDocument{doc: doc, content: content}
+	return nil
+}
+
+// Get implements Store.
+func (s *InMemoryStore) Get(id string) (Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.documents[id]
+	if !ok {
+		return Document{}, ErrDocumentNotFound
+	}
+	return stored.doc, nil
+}
+
+// Open implements Store.
+func (s *InMemoryStore) Open(id string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.documents[id]
+	if !ok {
+		return nil, ErrDocumentNotFound
+	}
+	return io.NopCloser(bytes.NewReader(stored.content)), nil
+}
+
+// Delete implements Store.
+func (s *InMemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.documents, id)
+	return nil
+}
@@ -0,0 +1,92 @@
+package document
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, NewInMemoryStore())
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "documents")
+	store, err := NewLocalStore(dir)
+	require.NoError(t, err)
+
+	testStore(t, store)
+
+	t.Run("Rejects IDs that are not plain file names", func(t *testing.T) {
+		assert.ErrorContains(t, store.Put(Document{ID: "../escape"}, strings.NewReader("x")), "invalid document id")
+		_, err := store.Get("../escape")
+		assert.ErrorIs(t, err, ErrDocumentNotFound)
+	})
+
+	t.Run("Leaves no temporary files behind", func(t *testing.T) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		for _, e := range entries {
+			assert.False(t, strings.HasPrefix(e.Name(), ".upload-"), e.Name())
+		}
+	})
+
+	t.Run("Survives a restart", func(t *testing.T) {
+		reopened, err := NewLocalStore(dir)
+		require.NoError(t, err)
+		doc, err := reopened.Get("doc-1")
+		require.NoError(t, err)
+		assert.Equal(t, "proof.jpg", doc.Filename)
+	})
+}
+
+func testStore(t *testing.T, store Store) {
+	doc := Document{
+		ID: "doc-1", Filename: "proof.jpg", ContentType: "image/jpeg", Size: 5, SHA256: "abc",
+		UploadedBy: "EMP001", CreatedAt: time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Put, Get and Open", func(t *testing.T) {
+		require.NoError(t, store.Put(doc, strings.NewReader("hello")))
+
+		got, err := store.Get("doc-1")
+		require.NoError(t, err)
+		assert.Equal(t, doc, got)
+
+		content, err := store.Open("doc-1")
+		require.NoError(t, err)
+		defer func() { _ = content.Close() }()
+		data, err := io.ReadAll(content)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+	})
+
+	t.Run("Unknown document", func(t *testing.T) {
+		_, err := store.Get("missing")
+		assert.ErrorIs(t, err, ErrDocumentNotFound)
+		_, err = store.Open("missing")
+		assert.ErrorIs(t, err, ErrDocumentNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		other := doc
+		other.ID = "doc-2"
+		require.NoError(t, store.Put(other, strings.NewReader("bye")))
+
+		require.NoError(t, store.Delete("doc-2"))
+		_, err := store.Get("doc-2")
+		assert.ErrorIs(t, err, ErrDocumentNotFound)
+		_, err = store.Open("doc-2")
+		assert.ErrorIs(t, err, ErrDocumentNotFound)
+		assert.NoError(t, store.Delete("doc-2"), "deleting again is not an error")
+
+		_, err = store.Get("doc-1")
+		assert.NoError(t, err, "other documents are kept")
+	})
+}
@@ -0,0 +1,118 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps documents in a directory of the local filesystem: the content of each in a file named
+// after its ID and the description next to it in <ID>.json.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates a store in dir, creating the directory if needed.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create document directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Put implements Store. The content is written first, so a crash never leaves a description without content.
+func (s *LocalStore) Put(doc Document, r io.Reader) error {
+	if !validID(doc.ID) {
+		return fmt.Errorf("invalid document id %q", doc.ID)
+	}
+	if err := s.write(doc.ID, func(f *os.File) error {
+		_, err := io.Copy(f, r)
+		return err
+	}); err != nil {
+		return fmt.Errorf("write document content: %w", err)
+	}
+	if err := s.write(doc.ID+".json", func(f *os.File) error {
+		return json.NewEncoder(f).Encode(doc)
+	}); err != nil {
+		return fmt.Errorf("write document description: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *LocalStore) Get(id string) (Document, error) {
+	if !validID(id) {
+		return Document{}, ErrDocumentNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Document{}, ErrDocumentNotFound
+	}
+	if err != nil {
+		return Document{}, fmt.Errorf("read document description: %w", err)
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Document{}, fmt.Errorf("decode document description: %w", err)
+	}
+	return doc, nil
+}
+
+// Open implements Store.
+func (s *LocalStore) Open(id string) (io.ReadCloser, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(s.dir, id))
+	if err != nil {
+		return nil, fmt.Errorf("open document content: %w", err)
+	}
+	return f, nil
+}
+
+// Delete implements Store. The description goes first, so the document is never visible without content.
+func (s *LocalStore) Delete(id string) error {
+	if !validID(id) {
+		return nil
+	}
+	for _, name := range []string{id + ".json", id} {
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete document: %w", err)
+		}
+	}
+	return nil
+}
+
+// write atomically replaces the file name in the store's directory with what fill writes.
+func (s *LocalStore) write(name string, fill func(*os.File) error) error {
+	f, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }() // Fails harmlessly once the file has been renamed
+
+	if err := fill(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(s.dir, name))
+}
+
+// validID reports whether id is safe to use as a file name: letters, digits, '-' and '_' only.
+func validID(id string) bool {
+	return id != "" && strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) < 0
+}
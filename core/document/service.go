@@ -0,0 +1,139 @@
+package document
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMaxSize is the largest upload accepted unless WithMaxSize says otherwise.
+const DefaultMaxSize = 10 << 20
+
+// AcceptedTypes are the content types accepted for upload: photos and PDF documents.
+var AcceptedTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+// Service accepts uploads, describes their content and serves them back.
+type Service struct {
+	store   Store
+	maxSize int64
+	now     func() time.Time
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithMaxSize sets the largest upload accepted, in bytes. Defaults to DefaultMaxSize.
+func WithMaxSize(n int64) Option {
+	return func(s *Service) {
+		s.maxSize = n
+	}
+}
+
+// WithClock replaces the service's source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// NewService creates a service on top of store.
+func NewService(store Store, opts ...Option) *Service {
+	s := &Service{store: store, maxSize: DefaultMaxSize, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// MaxSize returns the largest upload accepted, in bytes.
+func (s *Service) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload stores the content read from r as a new document.
+// The content type is detected from the content itself, whatever the file name or client claims.
+//
+// It fails with ErrTooLarge if the content is over the size limit and ErrUnsupportedType unless
+// its type is one of AcceptedTypes.
+func (s *Service) Upload(filename string, r io.Reader, uploadedBy string) (Document, error) {
+	content, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return Document{}, fmt.Errorf("read upload: %w", err)
+	}
+	if int64(len(content)) > s.maxSize {
+		return Document{}, fmt.Errorf("%w: the limit is %d bytes", ErrTooLarge, s.maxSize)
+	}
+	if len(content) == 0 {
+		return Document{}, errors.New("document is empty")
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(content), ";")
+	if !slices.Contains(AcceptedTypes, contentType) {
+		return Document{}, fmt.Errorf("%w %s (accepted: %s)", ErrUnsupportedType, contentType, strings.Join(AcceptedTypes, ", "))
+	}
+
+	sum := sha256.Sum256(content)
+	doc := Document{
+		ID:          uuid.NewString(),
+		Filename:    baseName(filename),
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		UploadedBy:  uploadedBy,
+		CreatedAt:   s.now().UTC().Truncate(time.Microsecond),
+	}
+	if err := s.store.Put(doc, bytes.NewReader(content)); err != nil {
+		return Document{}, err
+	}
+	return doc, nil
+}
+
+// Get returns the description of a document.
+func (s *Service) Get(id string) (Document, error) {
+	return s.store.Get(id)
+}
+
+// Open returns the description of a document and its content, which the caller must close.
+func (s *Service) Open(id string) (Document, io.ReadCloser, error) {
+	doc, err := s.store.Get(id)
+	if err != nil {
+		return Document{}, nil, err
+	}
+	content, err := s.store.Open(id)
+	if err != nil {
+		return Document{}, nil, err
+	}
+	return doc, content, nil
+}
+
+// Exists reports whether a document is stored under id.
+func (s *Service) Exists(id string) (bool, error) {
+	_, err := s.store.Get(id)
+	if errors.Is(err, ErrDocumentNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete removes a document and its content. Deleting an unknown document is not an error.
+func (s *Service) Delete(id string) error {
+	return s.store.Delete(id)
+}
+
+// baseName strips any directories, Unix or Windows style, from a client-supplied file name.
+func baseName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
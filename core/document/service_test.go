@@ -0,0 +1,89 @@
+package document
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pdf is the start of a PDF file, enough for its type to be detected.
+var pdf = []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<<>>\nendobj\n")
+
+// png is the start of a PNG file.
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestService_Upload(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	svc := NewService(NewInMemoryStore(), WithMaxSize(64), WithClock(func() time.Time { return now }))
+
+	doc, err := svc.Upload("C:\\Users\\field\\agreement.pdf", bytes.NewReader(pdf), "EMP001")
+	require.NoError(t, err)
+	sum := sha256.Sum256(pdf)
+	assert.NotEmpty(t, doc.ID)
+	assert.Equal(t, Document{
+		ID: doc.ID, Filename: "agreement.pdf", ContentType: "application/pdf", Size: int64(len(pdf)),
+		SHA256: hex.EncodeToString(sum[:]), UploadedBy: "EMP001", CreatedAt: now,
+	}, doc)
+
+	stored, content, err := svc.Open(doc.ID)
+	require.NoError(t, err)
+	defer func() { _ = content.Close() }()
+	data, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, doc, stored)
+	assert.Equal(t, pdf, data)
+
+	ok, err := svc.Exists(doc.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = svc.Exists("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	cases := []struct {
+		name     string
+		filename string
+		content  []byte
+		is       error
+		err      string
+	}{
+		{name: "type from content, not name", filename: "photo.pdf", content: png},
+		{name: "directories are stripped", filename: "../../etc/passwd.png", content: png},
+		{name: "empty", filename: "empty.pdf", err: "document is empty"},
+		{name: "too large", filename: "big.pdf", content: append(pdf, bytes.Repeat([]byte(" "), 64)...), is: ErrTooLarge, err: "the limit is 64 bytes"},
+		{name: "unsupported type", filename: "notes.pdf", content: []byte("just some text"), is: ErrUnsupportedType, err: "text/plain"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := svc.Upload(tc.filename, bytes.NewReader(tc.content), "EMP001")
+			if tc.err == "" {
+				require.NoError(t, err)
+				assert.False(t, strings.ContainsAny(doc.Filename, `/\`))
+				return
+			}
+			if tc.is != nil {
+				assert.ErrorIs(t, err, tc.is)
+			}
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	for in, want := range map[string]string{
+		"proof.jpg":                "proof.jpg",
+		"photos/proof.jpg":         "proof.jpg",
+		`C:\Users\field\proof.jpg`: "proof.jpg",
+		"../":                      "",
+		"":                         "",
+	} {
+		assert.Equal(t, want, baseName(in), in)
+	}
+}
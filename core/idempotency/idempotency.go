@@ -0,0 +1,102 @@
+// Package idempotency remembers the outcome of requests made with an idempotency key,
+// so a client retrying a request gets the original response instead of repeating its effect.
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// Retention is how long a key is remembered. Afterwards it can be used for a new request.
+const Retention = 24 * time.Hour
+
+// Record is a request made with an idempotency key and, once it finished, its response.
+type Record struct {
+	Scope       string            // What the key applies to, e.g. "POST /loans"
+	Key         string            // Key chosen by the client
+	Fingerprint string            // Hash of the request body, to detect a key reused for another request
+	Status      int               // HTTP status of the response; 0 while the request is in progress
+	Header      map[string]string // Response headers worth replaying (e.g. Content-Type, ETag)
+	Body        []byte            // Response body
+	CreatedAt   time.Time         // When the first request with the key arrived
+}
+
+// InProgress reports whether the first request with the key has not finished yet.
+func (r Record) InProgress() bool {
+	return r.Status == 0
+}
+
+// expired reports whether the record is older than Retention at the given time.
+func (r Record) expired(at time.Time) bool {
+	return !r.CreatedAt.After(at.Add(-Retention))
+}
+
+// Store keeps records per scope and key.
+//
+// Begin claims the scope and key of rec for a new request and returns true. If they are already
+// claimed by a record that has not expired (relative to rec.CreatedAt), it returns that record and false.
+// Complete stores the response of a claimed request; Release forgets the claim so the key can be used again.
+type Store interface {
+	Begin(rec Record) (Record, bool, error)
+	Complete(rec Record) error
+	Release(scope, key string) error
+}
+
+type recordKey struct{ scope, key string }
+
+// sweepInterval is how often InMemoryStore drops expired records.
+const sweepInterval = time.Hour
+
+// InMemoryStore is a Store for tests and single-instance development setups.
+// Expired records are dropped on Begin, at most once per sweepInterval, so memory stays bounded.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	records map[recordKey]Record
+	swept   time.Time // When expired records were last dropped
+}
+
+// NewInMemoryStore creates an empty store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{records: make(map[recordKey]Record)}
+}
+
+// Begin implements Store.
+func (s *InMemoryStore) Begin(rec Record) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec.CreatedAt.Sub(s.swept) >= sweepInterval {
+		s.sweep(rec.CreatedAt)
+	}
+	k := recordKey{rec.Scope, rec.Key}
+	if existing, ok := s.records[k]; ok && !existing.expired(rec.CreatedAt) {
+		return existing, false, nil
+	}
+	s.records[k] = rec
+	return rec, true, nil
+}
+
+// Complete implements Store.
+func (s *InMemoryStore) Complete(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[recordKey{rec.Scope, rec.Key}] = rec
+	return nil
+}
+
+// Release implements Store.
+func (s *InMemoryStore) Release(scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, recordKey{scope, key})
+	return nil
+}
+
+// sweep drops the records that have expired at the given time. The caller holds s.mu.
+func (s *InMemoryStore) sweep(at time.Time) {
+	for k, rec := range s.records {
+		if rec.expired(at) {
+			delete(s.records, k)
+		}
+	}
+	s.swept = at
+}
@@ -0,0 +1,124 @@
+package idempotency
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/database"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, NewInMemoryStore())
+}
+
+func TestInMemoryStore_DropsExpiredRecords(t *testing.T) {
+	store := NewInMemoryStore()
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	for _, key := range []string{"old-1", "old-2"} {
+		_, _, err := store.Begin(Record{Scope: "POST /loans", Key: key, CreatedAt: at})
+		require.NoError(t, err)
+	}
+	_, _, err := store.Begin(Record{Scope: "POST /loans", Key: "recent", CreatedAt: at.Add(Retention - time.Minute)})
+	require.NoError(t, err)
+	assert.Len(t, store.records, 3, "nothing expired yet")
+
+	_, _, err = store.Begin(Record{Scope: "POST /loans", Key: "new", CreatedAt: at.Add(Retention + sweepInterval)})
+	require.NoError(t, err)
+	assert.Len(t, store.records, 2, "expired keys are dropped without being used again")
+	assert.Contains(t, store.records, recordKey{"POST /loans", "recent"})
+}
+
+func TestSQLStore(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "idempotency.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testStore(t, NewSQLStore(db))
+}
+
+// testStore is the behaviour every Store implementation must satisfy.
+func testStore(t *testing.T, store Store) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	rec := Record{Scope: "POST /loans", Key: "key-1", Fingerprint: "abc", CreatedAt: at}
+
+	t.Run("First request claims the key", func(t *testing.T) {
+		got, claimed, err := store.Begin(rec)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		assert.True(t, got.InProgress())
+	})
+
+	t.Run("Retry sees the request in progress", func(t *testing.T) {
+		got, claimed, err := store.Begin(Record{Scope: rec.Scope, Key: rec.Key, Fingerprint: "abc", CreatedAt: at.Add(time.Second)})
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		assert.True(t, got.InProgress())
+		assert.Equal(t, "abc", got.Fingerprint)
+	})
+
+	t.Run("Retry gets the stored response", func(t *testing.T) {
+		done := rec
+		done.Status = 201
+		done.Header = map[string]string{"Content-Type": "application/json", "ETag": `"1"`}
+		done.Body = []byte(`{"id":"L1"}`)
+		require.NoError(t, store.Complete(done))
+
+		got, claimed, err := store.Begin(Record{Scope: rec.Scope, Key: rec.Key, Fingerprint: "other", CreatedAt: at.Add(time.Minute)})
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, 201, got.Status)
+		assert.Equal(t, done.Header, got.Header)
+		assert.JSONEq(t, `{"id":"L1"}`, string(got.Body))
+		assert.Equal(t, "abc", got.Fingerprint, "the original request is kept")
+		assert.True(t, at.Equal(got.CreatedAt))
+	})
+
+	t.Run("Keys are scoped", func(t *testing.T) {
+		_, claimed, err := store.Begin(Record{Scope: "POST /loans/L1/invest", Key: rec.Key, Fingerprint: "abc", CreatedAt: at})
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	})
+
+	t.Run("Expired keys can be reused", func(t *testing.T) {
+		_, claimed, err := store.Begin(Record{Scope: rec.Scope, Key: rec.Key, Fingerprint: "new", CreatedAt: at.Add(Retention)})
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	})
+
+	t.Run("Released keys can be reused", func(t *testing.T) {
+		fresh := Record{Scope: rec.Scope, Key: "key-2", Fingerprint: "abc", CreatedAt: at}
+		_, _, _ = store.Begin(fresh)
+		require.NoError(t, store.Release(fresh.Scope, fresh.Key))
+		_, claimed, err := store.Begin(fresh)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	})
+
+	t.Run("Only one concurrent request claims a key", func(t *testing.T) {
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			claimed int
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, ok, err := store.Begin(Record{Scope: rec.Scope, Key: "key-3", Fingerprint: "abc", CreatedAt: at})
+				assert.NoError(t, err)
+				if ok {
+					mu.Lock()
+					claimed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, claimed)
+	})
+}
@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"loan-service/database"
+)
+
+// SQLStore keeps records in the idempotency_keys table, so retries are recognised across restarts
+// and by every instance of the service.
+type SQLStore struct {
+	db *database.DB
+}
+
+// NewSQLStore creates a store on top of an already migrated database.
+func NewSQLStore(db *database.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Begin implements Store. Claims are made with an insert, so concurrent requests with the same key
+// cannot both succeed. Every expired record is deleted first, not only the one for rec's key.
+func (s *SQLStore) Begin(rec Record) (Record, bool, error) {
+	if _, err := s.db.Exec(s.db.Dialect.Rebind(`DELETE FROM idempotency_keys WHERE created_at <= ?`),
+		rec.CreatedAt.Add(-Retention).UTC()); err != nil {
+		return Record{}, false, fmt.Errorf("expire idempotency key: %w", err)
+	}
+
+	res, err := s.db.Exec(s.db.Dialect.Rebind(`INSERT INTO idempotency_keys
+		(scope, idempotency_key, fingerprint, status, header, body, created_at)
+		VALUES (?, ?, ?, 0, '', '', ?) ON CONFLICT (scope, idempotency_key) DO NOTHING`),
+		rec.Scope, rec.Key, rec.Fingerprint, rec.CreatedAt.UTC())
+	if err != nil {
+		return Record{}, false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return Record{}, false, err
+	} else if n == 1 {
+		return rec, true, nil
+	}
+
+	existing, err := s.get(rec.Scope, rec.Key)
+	return existing, false, err
+}
+
+// Complete implements Store.
+func (s *SQLStore) Complete(rec Record) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return fmt.Errorf("encode response headers: %w", err)
+	}
+	if _, err := s.db.Exec(s.db.Dialect.Rebind(`UPDATE idempotency_keys SET status = ?, header = ?, body = ?
+		WHERE scope = ? AND idempotency_key = ?`),
+		rec.Status, string(header), string(rec.Body), rec.Scope, rec.Key); err != nil {
+		return fmt.Errorf("store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release implements Store.
+func (s *SQLStore) Release(scope, key string) error {
+	if _, err := s.db.Exec(s.db.Dialect.Rebind(`DELETE FROM idempotency_keys
+		WHERE scope = ? AND idempotency_key = ?`), scope, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) get(scope, key string) (Record, error) {
+	var (
+		rec          Record
+		header, body string
+	)
+	if err := s.db.QueryRow(s.db.Dialect.Rebind(`SELECT scope, idempotency_key, fingerprint, status, header, body, created_at
+		FROM idempotency_keys WHERE scope = ? AND idempotency_key = ?`), scope, key).
+		Scan(&rec.Scope, &rec.Key, &rec.Fingerprint, &rec.Status, &header, &body, &rec.CreatedAt); err != nil {
+		return Record{}, fmt.Errorf("load idempotency key: %w", err)
+	}
+	if header != "" {
+		if err := json.Unmarshal([]byte(header), &rec.Header); err != nil {
+			return Record{}, fmt.Errorf("decode response headers: %w", err)
+		}
+	}
+	if body != "" {
+		rec.Body = []byte(body)
+	}
+	return rec, nil
+}
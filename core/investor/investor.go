@@ -0,0 +1,225 @@
+// Package investor keeps the registry of investors: who they are, whether their identity has been
+// verified (KYC) and how much they may invest.
+package investor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"loan-service/core/money"
+)
+
+var (
+	// ErrInvestorNotFound is returned when no investor is registered under an ID.
+	ErrInvestorNotFound = errors.New("investor not found")
+
+	// ErrInvestorExists is returned when registering an ID that is already taken.
+	ErrInvestorExists = errors.New("investor is already registered")
+
+	// ErrNotVerified is matched by every KYCError.
+	ErrNotVerified = errors.New("investor is not KYC verified")
+
+	// ErrLimitExceeded is matched by every LimitError and by investments in a currency the limits do not cover.
+	ErrLimitExceeded = errors.New("investment limit exceeded")
+)
+
+// KYCStatus tells how far an investor's identity check has got.
+type KYCStatus string
+
+const (
+	// Pending investors have registered but have not been checked yet. They cannot invest.
+	Pending KYCStatus = "pending"
+
+	// Verified investors passed the identity check and may invest within their limits.
+	Verified KYCStatus = "verified"
+
+	// Rejected investors failed the identity check. They cannot invest.
+	Rejected KYCStatus = "rejected"
+)
+
+// valid reports whether s is one of the known statuses.
+func (s KYCStatus) valid() bool {
+	return s == Pending || s == Verified || s == Rejected
+}
+
+// Limits bound how much an investor may invest. A nil limit does not apply.
+// All limits are in the same currency; investments in other currencies are refused while any limit is set.
+type Limits struct {
+	ExposureCap *money.Money `json:"exposure_cap,omitempty"` // Most the investor may have committed across open loans
+	MinTicket   *money.Money `json:"min_ticket,omitempty"`   // Least the investor may commit to a single loan
+	MaxTicket   *money.Money `json:"max_ticket,omitempty"`   // Most the investor may commit to a single loan
+}
+
+// Validate checks that the limits are positive, share a currency and are consistent with each other.
+func (l Limits) Validate() error {
+	var currency money.Currency
+	for _, limit := range []struct {
+		name  Limit
+		value *money.Money
+	}{{ExposureCap, l.ExposureCap}, {MinTicket, l.MinTicket}, {MaxTicket, l.MaxTicket}} {
+		if limit.value == nil {
+			continue
+		}
+		if !limit.value.IsPositive() {
+			return fmt.Errorf("%s must be positive", limit.name)
+		}
+		if currency != "" && limit.value.Currency() != currency {
+			return errors.New("limits must all be in the same currency")
+		}
+		currency = limit.value.Currency()
+	}
+	if l.MinTicket != nil && l.MaxTicket != nil && l.MinTicket.Cmp(*l.MaxTicket) > 0 {
+		return errors.New("min_ticket must not be more than max_ticket")
+	}
+	if l.MaxTicket != nil && l.ExposureCap != nil && l.MaxTicket.Cmp(*l.ExposureCap) > 0 {
+		return errors.New("max_ticket must not be more than exposure_cap")
+	}
+	return nil
+}
+
+// currency returns the currency of the limits, or "" when none is set.
+func (l Limits) currency() money.Currency {
+	for _, limit := range []*money.Money{l.ExposureCap, l.MinTicket, l.MaxTicket} {
+		if limit != nil {
+			return limit.Currency()
+		}
+	}
+	return ""
+}
+
+// Investor is a registered investor. Their ID is the investor ID used in loans and in bearer tokens.
+type Investor struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email,omitempty"`
+	KYCStatus     KYCStatus  `json:"kyc_status"`
+	KYCReviewedAt *time.Time `json:"kyc_reviewed_at,omitempty"` // When the status was last set by staff
+	Limits        Limits     `json:"limits"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// KYCError is returned when an investor whose identity has not been verified tries to invest.
+type KYCError struct {
+	InvestorID string
+	Status     KYCStatus
+}
+
+// Error implements the error interface.
+func (e *KYCError) Error() string {
+	return fmt.Sprintf("investor %s is not KYC verified (status %s)", e.InvestorID, e.Status)
+}
+
+// Is reports whether target is ErrNotVerified.
+func (e *KYCError) Is(target error) bool {
+	return target == ErrNotVerified
+}
+
+// Limit names one of the limits in Limits.
+type Limit string
+
+const (
+	MinTicket   Limit = "min_ticket"
+	MaxTicket   Limit = "max_ticket"
+	ExposureCap Limit = "exposure_cap"
+)
+
+// LimitError is returned when an investment would break one of the investor's limits.
+type LimitError struct {
+	InvestorID string
+	Limit      Limit
+	Bound      money.Money // The limit that would be broken
+	Amount     money.Money // The ticket or exposure the investment would lead to
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case MinTicket:
+		return fmt.Sprintf("investor %s must invest at least %s in a loan, not %s", e.InvestorID, e.Bound, e.Amount)
+	case MaxTicket:
+		return fmt.Sprintf("investor %s may invest at most %s in a loan, not %s", e.InvestorID, e.Bound, e.Amount)
+	default:
+		return fmt.Sprintf("investment would raise the exposure of investor %s to %s, over their cap of %s", e.InvestorID, e.Amount, e.Bound)
+	}
+}
+
+// Is reports whether target is ErrLimitExceeded.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// Repository stores investors.
+//
+// Create fails with ErrInvestorExists if the ID is taken; Get and Update fail with ErrInvestorNotFound
+// for unknown IDs. List is ordered by registration time.
+type Repository interface {
+	Create(inv Investor) error
+	Get(id string) (Investor, error)
+	List() ([]Investor, error)
+	Update(inv Investor) error
+}
+
+// InMemoryRepository is a Repository for tests and development.
+type InMemoryRepository struct {
+	mu        sync.Mutex
+	investors map[string]Investor
+}
+
+// NewInMemoryRepository creates an empty repository.
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{investors: make(map[string]Investor)}
+}
+
+// Create stores a new investor.
+func (r *InMemoryRepository) Create(inv Investor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.investors[inv.ID]; ok {
+		return ErrInvestorExists
+	}
+	r.investors[inv.ID] = inv
+	return nil
+}
+
+// Get returns an investor by ID.
+func (r *InMemoryRepository) Get(id string) (Investor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inv, ok := r.investors[id]
+	if !ok {
+		return Investor{}, ErrInvestorNotFound
+	}
+	return inv, nil
+}
+
+// List returns every investor.
+func (r *InMemoryRepository) List() ([]Investor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Investor, 0, len(r.investors))
+	for _, inv := range r.investors {
+		list = append(list, inv)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// Update replaces a stored investor.
+func (r *InMemoryRepository) Update(inv Investor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.investors[inv.ID]; !ok {
+		return ErrInvestorNotFound
+	}
+	r.investors[inv.ID] = inv
+	return nil
+}
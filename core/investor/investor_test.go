@@ -0,0 +1,124 @@
+package investor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+	"loan-service/database"
+)
+
+func idr(major int64) *money.Money {
+	m := money.FromMajor(major, money.IDR)
+	return &m
+}
+
+func TestLimits_Validate(t *testing.T) {
+	cases := []struct {
+		name   string
+		limits Limits
+		err    string
+	}{
+		{name: "no limits", limits: Limits{}},
+		{name: "all limits", limits: Limits{ExposureCap: idr(1000), MinTicket: idr(10), MaxTicket: idr(500)}},
+		{name: "zero cap", limits: Limits{ExposureCap: idr(0)}, err: "exposure_cap must be positive"},
+		{name: "mixed currencies", limits: Limits{MinTicket: idr(10), MaxTicket: func() *money.Money {
+			m := money.FromMajor(10, money.USD)
+			return &m
+		}()}, err: "same currency"},
+		{name: "min over max", limits: Limits{MinTicket: idr(600), MaxTicket: idr(500)}, err: "min_ticket must not be more than max_ticket"},
+		{name: "max over cap", limits: Limits{MaxTicket: idr(2000), ExposureCap: idr(1000)}, err: "max_ticket must not be more than exposure_cap"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.limits.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var err error = &KYCError{InvestorID: "INV001", Status: Pending}
+	assert.ErrorIs(t, err, ErrNotVerified)
+	assert.EqualError(t, err, "investor INV001 is not KYC verified (status pending)")
+
+	err = &LimitError{InvestorID: "INV001", Limit: ExposureCap, Bound: *idr(1000), Amount: *idr(1200)}
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.EqualError(t, err, "investment would raise the exposure of investor INV001 to IDR 1200.00, over their cap of IDR 1000.00")
+}
+
+func TestInMemoryRepository(t *testing.T) {
+	testRepository(t, NewInMemoryRepository())
+}
+
+func TestSQLRepository(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "investors.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testRepository(t, NewSQLRepository(db))
+}
+
+func testRepository(t *testing.T, repo Repository) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	first := Investor{ID: "INV001", Name: "Ani", Email: "ani@example.com", KYCStatus: Pending, CreatedAt: at, UpdatedAt: at}
+	second := Investor{ID: "INV002", Name: "Budi", KYCStatus: Pending, CreatedAt: at.Add(time.Minute), UpdatedAt: at.Add(time.Minute)}
+
+	t.Run("Create and Get", func(t *testing.T) {
+		require.NoError(t, repo.Create(second))
+		require.NoError(t, repo.Create(first))
+
+		got, err := repo.Get("INV001")
+		require.NoError(t, err)
+		assert.Equal(t, first, got)
+	})
+
+	t.Run("Create rejects a taken ID", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Create(Investor{ID: "INV001", Name: "Other", KYCStatus: Pending, CreatedAt: at, UpdatedAt: at}), ErrInvestorExists)
+	})
+
+	t.Run("Get unknown investor", func(t *testing.T) {
+		_, err := repo.Get("missing")
+		assert.ErrorIs(t, err, ErrInvestorNotFound)
+	})
+
+	t.Run("List in registration order", func(t *testing.T) {
+		list, err := repo.List()
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, "INV001", list[0].ID)
+		assert.Equal(t, "INV002", list[1].ID)
+	})
+
+	t.Run("Update round-trips KYC status and limits", func(t *testing.T) {
+		reviewed := at.Add(time.Hour)
+		updated := first
+		updated.KYCStatus = Verified
+		updated.KYCReviewedAt = &reviewed
+		updated.Limits = Limits{ExposureCap: idr(10000), MinTicket: idr(100), MaxTicket: idr(2500)}
+		updated.UpdatedAt = reviewed
+		require.NoError(t, repo.Update(updated))
+
+		got, err := repo.Get("INV001")
+		require.NoError(t, err)
+		assert.Equal(t, updated, got)
+
+		updated.Limits = Limits{MaxTicket: idr(500)}
+		require.NoError(t, repo.Update(updated))
+		got, err = repo.Get("INV001")
+		require.NoError(t, err)
+		assert.Equal(t, Limits{MaxTicket: idr(500)}, got.Limits)
+	})
+
+	t.Run("Update unknown investor", func(t *testing.T) {
+		assert.ErrorIs(t, repo.Update(Investor{ID: "missing"}), ErrInvestorNotFound)
+	})
+}
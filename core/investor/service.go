@@ -0,0 +1,142 @@
+package investor
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sync"
+	"time"
+
+	"loan-service/core/money"
+)
+
+// Service registers investors, records their KYC status and limits, and vets their investments.
+type Service struct {
+	repo Repository
+	now  func() time.Time
+	mu   sync.Mutex // Serialises read-modify-write updates of investors
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithClock replaces the service's source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// NewService creates a service on top of repo.
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds a new investor with a pending KYC status and no limits.
+func (s *Service) Register(id, name, email string) (Investor, error) {
+	if id == "" {
+		return Investor{}, errors.New("investor id is required")
+	}
+	if name == "" {
+		return Investor{}, errors.New("investor name is required")
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return Investor{}, fmt.Errorf("invalid email address: %w", err)
+		}
+	}
+
+	now := s.timestamp()
+	inv := Investor{ID: id, Name: name, Email: email, KYCStatus: Pending, CreatedAt: now, UpdatedAt: now}
+	if err := s.repo.Create(inv); err != nil {
+		return Investor{}, err
+	}
+	return inv, nil
+}
+
+// Get returns an investor by ID.
+func (s *Service) Get(id string) (Investor, error) {
+	return s.repo.Get(id)
+}
+
+// List returns every investor, oldest registration first.
+func (s *Service) List() ([]Investor, error) {
+	return s.repo.List()
+}
+
+// SetKYCStatus records the outcome of an investor's identity check.
+func (s *Service) SetKYCStatus(id string, status KYCStatus) (Investor, error) {
+	if !status.valid() {
+		return Investor{}, fmt.Errorf("unknown KYC status %q", status)
+	}
+	return s.update(id, func(inv *Investor) {
+		now := s.timestamp()
+		inv.KYCStatus = status
+		inv.KYCReviewedAt = &now
+	})
+}
+
+// SetLimits replaces an investor's investment limits.
+func (s *Service) SetLimits(id string, limits Limits) (Investor, error) {
+	if err := limits.Validate(); err != nil {
+		return Investor{}, err
+	}
+	return s.update(id, func(inv *Investor) {
+		inv.Limits = limits
+	})
+}
+
+// CheckInvestment vets an investment before it is accepted. ticket is what the investor would then have
+// committed to the loan and exposure what they would have committed across all open loans.
+//
+// It fails with ErrInvestorNotFound for unknown investors, a *KYCError unless they are verified, and a
+// *LimitError if either amount breaks their limits.
+func (s *Service) CheckInvestment(investorID string, ticket, exposure money.Money) error {
+	inv, err := s.repo.Get(investorID)
+	if err != nil {
+		return err
+	}
+	if inv.KYCStatus != Verified {
+		return &KYCError{InvestorID: inv.ID, Status: inv.KYCStatus}
+	}
+
+	limits := inv.Limits
+	if currency := limits.currency(); currency != "" && currency != ticket.Currency() {
+		return fmt.Errorf("%w: investor %s may only invest in %s", ErrLimitExceeded, inv.ID, currency)
+	}
+	switch {
+	case limits.MinTicket != nil && ticket.Cmp(*limits.MinTicket) < 0:
+		return &LimitError{InvestorID: inv.ID, Limit: MinTicket, Bound: *limits.MinTicket, Amount: ticket}
+	case limits.MaxTicket != nil && ticket.Cmp(*limits.MaxTicket) > 0:
+		return &LimitError{InvestorID: inv.ID, Limit: MaxTicket, Bound: *limits.MaxTicket, Amount: ticket}
+	case limits.ExposureCap != nil && exposure.Cmp(*limits.ExposureCap) > 0:
+		return &LimitError{InvestorID: inv.ID, Limit: ExposureCap, Bound: *limits.ExposureCap, Amount: exposure}
+	}
+	return nil
+}
+
+// update applies change to a stored investor and saves it.
+func (s *Service) update(id string, change func(*Investor)) (Investor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, err := s.repo.Get(id)
+	if err != nil {
+		return Investor{}, err
+	}
+	change(&inv)
+	inv.UpdatedAt = s.timestamp()
+	if err := s.repo.Update(inv); err != nil {
+		return Investor{}, err
+	}
+	return inv, nil
+}
+
+// timestamp returns the current time as every supported database stores it.
+func (s *Service) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
+}
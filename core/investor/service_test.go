@@ -0,0 +1,106 @@
+package investor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+)
+
+func TestService_Register(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	svc := NewService(NewInMemoryRepository(), WithClock(func() time.Time { return now }))
+
+	inv, err := svc.Register("INV001", "Ani", "ani@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, Investor{ID: "INV001", Name: "Ani", Email: "ani@example.com", KYCStatus: Pending, CreatedAt: now, UpdatedAt: now}, inv)
+
+	_, err = svc.Register("INV001", "Ani", "")
+	assert.ErrorIs(t, err, ErrInvestorExists)
+	_, err = svc.Register("", "Ani", "")
+	assert.ErrorContains(t, err, "id is required")
+	_, err = svc.Register("INV002", "", "")
+	assert.ErrorContains(t, err, "name is required")
+	_, err = svc.Register("INV002", "Budi", "not-an-address")
+	assert.ErrorContains(t, err, "invalid email address")
+}
+
+func TestService_SetKYCStatusAndLimits(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	clock := now
+	svc := NewService(NewInMemoryRepository(), WithClock(func() time.Time { return clock }))
+	_, err := svc.Register("INV001", "Ani", "")
+	require.NoError(t, err)
+
+	clock = now.Add(time.Hour)
+	inv, err := svc.SetKYCStatus("INV001", Verified)
+	require.NoError(t, err)
+	assert.Equal(t, Verified, inv.KYCStatus)
+	assert.Equal(t, clock, *inv.KYCReviewedAt)
+	assert.Equal(t, clock, inv.UpdatedAt)
+
+	_, err = svc.SetKYCStatus("INV001", "approved")
+	assert.ErrorContains(t, err, `unknown KYC status "approved"`)
+	_, err = svc.SetKYCStatus("missing", Verified)
+	assert.ErrorIs(t, err, ErrInvestorNotFound)
+
+	limits := Limits{MinTicket: idr(100), MaxTicket: idr(1000)}
+	inv, err = svc.SetLimits("INV001", limits)
+	require.NoError(t, err)
+	assert.Equal(t, limits, inv.Limits)
+	_, err = svc.SetLimits("INV001", Limits{MinTicket: idr(0)})
+	assert.ErrorContains(t, err, "must be positive")
+
+	stored, err := svc.Get("INV001")
+	require.NoError(t, err)
+	assert.Equal(t, inv, stored)
+}
+
+func TestService_CheckInvestment(t *testing.T) {
+	svc := NewService(NewInMemoryRepository())
+	for _, id := range []string{"pending", "rejected", "verified", "limited"} {
+		_, err := svc.Register(id, id, "")
+		require.NoError(t, err)
+	}
+	_, _ = svc.SetKYCStatus("rejected", Rejected)
+	_, _ = svc.SetKYCStatus("verified", Verified)
+	_, _ = svc.SetKYCStatus("limited", Verified)
+	_, err := svc.SetLimits("limited", Limits{ExposureCap: idr(5000), MinTicket: idr(100), MaxTicket: idr(2000)})
+	require.NoError(t, err)
+	usd := money.FromMajor(500, money.USD)
+
+	cases := []struct {
+		name             string
+		investor         string
+		ticket, exposure money.Money
+		is               error
+		err              string
+	}{
+		{name: "verified without limits", investor: "verified", ticket: *idr(1000000), exposure: *idr(9000000)},
+		{name: "within limits", investor: "limited", ticket: *idr(2000), exposure: *idr(5000)},
+		{name: "unknown", investor: "missing", ticket: *idr(100), exposure: *idr(100), is: ErrInvestorNotFound},
+		{name: "pending KYC", investor: "pending", ticket: *idr(100), exposure: *idr(100), is: ErrNotVerified, err: "status pending"},
+		{name: "rejected KYC", investor: "rejected", ticket: *idr(100), exposure: *idr(100), is: ErrNotVerified, err: "status rejected"},
+		{name: "below min ticket", investor: "limited", ticket: *idr(99), exposure: *idr(99), is: ErrLimitExceeded, err: "at least IDR 100.00"},
+		{name: "above max ticket", investor: "limited", ticket: *idr(2001), exposure: *idr(2001), is: ErrLimitExceeded, err: "at most IDR 2000.00"},
+		{name: "over exposure cap", investor: "limited", ticket: *idr(1000), exposure: *idr(5001), is: ErrLimitExceeded, err: "over their cap of IDR 5000.00"},
+		{name: "other currency", investor: "limited", ticket: usd, exposure: usd, is: ErrLimitExceeded, err: "may only invest in IDR"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := svc.CheckInvestment(tc.investor, tc.ticket, tc.exposure)
+			if tc.is == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.is)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	var limitErr *LimitError
+	require.ErrorAs(t, svc.CheckInvestment("limited", *idr(3000), *idr(3000)), &limitErr)
+	assert.Equal(t, MaxTicket, limitErr.Limit)
+}
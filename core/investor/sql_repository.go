@@ -0,0 +1,132 @@
+package investor
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"loan-service/core/money"
+	"loan-service/database"
+)
+
+// SQLRepository stores investors in the investors table.
+type SQLRepository struct {
+	db *database.DB
+}
+
+// NewSQLRepository creates a repository on top of an already migrated database.
+func NewSQLRepository(db *database.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// investorSelect lists the columns read by scanInvestor, in order.
+const investorSelect = `id, name, email, kyc_status, kyc_reviewed_at, limit_currency,
+	exposure_cap_minor, min_ticket_minor, max_ticket_minor, created_at, updated_at`
+
+// Create stores a new investor.
+func (r *SQLRepository) Create(inv Investor) error {
+	exposureCap, minTicket, maxTicket := minorUnits(inv.Limits.ExposureCap), minorUnits(inv.Limits.MinTicket), minorUnits(inv.Limits.MaxTicket)
+	res, err := r.db.Exec(r.db.Dialect.Rebind(`INSERT INTO investors (`+investorSelect+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+		inv.ID, inv.Name, inv.Email, string(inv.KYCStatus), utc(inv.KYCReviewedAt), string(inv.Limits.currency()),
+		exposureCap, minTicket, maxTicket, inv.CreatedAt.UTC(), inv.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("insert investor: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrInvestorExists
+	}
+	return nil
+}
+
+// Get returns an investor by ID.
+func (r *SQLRepository) Get(id string) (Investor, error) {
+	list, err := r.query(`WHERE id = ?`, id)
+	if err != nil {
+		return Investor{}, err
+	}
+	if len(list) == 0 {
+		return Investor{}, ErrInvestorNotFound
+	}
+	return list[0], nil
+}
+
+// List returns every investor.
+func (r *SQLRepository) List() ([]Investor, error) {
+	return r.query(`ORDER BY created_at, id`)
+}
+
+// Update replaces a stored investor.
+func (r *SQLRepository) Update(inv Investor) error {
+	exposureCap, minTicket, maxTicket := minorUnits(inv.Limits.ExposureCap), minorUnits(inv.Limits.MinTicket), minorUnits(inv.Limits.MaxTicket)
+	res, err := r.db.Exec(r.db.Dialect.Rebind(`UPDATE investors SET name = ?, email = ?, kyc_status = ?, kyc_reviewed_at = ?,
+		limit_currency = ?, exposure_cap_minor = ?, min_ticket_minor = ?, max_ticket_minor = ?, updated_at = ? WHERE id = ?`),
+		inv.Name, inv.Email, string(inv.KYCStatus), utc(inv.KYCReviewedAt),
+		string(inv.Limits.currency()), exposureCap, minTicket, maxTicket, inv.UpdatedAt.UTC(), inv.ID)
+	if err != nil {
+		return fmt.Errorf("update investor: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrInvestorNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) query(clause string, args ...any) ([]Investor, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT `+investorSelect+` FROM investors `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query investors: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	list := []Investor{}
+	for rows.Next() {
+		var (
+			inv                               Investor
+			status, currency                  string
+			reviewedAt                        sql.NullTime
+			exposureCap, minTicket, maxTicket sql.NullInt64
+		)
+		if err := rows.Scan(&inv.ID, &inv.Name, &inv.Email, &status, &reviewedAt, &currency,
+			&exposureCap, &minTicket, &maxTicket, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan investor: %w", err)
+		}
+		inv.KYCStatus = KYCStatus(status)
+		if reviewedAt.Valid {
+			inv.KYCReviewedAt = &reviewedAt.Time
+		}
+		cur := money.Currency(currency)
+		inv.Limits = Limits{ExposureCap: amount(exposureCap, cur), MinTicket: amount(minTicket, cur), MaxTicket: amount(maxTicket, cur)}
+		list = append(list, inv)
+	}
+	return list, rows.Err()
+}
+
+// minorUnits returns the column value of an optional amount.
+func minorUnits(m *money.Money) any {
+	if m == nil {
+		return nil
+	}
+	return m.MinorUnits()
+}
+
+// amount turns an optional column value back into an amount.
+func amount(minor sql.NullInt64, currency money.Currency) *money.Money {
+	if !minor.Valid {
+		return nil
+	}
+	m := money.New(minor.Int64, currency)
+	return &m
+}
+
+// utc returns the column value of an optional time.
+func utc(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
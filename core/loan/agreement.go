@@ -0,0 +1,63 @@
+package loan
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// AgreementGenerator writes the agreement letters of loans.
+type AgreementGenerator interface {
+	// Generate renders and stores the agreement letter of loan as it currently stands and returns the link to it.
+	Generate(loan *Loan) (string, error)
+
+	// Discard deletes a letter returned by Generate that no loan links to.
+	Discard(link string) error
+}
+
+// LetterLink returns the link to download a document through GET /documents/:id/content under baseURL.
+// Generators link agreement letters this way, so LetterDocument can tell which document a loan links to.
+func LetterLink(baseURL, documentID string) string {
+	return baseURL + "/documents/" + documentID + "/content"
+}
+
+// LetterDocument returns the ID of the document a LetterLink points to, whatever its base URL.
+// It returns false for anything else, such as the bare document ID of a letter given at disbursement.
+func LetterDocument(link string) (string, bool) {
+	i := strings.LastIndex(link, "/documents/")
+	if i < 0 {
+		return "", false
+	}
+	id, ok := strings.CutSuffix(link[i+len("/documents/"):], "/content")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
+
+// writeAgreement generates the agreement letter of loan, if the service has a generator, and links it
+// from the loan. It is called before notifications are queued, so they carry the link. If the change
+// is not stored in the end, the caller drops the letter again with discardAgreement.
+func (s *LoanService) writeAgreement(loan *Loan) error {
+	if s.agreements == nil {
+		return nil
+	}
+	link, err := s.agreements.Generate(loan)
+	if err != nil {
+		return fmt.Errorf("generate agreement letter: %w", err)
+	}
+	loan.AgreementLetterURL = link
+	return nil
+}
+
+// discardAgreement deletes a letter written by writeAgreement for a change that failed, so failed and
+// retried requests leave no letters behind, or a letter superseded by a stored one. Either way the
+// change is already decided, so errors are only logged.
+func (s *LoanService) discardAgreement(link string) {
+	if s.agreements == nil || link == "" {
+		return
+	}
+	if err := s.agreements.Discard(link); err != nil {
+		log.Printf("[AGREEMENT] %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package loan
+
+import "fmt"
+
+// DocumentStore tells the service which uploaded documents exist.
+type DocumentStore interface {
+	// Exists reports whether a document is stored under id.
+	Exists(id string) (bool, error)
+}
+
+// documentRef is a document ID given in a request field.
+type documentRef struct {
+	field string // Name of the request field, for error messages
+	id    string
+}
+
+// checkDocuments makes sure, when the service has a document store, that every reference with an ID
+// refers to an uploaded document.
+func (s *LoanService) checkDocuments(refs ...documentRef) error {
+	if s.documents == nil {
+		return nil
+	}
+	for _, ref := range refs {
+		if ref.id == "" {
+			continue
+		}
+		ok, err := s.documents.Exists(ref.id)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", ref.field, err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s refers to %q", ErrUnknownDocument, ref.field, ref.id)
+		}
+	}
+	return nil
+}
+
+// LoansWithDocument returns the loans referring to a document as their photo proof, signed agreement
+// or agreement letter, oldest first.
+func (s *LoanService) LoansWithDocument(documentID string) ([]*Loan, error) {
+	return s.searchAll(LoanQuery{DocumentID: documentID})
+}
@@ -0,0 +1,53 @@
+package loan
+
+import (
+	"fmt"
+
+	"loan-service/core/money"
+)
+
+// BorrowerRegistry vets borrowers before the service accepts their loan applications.
+type BorrowerRegistry interface {
+	// CheckLoan returns an error if the borrower may not apply for the loan. outstanding is the principal
+	// they would then owe across their active loans, pending the number of their other loans not yet disbursed.
+	CheckLoan(borrowerID string, principal, outstanding money.Money, pending int) error
+}
+
+// activeStates are the states of loans a borrower has applied for and not yet paid back.
+var activeStates = []LoanState{Proposed, Approved, Invested, Disbursed}
+
+// ActiveLoans returns the borrower's loans that are neither repaid nor closed, oldest first.
+func (s *LoanService) ActiveLoans(borrowerID string) ([]*Loan, error) {
+	return s.searchAll(LoanQuery{BorrowerID: borrowerID, States: activeStates})
+}
+
+// checkBorrower asks the borrower registry, if there is one, whether the borrower may apply for a loan of principal.
+// The registry's error is returned as is. The caller holds the borrower's lock.
+func (s *LoanService) checkBorrower(borrowerID string, principal money.Money) error {
+	active, err := s.ActiveLoans(borrowerID)
+	if err != nil {
+		return err
+	}
+	outstanding, pending := principal, 0
+	for _, loan := range active {
+		if loan.State != Disbursed {
+			pending++
+		}
+		if loan.PrincipalAmount.Currency() != principal.Currency() {
+			continue
+		}
+		owed := loan.PrincipalAmount
+		if loan.Outstanding != nil {
+			owed = loan.Outstanding.Principal
+		}
+		if outstanding, err = outstanding.CheckedAdd(owed); err != nil {
+			return fmt.Errorf("outstanding principal of borrower %s: %w", borrowerID, err)
+		}
+	}
+	return s.borrowers.CheckLoan(borrowerID, principal, outstanding, pending)
+}
+
+// borrowerLock returns the lock key serialising loan applications of a borrower.
+func borrowerLock(borrowerID string) string {
+	return "borrower:" + borrowerID
+}
@@ -0,0 +1,48 @@
+package loan
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLoanNotFound is returned by repositories when no loan has the requested ID.
+var ErrLoanNotFound = errors.New("loan not found")
+
+// ErrInvestmentNotFound is returned when an investor has no committed investment in a loan.
+var ErrInvestmentNotFound = errors.New("investment not found")
+
+// ErrNotOpenForInvestment is returned when money is offered to a loan that is not Approved or Invested.
+var ErrNotOpenForInvestment = errors.New("loan must be in approved or invested state to accept investments")
+
+// ErrOverInvestment is returned when an investment would take the total invested past the principal.
+var ErrOverInvestment = errors.New("investment exceeds loan principal")
+
+// ErrInvalidLoan is matched by every error about terms a loan cannot be created with, such as a principal that is not positive.
+var ErrInvalidLoan = errors.New("invalid loan")
+
+// ErrUnknownDocument is returned when a request refers to a document that was never uploaded.
+var ErrUnknownDocument = errors.New("unknown document")
+
+// ErrVersionConflict is matched by every ConflictError, so callers can use errors.Is without caring about the details.
+var ErrVersionConflict = errors.New("loan version conflict")
+
+// ConflictError is returned when a write is based on a stale version of a loan,
+// i.e. somebody else changed the loan between it being read and written back.
+type ConflictError struct {
+	LoanID   string // ID of the loan that was being written
+	Expected int64  // Version the writer based its change on
+	Actual   int64  // Version currently stored (0 if unknown)
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	if e.Actual == 0 {
+		return fmt.Sprintf("loan %s was modified concurrently: version %d is stale", e.LoanID, e.Expected)
+	}
+	return fmt.Sprintf("loan %s was modified concurrently: expected version %d, current version is %d", e.LoanID, e.Expected, e.Actual)
+}
+
+// Is reports whether target is ErrVersionConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
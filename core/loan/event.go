@@ -0,0 +1,66 @@
+package loan
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"loan-service/core/outbox"
+)
+
+// EventMessage is the kind of outbox message that carries an Event to the EventPublisher.
+const EventMessage = "loan.event"
+
+// EventType names something that happened to a loan.
+type EventType string
+
+const (
+	// ApprovedEvent is published when a loan is approved.
+	ApprovedEvent EventType = "loan.approved"
+
+	// InvestedEvent is published for every investment accepted into a loan.
+	InvestedEvent EventType = "loan.invested"
+
+	// FundedEvent is published when a loan becomes fully invested.
+	FundedEvent EventType = "loan.funded"
+
+	// DisbursedEvent is published when a loan is disbursed to the borrower.
+	DisbursedEvent EventType = "loan.disbursed"
+)
+
+// EventTypes lists every event type the service publishes.
+var EventTypes = []EventType{ApprovedEvent, InvestedEvent, FundedEvent, DisbursedEvent}
+
+// Event is a change to a loan, published for systems outside the service (e.g. webhook subscribers).
+type Event struct {
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	Loan       Loan      `json:"loan"`                 // The loan as stored by the change
+	Investment *Investor `json:"investment,omitempty"` // The investment behind a loan.invested event
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// EventPublisher receives the service's events once the change behind them is stored.
+type EventPublisher interface {
+	Publish(event Event) error
+}
+
+// errNoPublisher is returned when an event message is delivered but the service has no publisher.
+var errNoPublisher = errors.New("no event publisher configured")
+
+// publish builds the outbox messages carrying events of the given types about loan.
+// Nothing is queued unless the service has a publisher.
+func (s *LoanService) publish(loan *Loan, investment *Investor, types ...EventType) ([]outbox.Message, error) {
+	if s.events == nil {
+		return nil, nil
+	}
+	events := make([]any, 0, len(types))
+	for _, t := range types {
+		event := Event{ID: uuid.NewString(), Type: t, Loan: stored(loan), OccurredAt: s.now()}
+		if t == InvestedEvent {
+			event.Investment = investment
+		}
+		events = append(events, event)
+	}
+	return s.notify(EventMessage, events...)
+}
@@ -0,0 +1,44 @@
+package loan
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ExpiryScheduler periodically expires approved loans whose funding deadline has passed.
+type ExpiryScheduler struct {
+	service  *LoanService
+	interval time.Duration
+}
+
+// NewExpiryScheduler creates a scheduler that checks for overdue loans every interval.
+func NewExpiryScheduler(service *LoanService, interval time.Duration) *ExpiryScheduler {
+	return &ExpiryScheduler{service: service, interval: interval}
+}
+
+// Run checks for overdue loans once immediately and then on every tick until ctx is cancelled.
+// Failures are logged and retried on the next tick.
+func (e *ExpiryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.runOnce()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *ExpiryScheduler) runOnce() {
+	expired, err := e.service.ExpireOverdueLoans()
+	for _, loan := range expired {
+		log.Printf("[EXPIRY] Loan %s expired unfunded (deadline %s)", loan.ID, loan.FundingDeadline.Format(time.RFC3339))
+	}
+	if err != nil {
+		log.Printf("[EXPIRY] %v", err)
+	}
+}
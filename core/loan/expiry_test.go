@@ -0,0 +1,39 @@
+package loan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryScheduler_Run(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithClock(clock.Now))
+
+	ln, _ := svc.CreateLoan("B410", idr(1000), 10, 8)
+	_, err := svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP410", ApprovalDate: clock.Now()},
+		WithFundingDeadline(clock.Now().Add(time.Hour)))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewExpiryScheduler(svc, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	clock.Advance(time.Hour)
+	assert.Eventually(t, func() bool {
+		stored, _ := svc.GetLoan(ln.ID)
+		return stored.State == Expired
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after cancellation")
+	}
+}
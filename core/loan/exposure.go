@@ -0,0 +1,84 @@
+package loan
+
+import (
+	"fmt"
+
+	"loan-service/core/money"
+)
+
+// InvestorRegistry vets investors before the service accepts their money.
+type InvestorRegistry interface {
+	// CheckInvestment returns an error if the investor may not invest. ticket is what they would then have
+	// committed to the loan, exposure what they would have committed across all open loans.
+	CheckInvestment(investorID string, ticket, exposure money.Money) error
+}
+
+// openStates are the states in which investors' money is tied up in a loan.
+var openStates = []LoanState{Approved, Invested, Disbursed}
+
+// checkInvestor asks the investor registry whether investor may invest in loan.
+// The registry's error is returned as is. The caller holds the investor's lock, so that investments
+// in other loans cannot change their exposure before this one is stored.
+func (s *LoanService) checkInvestor(loan *Loan, investor Investor) error {
+	ticket := committedBy(loan, investor.ID, investor.Amount.Currency()).Add(investor.Amount)
+	exposure, err := s.exposure(investor.ID, investor.Amount.Currency(), loan.ID)
+	if err != nil {
+		return err
+	}
+	if exposure, err = exposure.CheckedAdd(ticket); err != nil {
+		return fmt.Errorf("exposure of investor %s: %w", investor.ID, err)
+	}
+	return s.investors.CheckInvestment(investor.ID, ticket, exposure)
+}
+
+// exposure returns how much the investor has committed, in currency, to open loans other than skipLoanID.
+func (s *LoanService) exposure(investorID string, currency money.Currency, skipLoanID string) (money.Money, error) {
+	loans, err := s.searchAll(LoanQuery{InvestorID: investorID, States: openStates})
+	if err != nil {
+		return money.Money{}, err
+	}
+	total := money.Zero(currency)
+	for _, loan := range loans {
+		if loan.ID == skipLoanID || loan.PrincipalAmount.Currency() != currency {
+			continue
+		}
+		if total, err = total.CheckedAdd(committedBy(loan, investorID, currency)); err != nil {
+			return money.Money{}, fmt.Errorf("exposure of investor %s: %w", investorID, err)
+		}
+	}
+	return total, nil
+}
+
+// investorLock returns the lock key serialising investments of an investor across loans.
+// It is always taken after the loan's lock, never before, so the two cannot deadlock.
+func investorLock(investorID string) string {
+	return "investor:" + investorID
+}
+
+// searchAll returns every loan matching query, going through all of its pages.
+func (s *LoanService) searchAll(query LoanQuery) ([]*Loan, error) {
+	var loans []*Loan
+	query.Limit = MaxPageSize
+	for {
+		page, err := s.repo.Search(query)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, page.Loans...)
+		if page.NextCursor == "" {
+			return loans, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// committedBy returns the sum of the investor's committed investments in loan.
+func committedBy(loan *Loan, investorID string, currency money.Currency) money.Money {
+	total := money.Zero(currency)
+	for _, inv := range loan.Investors {
+		if inv.ID == investorID && inv.isCommitted() {
+			total = total.Add(inv.Amount)
+		}
+	}
+	return total
+}
@@ -0,0 +1,40 @@
+package loan
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	t.Run("Serialises the same key", func(t *testing.T) {
+		var (
+			locks   keyedMutex
+			wg      sync.WaitGroup
+			counter int
+		)
+		for i := 0; i < 200; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				unlock := locks.Lock("loan-1")
+				defer unlock()
+				counter++
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 200, counter)
+	})
+
+	t.Run("Releases entries once unlocked", func(t *testing.T) {
+		var locks keyedMutex
+		unlockA := locks.Lock("a")
+		unlockB := locks.Lock("b")
+		assert.Len(t, locks.locks, 2)
+
+		unlockA()
+		unlockB()
+		assert.Empty(t, locks.locks)
+	})
+}
@@ -1,6 +1,10 @@
 package loan
 
-import "time"
+import (
+	"time"
+
+	"loan-service/core/money"
+)
 
 // LoanState represents the lifecycle state of a loan.
 type LoanState string
@@ -17,41 +21,164 @@ const (
 
 	// Disbursed is the state after the loan is handed over to the borrower.
 	Disbursed LoanState = "disbursed"
+
+	// Repaid is the terminal state once the borrower has paid back everything owed.
+	Repaid LoanState = "repaid"
+
+	// Rejected is the terminal state of a proposal that staff turned down.
+	Rejected LoanState = "rejected"
+
+	// Cancelled is the terminal state of a loan withdrawn by staff before it was fully funded.
+	Cancelled LoanState = "cancelled"
+
+	// Expired is the terminal state of an approved loan that was not fully funded in time.
+	Expired LoanState = "expired"
 )
 
+// AllStates lists every LoanState, in lifecycle order.
+var AllStates = []LoanState{Proposed, Approved, Invested, Disbursed, Repaid, Rejected, Cancelled, Expired}
+
+// IsClosed reports whether the loan ended without being disbursed.
+func (s LoanState) IsClosed() bool {
+	return s == Rejected || s == Cancelled || s == Expired
+}
+
 // Loan represents a loan given to a borrower, along with its current state and data.
 type Loan struct {
-	ID                 string        `json:"id"`                     // Unique identifier of the loan
-	BorrowerID         string        `json:"borrower_id"`            // Identifier of the borrower
-	PrincipalAmount    float64       `json:"principal_amount"`       // Total loan principal amount
-	Rate               float64       `json:"rate"`                   // Interest rate the borrower must pay (in %)
-	ROI                float64       `json:"roi"`                    // Return of investment for investors (in %)
-	AgreementLetterURL string        `json:"agreement_letter_link"`  // URL to agreement letter (if generated)
-	State              LoanState     `json:"state"`                  // Current lifecycle state of the loan
-	Approval           *Approval     `json:"approval,omitempty"`     // Approval information (if approved)
-	Disbursement       *Disbursement `json:"disbursement,omitempty"` // Disbursement information (if disbursed)
-	Investors          []Investor    `json:"investors"`              // List of investors
-	TotalInvested      float64       `json:"total_invested"`         // Total amount invested by all investors
-	CreatedAt          time.Time     `json:"created_at"`             // Timestamp when loan was created
-	UpdatedAt          time.Time     `json:"updated_at"`             // Timestamp when loan was last updated
+	ID                 string         `json:"id"`                         // Unique identifier of the loan
+	BorrowerID         string         `json:"borrower_id"`                // Identifier of the borrower
+	PrincipalAmount    money.Money    `json:"principal_amount"`           // Total loan principal amount; its currency is the loan currency
+	Rate               float64        `json:"rate"`                       // Interest rate the borrower must pay (in %)
+	ROI                float64        `json:"roi"`                        // Return of investment for investors (in %)
+	AgreementLetterURL string         `json:"agreement_letter_link"`      // Agreement letter (if generated; a document ID when documents are uploaded)
+	State              LoanState      `json:"state"`                      // Current lifecycle state of the loan
+	Approval           *Approval      `json:"approval,omitempty"`         // Approval information (if approved)
+	Disbursement       *Disbursement  `json:"disbursement,omitempty"`     // Disbursement information (if disbursed)
+	Closure            *Closure       `json:"closure,omitempty"`          // Why and when the loan was rejected, cancelled or expired
+	FundingDeadline    *time.Time     `json:"funding_deadline,omitempty"` // Approved loans not fully funded by then expire
+	Investors          []Investor     `json:"investors"`                  // List of investors
+	Terms              RepaymentTerms `json:"repayment_terms"`            // How the borrower repays the loan
+	Installments       []Installment  `json:"-"`                          // Repayment schedule, generated at disbursement
+	Outstanding        *Balance       `json:"outstanding,omitempty"`      // What the borrower still owes (set at disbursement)
+	Repayments         []Repayment    `json:"repayments,omitempty"`       // Repayments received from the borrower
+	TotalInvested      money.Money    `json:"total_invested"`             // Total amount invested by all investors
+	Version            int64          `json:"version"`                    // Incremented on every update, used for optimistic concurrency
+	CreatedAt          time.Time      `json:"created_at"`                 // Timestamp when loan was created
+	UpdatedAt          time.Time      `json:"updated_at"`                 // Timestamp when loan was last updated
+}
+
+// clone returns a deep copy of the loan so callers can mutate it without affecting stored data.
+func (l *Loan) clone() *Loan {
+	c := *l
+	if l.Approval != nil {
+		a := *l.Approval
+		c.Approval = &a
+	}
+	if l.Disbursement != nil {
+		d := *l.Disbursement
+		c.Disbursement = &d
+	}
+	if l.FundingDeadline != nil {
+		d := *l.FundingDeadline
+		c.FundingDeadline = &d
+	}
+	if l.Closure != nil {
+		cl := *l.Closure
+		c.Closure = &cl
+	}
+	if l.Investors != nil {
+		c.Investors = append([]Investor(nil), l.Investors...)
+	}
+	if l.Installments != nil {
+		c.Installments = append([]Installment(nil), l.Installments...)
+	}
+	if l.Outstanding != nil {
+		b := *l.Outstanding
+		c.Outstanding = &b
+	}
+	if l.Repayments != nil {
+		c.Repayments = append([]Repayment(nil), l.Repayments...)
+	}
+	return &c
+}
+
+// fundingOverdue reports whether the loan is still waiting for funding after its deadline.
+func (l *Loan) fundingOverdue(at time.Time) bool {
+	return l.State == Approved && l.FundingDeadline != nil && !l.FundingDeadline.After(at)
 }
 
 // Approval holds information regarding the loan approval by a field validator.
 type Approval struct {
-	PhotoProofURL string    `json:"photo_proof_url"`    // URL of photo proof taken by field validator
+	PhotoProofURL string    `json:"photo_proof_url"`    // Photo proof taken by field validator (a document ID when documents are uploaded)
 	ValidatorID   string    `json:"field_validator_id"` // Employee ID of the field validator
 	ApprovalDate  time.Time `json:"approval_date"`      // Date of approval
 }
 
 // Disbursement contains details about loan disbursement to the borrower.
 type Disbursement struct {
-	AgreementFile    string    `json:"agreement_letter_file"` // File or image of signed agreement letter
+	AgreementFile    string    `json:"agreement_letter_file"` // Signed agreement letter (a document ID when documents are uploaded)
 	FieldOfficerID   string    `json:"field_officer_id"`      // Employee ID of field officer
 	DisbursementDate time.Time `json:"disbursement_date"`     // Date of disbursement
 }
 
+// Closure records why a loan ended in Rejected, Cancelled or Expired state.
+type Closure struct {
+	Reason   string    `json:"reason"`             // Why the loan was closed
+	StaffID  string    `json:"staff_id,omitempty"` // Employee who closed the loan; empty when it expired on its own
+	ClosedAt time.Time `json:"closed_at"`          // When the loan was closed
+}
+
+// CommitmentStatus tells whether an investment still counts towards funding the loan.
+type CommitmentStatus string
+
+const (
+	// Committed investments count towards TotalInvested.
+	Committed CommitmentStatus = "committed"
+
+	// Released investments were handed back to the investor because the loan closed before funding.
+	Released CommitmentStatus = "released"
+
+	// Withdrawn investments were taken back by the investor while the loan was still being funded.
+	Withdrawn CommitmentStatus = "withdrawn"
+)
+
 // Investor represents a single investor and the amount they contributed to the loan.
 type Investor struct {
-	ID     string  `json:"investor_id"` // Unique identifier of the investor
-	Amount float64 `json:"amount"`      // Amount invested
+	ID     string           `json:"investor_id"` // Unique identifier of the investor
+	Amount money.Money      `json:"amount"`      // Amount invested, in the loan currency
+	Status CommitmentStatus `json:"status"`      // Whether the investment is still committed to the loan
+}
+
+// Commitments returns how much each investor has committed to the loan, in order of their first investment.
+func (l *Loan) Commitments() []Investor {
+	var list []Investor
+	index := make(map[string]int)
+	for _, inv := range l.Investors {
+		if !inv.isCommitted() {
+			continue
+		}
+		if i, ok := index[inv.ID]; ok {
+			list[i].Amount = list[i].Amount.Add(inv.Amount)
+			continue
+		}
+		index[inv.ID] = len(list)
+		list = append(list, Investor{ID: inv.ID, Amount: inv.Amount, Status: Committed})
+	}
+	return list
+}
+
+// HasInvestor reports whether the investor has ever invested in the loan, including withdrawn and released investments.
+func (l *Loan) HasInvestor(investorID string) bool {
+	for _, inv := range l.Investors {
+		if inv.ID == investorID {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommitted reports whether the investment still counts towards the loan.
+// Investments stored before statuses existed have none and are committed.
+func (i Investor) isCommitted() bool {
+	return i.Status == "" || i.Status == Committed
 }
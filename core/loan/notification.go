@@ -0,0 +1,170 @@
+package loan
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"loan-service/core/money"
+	"loan-service/core/outbox"
+)
+
+// Kinds of outbox messages written by the loan service: one per lifecycle event, and one for payouts.
+const (
+	// ApprovedNotification tells the borrower that their loan was approved and is open for funding.
+	ApprovedNotification = "loan.approved"
+
+	// InvestmentNotification confirms an investment to the investor who made it.
+	InvestmentNotification = "loan.investment_received"
+
+	// FundedNotification tells the approving staff and every investor that a loan is fully funded.
+	FundedNotification = "loan.funded"
+
+	// DisbursedNotification tells the borrower and every investor that the money was handed over.
+	DisbursedNotification = "loan.disbursed"
+
+	// ExpiredNotification tells an investor that a loan expired unfunded and their money was released.
+	ExpiredNotification = "loan.expired"
+
+	// PayoutMessage carries the investor payouts of a repayment to the PayoutRepository.
+	PayoutMessage = "loan.payouts"
+)
+
+// RecipientRole tells what part a notification's recipient plays in the loan.
+type RecipientRole string
+
+const (
+	// BorrowerRecipient is the borrower of the loan; the recipient ID is the borrower ID.
+	BorrowerRecipient RecipientRole = "borrower"
+
+	// InvestorRecipient is an investor in the loan; the recipient ID is the investor ID.
+	InvestorRecipient RecipientRole = "investor"
+
+	// StaffRecipient is an employee; the recipient ID is their employee ID.
+	StaffRecipient RecipientRole = "staff"
+)
+
+// Recipient is who a notification is addressed to.
+type Recipient struct {
+	Role RecipientRole `json:"role"`
+	ID   string        `json:"id"`
+}
+
+// Notification is the context every lifecycle email carries.
+type Notification struct {
+	Recipient Recipient `json:"recipient"`
+	Loan      Loan      `json:"loan"` // The loan as stored by the change that triggered the notification
+}
+
+// LoanApproved is sent to the borrower when their loan is approved.
+type LoanApproved struct {
+	Notification
+}
+
+// InvestmentReceived is sent to an investor when their investment is accepted.
+type InvestmentReceived struct {
+	Notification
+	Amount money.Money `json:"amount"` // The amount just invested
+}
+
+// LoanFunded is sent to the staff who approved the loan and to every investor once it is fully funded.
+type LoanFunded struct {
+	Notification
+}
+
+// LoanDisbursed is sent to the borrower and to every investor when the loan is disbursed.
+type LoanDisbursed struct {
+	Notification
+}
+
+// FundingExpired is sent to every investor whose money was released because the loan expired unfunded.
+type FundingExpired struct {
+	Notification
+}
+
+// notification returns the context for a notification about a change to loan that is about to be stored.
+func notification(loan *Loan, to Recipient) Notification {
+	return Notification{Recipient: to, Loan: stored(loan)}
+}
+
+// stored returns a copy of loan as it will be once the change being made to it is stored.
+func stored(loan *Loan) Loan {
+	snapshot := loan.clone()
+	snapshot.Version++ // Messages are stored together with the change, which bumps the version
+	return *snapshot
+}
+
+// borrower returns the borrower of loan as a recipient.
+func borrower(loan *Loan) Recipient {
+	return Recipient{Role: BorrowerRecipient, ID: loan.BorrowerID}
+}
+
+// investors returns every investor with a committed investment in loan, each once, in order.
+func investors(loan *Loan) []Recipient {
+	var recipients []Recipient
+	seen := make(map[string]bool)
+	for _, inv := range loan.Investors {
+		if inv.isCommitted() && !seen[inv.ID] {
+			seen[inv.ID] = true
+			recipients = append(recipients, Recipient{Role: InvestorRecipient, ID: inv.ID})
+		}
+	}
+	return recipients
+}
+
+// notify builds an outbox message of the given kind for each event.
+func (s *LoanService) notify(kind string, events ...any) ([]outbox.Message, error) {
+	messages := make([]outbox.Message, 0, len(events))
+	for _, event := range events {
+		msg, err := outbox.New(kind, event, s.now())
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// DeliverNotification sends the email, publishes the event or saves the payouts behind an outbox message
+// written by the service. It is the outbox.Handler to run the dispatcher with.
+func (s *LoanService) DeliverNotification(msg outbox.Message) error {
+	switch msg.Kind {
+	case ApprovedNotification:
+		return deliver(msg, s.email.SendLoanApproved)
+	case InvestmentNotification:
+		return deliver(msg, s.email.SendInvestmentReceived)
+	case FundedNotification:
+		return deliver(msg, s.email.SendLoanFunded)
+	case DisbursedNotification:
+		return deliver(msg, s.email.SendLoanDisbursed)
+	case ExpiredNotification:
+		return deliver(msg, s.email.SendFundingExpired)
+	case PayoutMessage:
+		return deliver(msg, s.payouts.Save)
+	case EventMessage:
+		if s.events == nil {
+			return errNoPublisher
+		}
+		return deliver(msg, s.events.Publish)
+	default:
+		return fmt.Errorf("unknown notification kind %q", msg.Kind)
+	}
+}
+
+// deliver decodes the event in msg and hands it to send.
+func deliver[E any](msg outbox.Message, send func(E) error) error {
+	var event E
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		return fmt.Errorf("decode %s notification: %w", msg.Kind, err)
+	}
+	return send(event)
+}
+
+// ListNotifications returns the outbox messages with the given status (all of them if empty).
+func (s *LoanService) ListNotifications(status outbox.Status) ([]outbox.Message, error) {
+	return s.repo.Outbox().List(status)
+}
+
+// RetryNotification queues a failed outbox message for delivery again, with a fresh set of attempts.
+func (s *LoanService) RetryNotification(id string) (outbox.Message, error) {
+	return s.repo.Outbox().Retry(id, s.now())
+}
@@ -0,0 +1,126 @@
+package loan
+
+import "time"
+
+// Option customises a single LoanService call.
+type Option func(*options)
+
+type options struct {
+	expectedVersion *int64
+	terms           *RepaymentTerms
+	fundingDeadline *time.Time
+	actor           string
+}
+
+// IfVersion makes the call fail with a ConflictError unless the loan is currently at version v.
+// It is how clients implement read-modify-write cycles (HTTP If-Match) without overwriting others' changes.
+func IfVersion(v int64) Option {
+	return func(o *options) {
+		o.expectedVersion = &v
+	}
+}
+
+// WithRepaymentTerms sets how a new loan will be repaid. Only used by CreateLoan;
+// loans created without it get DefaultRepaymentTerms.
+func WithRepaymentTerms(terms RepaymentTerms) Option {
+	return func(o *options) {
+		o.terms = &terms
+	}
+}
+
+// WithFundingDeadline gives an approved loan until deadline to be fully funded; after that it expires.
+// Only used by ApproveLoan; loans approved without it stay open for funding indefinitely.
+func WithFundingDeadline(deadline time.Time) Option {
+	return func(o *options) {
+		o.fundingDeadline = &deadline
+	}
+}
+
+// WithActor records who made the change in the audit trail. Without it the service
+// falls back to the person named in the request itself, e.g. the validator of an approval.
+func WithActor(id string) Option {
+	return func(o *options) {
+		o.actor = id
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// actorOr returns the actor set with WithActor, or fallback if there is none.
+func (o options) actorOr(fallback string) string {
+	if o.actor != "" {
+		return o.actor
+	}
+	return fallback
+}
+
+// checkVersion enforces IfVersion against the loan that was just loaded.
+func (o options) checkVersion(loan *Loan) error {
+	if o.expectedVersion != nil && *o.expectedVersion != loan.Version {
+		return &ConflictError{LoanID: loan.ID, Expected: *o.expectedVersion, Actual: loan.Version}
+	}
+	return nil
+}
+
+// ServiceOption configures optional dependencies of a LoanService.
+type ServiceOption func(*LoanService)
+
+// WithPayoutRepository sets where investor payouts are stored. Defaults to an in-memory repository.
+func WithPayoutRepository(repo PayoutRepository) ServiceOption {
+	return func(s *LoanService) {
+		s.payouts = repo
+	}
+}
+
+// WithEventPublisher makes the service publish loan events (see EventTypes) through the outbox.
+// Without it no events are published.
+func WithEventPublisher(p EventPublisher) ServiceOption {
+	return func(s *LoanService) {
+		s.events = p
+	}
+}
+
+// WithInvestorRegistry makes InvestLoan accept investments only from investors the registry approves of.
+// Without it any investor ID is accepted.
+func WithInvestorRegistry(r InvestorRegistry) ServiceOption {
+	return func(s *LoanService) {
+		s.investors = r
+	}
+}
+
+// WithBorrowerRegistry makes CreateLoan accept applications only from borrowers the registry approves of.
+// Without it any borrower ID is accepted.
+func WithBorrowerRegistry(r BorrowerRegistry) ServiceOption {
+	return func(s *LoanService) {
+		s.borrowers = r
+	}
+}
+
+// WithDocumentStore makes ApproveLoan and DisburseLoan accept only IDs of uploaded documents as the photo proof,
+// agreement file and agreement letter. Without it any text is accepted.
+func WithDocumentStore(store DocumentStore) ServiceOption {
+	return func(s *LoanService) {
+		s.documents = store
+	}
+}
+
+// WithAgreementGenerator makes the service generate the agreement letter of a loan when it is approved, and
+// again with the final list of investors when it is fully funded. Without it the letter is only linked at disbursement.
+func WithAgreementGenerator(g AgreementGenerator) ServiceOption {
+	return func(s *LoanService) {
+		s.agreements = g
+	}
+}
+
+// WithClock replaces the service's source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) ServiceOption {
+	return func(s *LoanService) {
+		s.now = now
+	}
+}
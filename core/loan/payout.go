@@ -0,0 +1,140 @@
+package loan
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"loan-service/core/money"
+)
+
+// Payout is the share of a borrower repayment paid out to one investor.
+type Payout struct {
+	ID          string      `json:"id"`           // Deterministic: <repayment ID>:<investor ID>
+	LoanID      string      `json:"loan_id"`      // Loan the repayment belongs to
+	RepaymentID string      `json:"repayment_id"` // Repayment that funded the payout
+	InvestorID  string      `json:"investor_id"`  // Investor receiving the payout
+	Principal   money.Money `json:"principal"`    // Investor's share of the repaid principal
+	Return      money.Money `json:"return"`       // Investor's share of the return (interest × ROI / rate)
+	Amount      money.Money `json:"amount"`       // Principal + Return
+	CreatedAt   time.Time   `json:"created_at"`   // Time the payout was recorded
+}
+
+// PayoutRepository stores the payout history of investors.
+// Save must be idempotent on Payout.ID so a distribution can safely be retried.
+type PayoutRepository interface {
+	Save(payouts []Payout) error
+	ListByLoan(loanID string) ([]Payout, error)
+	ListByInvestor(investorID string) ([]Payout, error)
+}
+
+// distribute splits a repayment across the loan's investors in proportion to what each invested.
+//
+// The investor pool is the repaid principal plus the investor return, which is the repaid interest
+// scaled by ROI/Rate (capped at the interest itself); fees and the remaining interest stay with the
+// platform. Principal and return are split separately with money.Allocate, so the shares always add
+// up exactly and leftover minor units go to the largest remainders, ties to the earliest investor.
+// Several investments by the same investor are combined into one payout; released ones are skipped.
+func distribute(loan *Loan, repayment Repayment) []Payout {
+	var (
+		order   []string
+		weights = make(map[string]int64)
+	)
+	for _, inv := range loan.Investors {
+		if !inv.isCommitted() {
+			continue
+		}
+		if _, seen := weights[inv.ID]; !seen {
+			order = append(order, inv.ID)
+		}
+		weights[inv.ID] += inv.Amount.MinorUnits()
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	w := make([]int64, len(order))
+	for i, id := range order {
+		w[i] = weights[id]
+	}
+	principals := repayment.Principal.Allocate(w)
+	returns := investorReturn(loan, repayment.Interest).Allocate(w)
+
+	payouts := make([]Payout, 0, len(order))
+	for i, id := range order {
+		payouts = append(payouts, Payout{
+			ID:          repayment.ID + ":" + id,
+			LoanID:      loan.ID,
+			RepaymentID: repayment.ID,
+			InvestorID:  id,
+			Principal:   principals[i],
+			Return:      returns[i],
+			Amount:      principals[i].Add(returns[i]),
+			CreatedAt:   repayment.RecordedAt,
+		})
+	}
+	return payouts
+}
+
+// investorReturn is the part of the repaid interest owed to investors: interest × ROI / Rate, at most the interest.
+func investorReturn(loan *Loan, interest money.Money) money.Money {
+	if loan.Rate <= 0 || loan.ROI <= 0 {
+		return money.Zero(interest.Currency())
+	}
+	share := new(big.Rat).Quo(money.PercentRat(loan.ROI), money.PercentRat(loan.Rate))
+	if share.Cmp(big.NewRat(1, 1)) > 0 {
+		return interest
+	}
+	return interest.MulRat(share)
+}
+
+// InMemoryPayoutRepository keeps payouts in memory. It is safe for concurrent use.
+type InMemoryPayoutRepository struct {
+	mu      sync.RWMutex
+	payouts []Payout
+	ids     map[string]bool
+}
+
+// NewInMemoryPayoutRepository creates an empty in-memory payout repository.
+func NewInMemoryPayoutRepository() *InMemoryPayoutRepository {
+	return &InMemoryPayoutRepository{ids: make(map[string]bool)}
+}
+
+// Save stores payouts, skipping any whose ID is already known.
+func (r *InMemoryPayoutRepository) Save(payouts []Payout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range payouts {
+		if r.ids[p.ID] {
+			continue
+		}
+		r.ids[p.ID] = true
+		r.payouts = append(r.payouts, p)
+	}
+	return nil
+}
+
+// ListByLoan returns the payouts of a loan in the order they were recorded.
+func (r *InMemoryPayoutRepository) ListByLoan(loanID string) ([]Payout, error) {
+	return r.filter(func(p Payout) bool { return p.LoanID == loanID }), nil
+}
+
+// ListByInvestor returns the payouts of an investor in the order they were recorded.
+func (r *InMemoryPayoutRepository) ListByInvestor(investorID string) ([]Payout, error) {
+	return r.filter(func(p Payout) bool { return p.InvestorID == investorID }), nil
+}
+
+func (r *InMemoryPayoutRepository) filter(keep func(Payout) bool) []Payout {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []Payout{}
+	for _, p := range r.payouts {
+		if keep(p) {
+			result = append(result, p)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
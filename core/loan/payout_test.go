@@ -0,0 +1,131 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+)
+
+func TestDistribute(t *testing.T) {
+	recordedAt := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	repayment := Repayment{ID: "R1", Principal: money.New(100, money.IDR), Interest: money.New(30, money.IDR), RecordedAt: recordedAt}
+
+	t.Run("Pro rata with ROI applied", func(t *testing.T) {
+		// Rate 12%, ROI 8% → investors get 2/3 of the interest: 20 of 30.
+		ln := &Loan{ID: "L1", Rate: 12, ROI: 8, Investors: []Investor{
+			{ID: "A", Amount: idr(1000)},
+			{ID: "B", Amount: idr(3000)},
+		}}
+
+		payouts := distribute(ln, repayment)
+		require.Len(t, payouts, 2)
+		assert.Equal(t, Payout{
+			ID: "R1:A", LoanID: "L1", RepaymentID: "R1", InvestorID: "A",
+			Principal: money.New(25, money.IDR), Return: money.New(5, money.IDR), Amount: money.New(30, money.IDR),
+			CreatedAt: recordedAt,
+		}, payouts[0])
+		assert.Equal(t, money.New(75, money.IDR), payouts[1].Principal)
+		assert.Equal(t, money.New(15, money.IDR), payouts[1].Return)
+	})
+
+	t.Run("Remainder goes deterministically and nothing is lost", func(t *testing.T) {
+		ln := &Loan{ID: "L2", Rate: 10, ROI: 10, Investors: []Investor{
+			{ID: "A", Amount: idr(1)}, {ID: "B", Amount: idr(1)}, {ID: "C", Amount: idr(1)},
+		}}
+
+		for i := 0; i < 3; i++ {
+			payouts := distribute(ln, repayment)
+			require.Len(t, payouts, 3)
+			assert.Equal(t, []int64{34, 33, 33}, []int64{
+				payouts[0].Principal.MinorUnits(), payouts[1].Principal.MinorUnits(), payouts[2].Principal.MinorUnits(),
+			})
+			assert.Equal(t, []int64{10, 10, 10}, []int64{
+				payouts[0].Return.MinorUnits(), payouts[1].Return.MinorUnits(), payouts[2].Return.MinorUnits(),
+			})
+		}
+	})
+
+	t.Run("Repeat investors are combined", func(t *testing.T) {
+		ln := &Loan{ID: "L3", Rate: 10, ROI: 10, Investors: []Investor{
+			{ID: "A", Amount: idr(1000)}, {ID: "B", Amount: idr(2000)}, {ID: "A", Amount: idr(1000)},
+		}}
+
+		payouts := distribute(ln, repayment)
+		require.Len(t, payouts, 2)
+		assert.Equal(t, "A", payouts[0].InvestorID)
+		assert.Equal(t, money.New(50, money.IDR), payouts[0].Principal)
+		assert.Equal(t, money.New(50, money.IDR), payouts[1].Principal)
+	})
+
+	t.Run("Return never exceeds the interest paid", func(t *testing.T) {
+		ln := &Loan{ID: "L4", Rate: 5, ROI: 10, Investors: []Investor{{ID: "A", Amount: idr(1)}}}
+		assert.Equal(t, money.New(30, money.IDR), distribute(ln, repayment)[0].Return)
+	})
+
+	t.Run("Released investments are skipped", func(t *testing.T) {
+		ln := &Loan{ID: "L6", Rate: 10, ROI: 10, Investors: []Investor{
+			{ID: "A", Amount: idr(1000), Status: Released}, {ID: "B", Amount: idr(1000), Status: Committed},
+		}}
+		payouts := distribute(ln, repayment)
+		require.Len(t, payouts, 1)
+		assert.Equal(t, "B", payouts[0].InvestorID)
+		assert.Equal(t, money.New(100, money.IDR), payouts[0].Principal)
+	})
+
+	t.Run("No investors", func(t *testing.T) {
+		assert.Empty(t, distribute(&Loan{ID: "L5", Rate: 10, ROI: 10}, repayment))
+	})
+}
+
+func TestInMemoryPayoutRepository(t *testing.T) {
+	testPayoutRepository(t, NewInMemoryPayoutRepository())
+}
+
+func TestSQLPayoutRepository(t *testing.T) {
+	loans := newSQLiteRepository(t)
+	ln := &Loan{BorrowerID: "B900", PrincipalAmount: idr(1000)}
+	require.NoError(t, loans.Create(ln, nil))
+
+	repo := NewSQLPayoutRepository(loans.db)
+	testPayoutRepository(t, repo, ln.ID)
+}
+
+// testPayoutRepository is the behaviour every PayoutRepository implementation must satisfy.
+// loanIDs optionally provides existing loans for backends that enforce foreign keys.
+func testPayoutRepository(t *testing.T, repo PayoutRepository, loanIDs ...string) {
+	loanID := "L1"
+	if len(loanIDs) > 0 {
+		loanID = loanIDs[0]
+	}
+	at := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	payout := func(repaymentID, investorID string, amount int64, at time.Time) Payout {
+		return Payout{
+			ID: repaymentID + ":" + investorID, LoanID: loanID, RepaymentID: repaymentID, InvestorID: investorID,
+			Principal: idr(amount), Return: idr(0), Amount: idr(amount), CreatedAt: at,
+		}
+	}
+
+	require.NoError(t, repo.Save([]Payout{payout("R1", "A", 10, at), payout("R1", "B", 20, at)}))
+	require.NoError(t, repo.Save([]Payout{payout("R2", "A", 30, at.Add(time.Hour))}))
+	require.NoError(t, repo.Save([]Payout{payout("R1", "A", 99, at)}), "saving an existing ID is a no-op")
+
+	byLoan, err := repo.ListByLoan(loanID)
+	require.NoError(t, err)
+	require.Len(t, byLoan, 3)
+	assert.Equal(t, "R1:A", byLoan[0].ID)
+	assert.Equal(t, idr(10), byLoan[0].Amount)
+	assert.Equal(t, "R2:A", byLoan[2].ID)
+
+	byInvestor, err := repo.ListByInvestor("A")
+	require.NoError(t, err)
+	require.Len(t, byInvestor, 2)
+	assert.Equal(t, idr(30), byInvestor[1].Principal)
+	assert.True(t, at.Add(time.Hour).Equal(byInvestor[1].CreatedAt))
+
+	none, err := repo.ListByInvestor("nobody")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
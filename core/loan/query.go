@@ -0,0 +1,250 @@
+package loan
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"loan-service/core/money"
+)
+
+// Page sizes for Search.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidQuery is matched by every error about a malformed LoanQuery.
+var ErrInvalidQuery = errors.New("invalid loan query")
+
+// LoanSort is the field loans are ordered by. Ties are broken by loan ID.
+type LoanSort string
+
+const (
+	// SortByCreatedAt orders loans by when they were created. It is the default.
+	SortByCreatedAt LoanSort = "created_at"
+
+	// SortByPrincipal orders loans by their principal amount, in minor units.
+	SortByPrincipal LoanSort = "principal_amount"
+)
+
+// LoanQuery selects, orders and pages the loans returned by Search. Zero fields do not filter.
+type LoanQuery struct {
+	States      []LoanState  // Loans in any of these states
+	BorrowerID  string       // Loans of this borrower
+	InvestorID  string       // Loans this investor has invested in, including withdrawn and released investments
+	DocumentID  string       // Loans whose photo proof, signed agreement or agreement letter is this document
+	CreatedFrom *time.Time   // Loans created at or after this time
+	CreatedTo   *time.Time   // Loans created before this time
+	MinAmount   *money.Money // Loans in the same currency with at least this principal
+	MaxAmount   *money.Money // Loans in the same currency with at most this principal
+	Party       *Party       // Only loans this borrower or investor may see; nil for every loan
+
+	Sort       LoanSort // Defaults to SortByCreatedAt
+	Descending bool
+	Limit      int    // Page size; defaults to DefaultPageSize
+	Cursor     string // LoanPage.NextCursor of the previous page; empty for the first page
+}
+
+// Party limits a LoanQuery to the loans a borrower or investor may see. A Party with neither ID matches no loan.
+type Party struct {
+	BorrowerID string // The borrower's own loans
+	InvestorID string // Loans the investor has invested in, and loans open for investment (Approved)
+}
+
+// sees reports whether the party may see loan.
+func (p *Party) sees(loan *Loan) bool {
+	return (p.BorrowerID != "" && loan.BorrowerID == p.BorrowerID) ||
+		(p.InvestorID != "" && (loan.HasInvestor(p.InvestorID) || loan.State == Approved))
+}
+
+// LoanPage is one page of Search results.
+type LoanPage struct {
+	Loans      []*Loan `json:"loans"`
+	Total      int     `json:"total"`                 // Number of loans matching the filters, across all pages
+	NextCursor string  `json:"next_cursor,omitempty"` // Cursor of the next page; empty on the last page
+}
+
+// Validate checks the query and fills in the default sort and page size.
+func (q *LoanQuery) Validate() error {
+	for _, state := range q.States {
+		if !knownState(state) {
+			return fmt.Errorf("%w: unknown state %q", ErrInvalidQuery, state)
+		}
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = SortByCreatedAt
+	case SortByCreatedAt, SortByPrincipal:
+	default:
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, q.Sort)
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultPageSize
+	case q.Limit < 0 || q.Limit > MaxPageSize:
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && !q.MinAmount.SameCurrency(*q.MaxAmount) {
+		return fmt.Errorf("%w: amount range mixes currencies", ErrInvalidQuery)
+	}
+	if q.Cursor != "" {
+		if _, err := q.after(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// knownState reports whether state is one of the loan lifecycle states.
+func knownState(state LoanState) bool {
+	switch state {
+	case Proposed, Approved, Invested, Disbursed, Repaid, Rejected, Cancelled, Expired:
+		return true
+	}
+	return false
+}
+
+// cursor is the position of the last loan on a page, in the order the page was sorted by.
+// Encoding the sort key rather than an offset keeps pages stable while loans are being created.
+type cursor struct {
+	Sort       LoanSort `json:"s"`
+	Descending bool     `json:"d,omitempty"`
+	Value      string   `json:"v"` // Sort key of the loan: RFC 3339 creation time or principal in minor units
+	ID         string   `json:"id"`
+}
+
+// cursorAfter returns the cursor pointing just past loan.
+func (q *LoanQuery) cursorAfter(loan *Loan) string {
+	c := cursor{Sort: q.Sort, Descending: q.Descending, ID: loan.ID}
+	if q.Sort == SortByPrincipal {
+		c.Value = strconv.FormatInt(loan.PrincipalAmount.MinorUnits(), 10)
+	} else {
+		c.Value = loan.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// after decodes the query's cursor, which must have been issued for the same ordering.
+func (q *LoanQuery) after() (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err == nil {
+		_, err = c.key()
+	}
+	if err != nil || c.ID == "" {
+		return cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != q.Sort || c.Descending != q.Descending {
+		return cursor{}, fmt.Errorf("%w: cursor belongs to a different sort order", ErrInvalidQuery)
+	}
+	return c, nil
+}
+
+// key returns the cursor's sort key as the value stored in the database: a time or minor units.
+func (c cursor) key() (any, error) {
+	if c.Sort == SortByPrincipal {
+		return strconv.ParseInt(c.Value, 10, 64)
+	}
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	return t, err
+}
+
+// matches reports whether loan passes the query's filters.
+func (q *LoanQuery) matches(loan *Loan) bool {
+	if len(q.States) > 0 && !slices.Contains(q.States, loan.State) {
+		return false
+	}
+	if q.BorrowerID != "" && loan.BorrowerID != q.BorrowerID {
+		return false
+	}
+	if q.InvestorID != "" && !loan.HasInvestor(q.InvestorID) {
+		return false
+	}
+	if q.DocumentID != "" && !refersTo(loan, q.DocumentID) {
+		return false
+	}
+	if q.Party != nil && !q.Party.sees(loan) {
+		return false
+	}
+	if q.CreatedFrom != nil && loan.CreatedAt.Before(*q.CreatedFrom) {
+		return false
+	}
+	if q.CreatedTo != nil && !loan.CreatedAt.Before(*q.CreatedTo) {
+		return false
+	}
+	if m := q.MinAmount; m != nil && (currencyOf(loan) != m.Currency() || loan.PrincipalAmount.MinorUnits() < m.MinorUnits()) {
+		return false
+	}
+	if m := q.MaxAmount; m != nil && (currencyOf(loan) != m.Currency() || loan.PrincipalAmount.MinorUnits() > m.MinorUnits()) {
+		return false
+	}
+	return true
+}
+
+// less reports whether a comes before b in the query's order.
+func (q *LoanQuery) less(a, b *Loan) bool {
+	var order int
+	if q.Sort == SortByPrincipal {
+		order = cmp.Compare(a.PrincipalAmount.MinorUnits(), b.PrincipalAmount.MinorUnits())
+	} else {
+		order = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if order == 0 {
+		order = cmp.Compare(a.ID, b.ID)
+	}
+	if q.Descending {
+		return order > 0
+	}
+	return order < 0
+}
+
+// paginate sorts the matching loans and cuts out the page the query asks for.
+func (q *LoanQuery) paginate(matching []*Loan) (*LoanPage, error) {
+	sort.Slice(matching, func(i, j int) bool { return q.less(matching[i], matching[j]) })
+
+	page := &LoanPage{Loans: []*Loan{}, Total: len(matching)}
+	rest := matching
+	if q.Cursor != "" {
+		c, err := q.after()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := c.key()
+		last := &Loan{ID: c.ID}
+		if t, ok := key.(time.Time); ok {
+			last.CreatedAt = t
+		} else {
+			last.PrincipalAmount = money.New(key.(int64), "")
+		}
+		rest = matching[sort.Search(len(matching), func(i int) bool { return q.less(last, matching[i]) }):]
+	}
+	if len(rest) > q.Limit {
+		rest = rest[:q.Limit]
+		page.NextCursor = q.cursorAfter(rest[len(rest)-1])
+	}
+	page.Loans = append(page.Loans, rest...)
+	return page, nil
+}
+
+// refersTo reports whether loan refers to the document as its photo proof, signed agreement or agreement letter.
+// The agreement letter is either linked (see LetterLink) or, when given at disbursement, named by its ID.
+func refersTo(loan *Loan, documentID string) bool {
+	letter, ok := LetterDocument(loan.AgreementLetterURL)
+	if !ok {
+		letter = loan.AgreementLetterURL
+	}
+	return letter == documentID ||
+		(loan.Approval != nil && loan.Approval.PhotoProofURL == documentID) ||
+		(loan.Disbursement != nil && loan.Disbursement.AgreementFile == documentID)
+}
@@ -0,0 +1,162 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+)
+
+func TestLoanQuery_Validate(t *testing.T) {
+	usd := money.FromMajor(10, money.USD)
+	cases := []struct {
+		name  string
+		query LoanQuery
+		err   string
+	}{
+		{name: "defaults", query: LoanQuery{}},
+		{name: "known states", query: LoanQuery{States: []LoanState{Approved, Expired}}},
+		{name: "unknown state", query: LoanQuery{States: []LoanState{"pending"}}, err: `unknown state "pending"`},
+		{name: "unknown sort", query: LoanQuery{Sort: "rate"}, err: `cannot sort by "rate"`},
+		{name: "negative limit", query: LoanQuery{Limit: -1}, err: "limit must be between 1 and 100"},
+		{name: "limit too large", query: LoanQuery{Limit: MaxPageSize + 1}, err: "limit must be between 1 and 100"},
+		{name: "mixed currencies", query: LoanQuery{MinAmount: ptr(idr(1)), MaxAmount: &usd}, err: "amount range mixes currencies"},
+		{name: "malformed cursor", query: LoanQuery{Cursor: "not-a-cursor"}, err: "malformed cursor"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.query.Validate()
+			if tc.err != "" {
+				assert.ErrorIs(t, err, ErrInvalidQuery)
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, SortByCreatedAt, tc.query.Sort)
+			assert.Equal(t, DefaultPageSize, tc.query.Limit)
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("InMemory", func(t *testing.T) { testSearch(t, NewInMemoryLoanRepository()) })
+	t.Run("SQL", func(t *testing.T) { testSearch(t, newSQLiteRepository(t)) })
+}
+
+func testSearch(t *testing.T, repo LoanRepository) {
+	create := func(borrower string, principal money.Money, state LoanState, investors ...Investor) *Loan {
+		time.Sleep(2 * time.Millisecond) // Keep creation times apart
+		ln := &Loan{BorrowerID: borrower, PrincipalAmount: principal, Rate: 10, ROI: 8}
+		require.NoError(t, repo.Create(ln, nil))
+		ln.State = state
+		ln.Investors = investors
+		require.NoError(t, repo.Update(ln, nil))
+		return ln
+	}
+	l1 := create("B1", idr(1000), Proposed)
+	l2 := create("B1", idr(3000), Approved, Investor{ID: "I1", Amount: idr(500), Status: Committed})
+	l3 := create("B2", idr(2000), Invested,
+		Investor{ID: "I1", Amount: idr(500), Status: Withdrawn}, Investor{ID: "I2", Amount: idr(2000), Status: Committed})
+	l4 := create("B2", idr(5000), Rejected)
+	l5 := create("B3", idr(4000), Proposed)
+	usd := create("B3", money.FromMajor(3000, money.USD), Proposed)
+	l2.Approval = &Approval{PhotoProofURL: "DOC1", ValidatorID: "EMP1", ApprovalDate: l2.CreatedAt}
+	require.NoError(t, repo.Update(l2, nil))
+	l3.Disbursement = &Disbursement{AgreementFile: "DOC1", FieldOfficerID: "FO1", DisbursementDate: l3.CreatedAt}
+	require.NoError(t, repo.Update(l3, nil))
+	l5.AgreementLetterURL = "DOC1"
+	require.NoError(t, repo.Update(l5, nil))
+	l4.AgreementLetterURL = LetterLink("https://loans.example.com", "DOC1")
+	require.NoError(t, repo.Update(l4, nil))
+	usd.AgreementLetterURL = LetterLink("", "DOC12")
+	require.NoError(t, repo.Update(usd, nil))
+
+	search := func(t *testing.T, query LoanQuery) *LoanPage {
+		t.Helper()
+		page, err := repo.Search(query)
+		require.NoError(t, err)
+		return page
+	}
+	ids := func(loans ...*Loan) []string {
+		out := make([]string, len(loans))
+		for i, ln := range loans {
+			out[i] = ln.ID
+		}
+		return out
+	}
+	pageIDs := func(page *LoanPage) []string { return ids(page.Loans...) }
+
+	cases := []struct {
+		name  string
+		query LoanQuery
+		want  []*Loan
+	}{
+		{name: "everything, oldest first", query: LoanQuery{}, want: []*Loan{l1, l2, l3, l4, l5, usd}},
+		{name: "by state", query: LoanQuery{States: []LoanState{Approved, Invested}}, want: []*Loan{l2, l3}},
+		{name: "by borrower", query: LoanQuery{BorrowerID: "B2"}, want: []*Loan{l3, l4}},
+		{name: "by investor, including withdrawn investments", query: LoanQuery{InvestorID: "I1"}, want: []*Loan{l2, l3}},
+		{name: "by document", query: LoanQuery{DocumentID: "DOC1"}, want: []*Loan{l2, l3, l4, l5}},
+		{name: "by linked document", query: LoanQuery{DocumentID: "DOC12"}, want: []*Loan{usd}},
+		{name: "by document, without wildcards", query: LoanQuery{DocumentID: "DOC_2"}, want: []*Loan{}},
+		{name: "seen by a borrower", query: LoanQuery{Party: &Party{BorrowerID: "B2"}}, want: []*Loan{l3, l4}},
+		{name: "seen by an investor, with loans open for investment", query: LoanQuery{Party: &Party{InvestorID: "I2"}}, want: []*Loan{l2, l3}},
+		{name: "seen by a borrower who also invests", query: LoanQuery{Party: &Party{BorrowerID: "B3", InvestorID: "B3"}}, want: []*Loan{l2, l5, usd}},
+		{name: "seen by a party without IDs", query: LoanQuery{Party: &Party{}}, want: []*Loan{}},
+		{name: "seen by a borrower, by state", query: LoanQuery{Party: &Party{BorrowerID: "B1"}, States: []LoanState{Proposed}}, want: []*Loan{l1}},
+		{name: "by creation time", query: LoanQuery{CreatedFrom: &l2.CreatedAt, CreatedTo: &l4.CreatedAt}, want: []*Loan{l2, l3}},
+		{name: "by amount", query: LoanQuery{MinAmount: ptr(idr(2000)), MaxAmount: ptr(idr(4000))}, want: []*Loan{l2, l3, l5}},
+		{name: "by amount in another currency", query: LoanQuery{MinAmount: ptr(money.FromMajor(1, money.USD))}, want: []*Loan{usd}},
+		{name: "largest first", query: LoanQuery{Sort: SortByPrincipal, Descending: true, MaxAmount: ptr(idr(9000))}, want: []*Loan{l4, l5, l2, l3, l1}},
+		{name: "newest first", query: LoanQuery{Descending: true, BorrowerID: "B1"}, want: []*Loan{l2, l1}},
+		{name: "nothing matches", query: LoanQuery{BorrowerID: "B9"}, want: []*Loan{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page := search(t, tc.query)
+			assert.Equal(t, ids(tc.want...), pageIDs(page))
+			assert.Equal(t, len(tc.want), page.Total)
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+
+	t.Run("pages through the results", func(t *testing.T) {
+		query := LoanQuery{Sort: SortByPrincipal, Limit: 2, MaxAmount: ptr(idr(9000))}
+		var got [][]string
+		for {
+			page := search(t, query)
+			assert.Equal(t, 5, page.Total)
+			got = append(got, pageIDs(page))
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, [][]string{ids(l1, l3), ids(l2, l5), ids(l4)}, got)
+	})
+
+	t.Run("pages stay stable while loans are created", func(t *testing.T) {
+		query := LoanQuery{Descending: true, Limit: 2}
+		first := search(t, query)
+		assert.Equal(t, ids(usd, l5), pageIDs(first))
+
+		create("B4", idr(1000), Proposed)
+		query.Cursor = first.NextCursor
+		second := search(t, query)
+		assert.Equal(t, ids(l4, l3), pageIDs(second))
+		assert.Equal(t, 7, second.Total)
+	})
+
+	t.Run("cursor of another sort order", func(t *testing.T) {
+		page := search(t, LoanQuery{Limit: 1})
+		_, err := repo.Search(LoanQuery{Limit: 1, Sort: SortByPrincipal, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidQuery)
+		_, err = repo.Search(LoanQuery{Limit: 1, Descending: true, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidQuery)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
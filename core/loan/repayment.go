@@ -0,0 +1,68 @@
+package loan
+
+import (
+	"time"
+
+	"loan-service/core/money"
+)
+
+// Balance is what the borrower still owes, split by component.
+type Balance struct {
+	Fees      money.Money `json:"fees"`      // Unpaid fees
+	Interest  money.Money `json:"interest"`  // Unpaid interest
+	Principal money.Money `json:"principal"` // Unpaid principal
+}
+
+// Total returns the sum of all components.
+func (b Balance) Total() money.Money {
+	return b.Fees.Add(b.Interest).Add(b.Principal)
+}
+
+// IsZero reports whether nothing is owed any more.
+func (b Balance) IsZero() bool {
+	return b.Fees.IsZero() && b.Interest.IsZero() && b.Principal.IsZero()
+}
+
+// Repayment is a payment received from the borrower and how it was allocated.
+type Repayment struct {
+	ID         string      `json:"id"`          // Unique identifier of the repayment
+	Amount     money.Money `json:"amount"`      // Amount paid by the borrower
+	Fees       money.Money `json:"fees"`        // Part of Amount allocated to fees
+	Interest   money.Money `json:"interest"`    // Part of Amount allocated to interest
+	Principal  money.Money `json:"principal"`   // Part of Amount allocated to principal
+	Reference  string      `json:"reference"`   // External payment reference (e.g. bank transfer ID)
+	PaidAt     time.Time   `json:"paid_at"`     // Date the borrower paid
+	RecordedAt time.Time   `json:"recorded_at"` // Time the repayment was recorded
+}
+
+// scheduledBalance is what the borrower owes over the whole schedule, before any repayment.
+func scheduledBalance(installments []Installment, currency money.Currency) Balance {
+	b := Balance{Fees: money.Zero(currency), Interest: money.Zero(currency), Principal: money.Zero(currency)}
+	for _, inst := range installments {
+		b.Fees = b.Fees.Add(inst.Fee)
+		b.Interest = b.Interest.Add(inst.Interest)
+		b.Principal = b.Principal.Add(inst.Principal)
+	}
+	return b
+}
+
+// allocate applies amount to the outstanding balance: fees first, then interest, then principal.
+// It returns the updated balance and the repayment split. amount must not exceed outstanding.Total().
+func allocate(outstanding Balance, amount money.Money) (Balance, Repayment) {
+	split := Repayment{Amount: amount}
+	remaining := amount
+
+	take := func(owed money.Money) (paid, left money.Money) {
+		paid = owed
+		if remaining.Cmp(owed) < 0 {
+			paid = remaining
+		}
+		remaining = remaining.Sub(paid)
+		return paid, owed.Sub(paid)
+	}
+
+	split.Fees, outstanding.Fees = take(outstanding.Fees)
+	split.Interest, outstanding.Interest = take(outstanding.Interest)
+	split.Principal, outstanding.Principal = take(outstanding.Principal)
+	return outstanding, split
+}
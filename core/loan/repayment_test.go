@@ -0,0 +1,61 @@
+package loan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocate(t *testing.T) {
+	owed := Balance{Fees: idr(100), Interest: idr(1000), Principal: idr(10000)}
+
+	tests := []struct {
+		name      string
+		amount    int64
+		paid      Balance
+		remaining Balance
+	}{
+		{
+			"Covers part of the fees",
+			60,
+			Balance{Fees: idr(60), Interest: idr(0), Principal: idr(0)},
+			Balance{Fees: idr(40), Interest: idr(1000), Principal: idr(10000)},
+		},
+		{
+			"Fees then interest",
+			600,
+			Balance{Fees: idr(100), Interest: idr(500), Principal: idr(0)},
+			Balance{Fees: idr(0), Interest: idr(500), Principal: idr(10000)},
+		},
+		{
+			"Fees, interest, then principal",
+			3100,
+			Balance{Fees: idr(100), Interest: idr(1000), Principal: idr(2000)},
+			Balance{Fees: idr(0), Interest: idr(0), Principal: idr(8000)},
+		},
+		{
+			"Pays everything",
+			11100,
+			Balance{Fees: idr(100), Interest: idr(1000), Principal: idr(10000)},
+			Balance{Fees: idr(0), Interest: idr(0), Principal: idr(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, split := allocate(owed, idr(tt.amount))
+			assert.Equal(t, tt.remaining, remaining)
+			assert.Equal(t, idr(tt.amount), split.Amount)
+			assert.Equal(t, tt.paid.Fees, split.Fees)
+			assert.Equal(t, tt.paid.Interest, split.Interest)
+			assert.Equal(t, tt.paid.Principal, split.Principal)
+		})
+	}
+}
+
+func TestBalance(t *testing.T) {
+	b := Balance{Fees: idr(1), Interest: idr(2), Principal: idr(3)}
+	assert.Equal(t, idr(6), b.Total())
+	assert.False(t, b.IsZero())
+	assert.True(t, Balance{}.IsZero())
+}
@@ -1,61 +1,127 @@
 package loan
 
 import (
-	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"loan-service/core/audit"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
 )
 
 // LoanRepository defines the contract for any loan storage mechanism.
+//
+// Create starts a loan at version 1. Update only succeeds when loan.Version still matches
+// the stored version; it then increments loan.Version. A stale write fails with a *ConflictError.
+//
+// Search returns one page of the loans matching query, in the order it asks for, along with the
+// number of matching loans. A malformed query fails with an error matching ErrInvalidQuery.
+//
+// ListFundingOverdue returns the Approved loans whose funding deadline is at or before the given
+// time, earliest deadline first.
+//
+// Update also stores the given outbox messages, atomically with the loan: either both are saved or
+// neither is. Outbox returns the store those messages end up in.
+//
+// Create and Update append the audit event recording the change, if it is not nil, atomically with the
+// loan as well, after setting its LoanID and LoanVersion to those of the stored loan. Audit returns the
+// trail those events end up in.
 type LoanRepository interface {
-	Create(loan *Loan) error
+	Create(loan *Loan, event *audit.Event) error
 	GetByID(id string) (*Loan, error)
-	Update(loan *Loan) error
+	Update(loan *Loan, event *audit.Event, messages ...outbox.Message) error
 	List() ([]*Loan, error)
+	Search(query LoanQuery) (*LoanPage, error)
+	ListFundingOverdue(at time.Time) ([]*Loan, error)
+	Totals() ([]LoanTotals, error)
+	Outbox() outbox.Store
+	Audit() audit.Repository
 }
 
 // InMemoryLoanRepository provides a thread-safe in-memory store for loans.
 // It is useful for development, testing, or as a temporary mock.
+//
+// Loans are copied on the way in and out, so a loan returned by GetByID or List
+// can be modified freely and only becomes visible to others through Update.
 type InMemoryLoanRepository struct {
-	store sync.Map
+	store  sync.Map
+	outbox *outbox.InMemoryStore
+	audit  *audit.InMemoryRepository
 }
 
 // NewInMemoryLoanRepository creates and returns a new in-memory loan repository instance.
 func NewInMemoryLoanRepository() *InMemoryLoanRepository {
-	return &InMemoryLoanRepository{}
+	return &InMemoryLoanRepository{outbox: outbox.NewInMemoryStore(), audit: audit.NewInMemoryRepository()}
 }
 
 // Create inserts a new loan into the store and assigns it a unique ID.
-func (r *InMemoryLoanRepository) Create(loan *Loan) error {
+func (r *InMemoryLoanRepository) Create(loan *Loan, event *audit.Event) error {
 	loan.ID = uuid.NewString()
 	now := time.Now()
 	loan.CreatedAt = now
 	loan.UpdatedAt = now
 	loan.State = Proposed
-	r.store.Store(loan.ID, loan)
-	return nil
+	loan.Version = 1
+	r.store.Store(loan.ID, loan.clone())
+	return r.append(loan, event)
 }
 
 // GetByID retrieves a loan by its ID. Returns error if not found.
 func (r *InMemoryLoanRepository) GetByID(id string) (*Loan, error) {
 	if val, ok := r.store.Load(id); ok {
 		if loan, valid := val.(*Loan); valid {
-			return loan, nil
+			return loan.clone(), nil
 		}
 	}
-	return nil, errors.New("loan not found")
+	return nil, ErrLoanNotFound
 }
 
-// Update updates an existing loan in the store.
-func (r *InMemoryLoanRepository) Update(loan *Loan) error {
-	if _, ok := r.store.Load(loan.ID); !ok {
-		return errors.New("loan not found for update")
+// Update updates an existing loan in the store, provided its version is not stale.
+// The event and messages are stored only once the loan has been swapped in.
+func (r *InMemoryLoanRepository) Update(loan *Loan, event *audit.Event, messages ...outbox.Message) error {
+	current, ok := r.store.Load(loan.ID)
+	if !ok {
+		return fmt.Errorf("%w for update", ErrLoanNotFound)
 	}
-	loan.UpdatedAt = time.Now()
-	r.store.Store(loan.ID, loan)
-	return nil
+	if stored := current.(*Loan); stored.Version != loan.Version {
+		return &ConflictError{LoanID: loan.ID, Expected: loan.Version, Actual: stored.Version}
+	}
+
+	next := loan.clone()
+	next.Version++
+	next.UpdatedAt = time.Now()
+	if !r.store.CompareAndSwap(loan.ID, current, next) {
+		return &ConflictError{LoanID: loan.ID, Expected: loan.Version}
+	}
+
+	loan.Version = next.Version
+	loan.UpdatedAt = next.UpdatedAt
+	if err := r.append(loan, event); err != nil {
+		return err
+	}
+	return r.outbox.Enqueue(messages...)
+}
+
+// append adds event, if any, to the audit trail of the loan that was just stored.
+func (r *InMemoryLoanRepository) append(loan *Loan, event *audit.Event) error {
+	if event == nil {
+		return nil
+	}
+	event.LoanID, event.LoanVersion = loan.ID, loan.Version
+	return r.audit.Append(*event)
+}
+
+// Outbox returns the store holding messages queued by Update.
+func (r *InMemoryLoanRepository) Outbox() outbox.Store {
+	return r.outbox
+}
+
+// Audit returns the trail holding events appended by Create and Update.
+func (r *InMemoryLoanRepository) Audit() audit.Repository {
+	return r.audit
 }
 
 // List returns all loans in the store.
@@ -63,9 +129,79 @@ func (r *InMemoryLoanRepository) List() ([]*Loan, error) {
 	var result []*Loan
 	r.store.Range(func(_, val any) bool {
 		if loan, ok := val.(*Loan); ok {
-			result = append(result, loan)
+			result = append(result, loan.clone())
+		}
+		return true
+	})
+	return result, nil
+}
+
+// Search returns a page of the loans matching query.
+func (r *InMemoryLoanRepository) Search(query LoanQuery) (*LoanPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	var matching []*Loan
+	r.store.Range(func(_, val any) bool {
+		if loan, ok := val.(*Loan); ok && query.matches(loan) {
+			matching = append(matching, loan.clone())
 		}
 		return true
 	})
+	return query.paginate(matching)
+}
+
+// Totals sums up the stored loans by state and currency, ordered by state and currency.
+func (r *InMemoryLoanRepository) Totals() ([]LoanTotals, error) {
+	type key struct {
+		state    LoanState
+		currency money.Currency
+	}
+	sums := make(map[key]*LoanTotals)
+	r.store.Range(func(_, val any) bool {
+		loan, ok := val.(*Loan)
+		if !ok {
+			return true
+		}
+		k := key{loan.State, loan.PrincipalAmount.Currency()}
+		t, ok := sums[k]
+		if !ok {
+			zero := money.Zero(k.currency)
+			t = &LoanTotals{State: k.state, Currency: k.currency, Principal: zero, Invested: zero, Outstanding: zero}
+			sums[k] = t
+		}
+		t.Count++
+		t.Principal = t.Principal.Add(loan.PrincipalAmount)
+		t.Invested = t.Invested.Add(loan.TotalInvested)
+		if loan.Outstanding != nil {
+			t.Outstanding = t.Outstanding.Add(loan.Outstanding.Principal)
+		}
+		return true
+	})
+	totals := make([]LoanTotals, 0, len(sums))
+	for _, t := range sums {
+		totals = append(totals, *t)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].State != totals[j].State {
+			return totals[i].State < totals[j].State
+		}
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals, nil
+}
+
+// ListFundingOverdue returns approved loans whose funding deadline has passed at the given time.
+func (r *InMemoryLoanRepository) ListFundingOverdue(at time.Time) ([]*Loan, error) {
+	var result []*Loan
+	r.store.Range(func(_, val any) bool {
+		if loan, ok := val.(*Loan); ok && loan.fundingOverdue(at) {
+			result = append(result, loan.clone())
+		}
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FundingDeadline.Before(*result[j].FundingDeadline)
+	})
 	return result, nil
 }
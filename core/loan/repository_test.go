@@ -1,17 +1,174 @@
 package loan
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/audit"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
+	"loan-service/database"
 )
 
+// idr is a shorthand for whole-rupiah amounts in tests.
+func idr(major int64) money.Money {
+	return money.FromMajor(major, money.IDR)
+}
+
+// newSQLiteRepository returns a SQL repository backed by a fresh SQLite file in a temp dir.
+func newSQLiteRepository(t *testing.T) *SQLLoanRepository {
+	t.Helper()
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "loans.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewSQLLoanRepository(db)
+}
+
 func TestInMemoryLoanRepository(t *testing.T) {
-	repo := NewInMemoryLoanRepository()
+	testLoanRepository(t, NewInMemoryLoanRepository())
+}
+
+func TestSQLLoanRepository(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	testLoanRepository(t, repo)
+
+	t.Run("Round-trips approval, disbursement and investors", func(t *testing.T) {
+		approvedAt := time.Date(2025, 7, 22, 0, 0, 0, 0, time.UTC)
+		ln := &Loan{BorrowerID: "B003", PrincipalAmount: idr(3000), Rate: 10, ROI: 8}
+		require.NoError(t, repo.Create(ln, nil))
+
+		ln.State = Disbursed
+		ln.AgreementLetterURL = "https://agreement"
+		ln.Approval = &Approval{PhotoProofURL: "proof", ValidatorID: "EMP001", ApprovalDate: approvedAt}
+		ln.Disbursement = &Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO001", DisbursementDate: approvedAt}
+		ln.Investors = []Investor{{ID: "INV1", Amount: idr(1000), Status: Committed}, {ID: "INV2", Amount: idr(2000), Status: Committed}}
+		ln.TotalInvested = idr(3000)
+		require.NoError(t, repo.Update(ln, nil))
+
+		fetched, err := repo.GetByID(ln.ID)
+		require.NoError(t, err)
+		assert.Equal(t, Disbursed, fetched.State)
+		assert.Equal(t, "https://agreement", fetched.AgreementLetterURL)
+		assert.Equal(t, "EMP001", fetched.Approval.ValidatorID)
+		assert.True(t, approvedAt.Equal(fetched.Approval.ApprovalDate))
+		assert.Equal(t, "FO001", fetched.Disbursement.FieldOfficerID)
+		assert.Equal(t, ln.Investors, fetched.Investors)
+		assert.Equal(t, idr(3000), fetched.TotalInvested)
+		assert.Equal(t, idr(3000), fetched.PrincipalAmount)
+		assert.True(t, ln.CreatedAt.Equal(fetched.CreatedAt))
+	})
+
+	t.Run("Round-trips repayment terms and installments", func(t *testing.T) {
+		terms := RepaymentTerms{Method: EffectiveMethod, Tenor: 6, InstallmentFee: idr(500)}
+		ln := &Loan{BorrowerID: "B007", PrincipalAmount: idr(600000), Rate: 12, Terms: terms}
+		require.NoError(t, repo.Create(ln, nil))
+
+		insts, err := GenerateInstallments(ln.PrincipalAmount, ln.Rate, terms, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		paidAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		ln.Installments = insts
+		ln.Outstanding = &Balance{Fees: idr(2500), Interest: idr(10000), Principal: idr(600000)}
+		ln.Repayments = []Repayment{{
+			ID: "R1", Amount: idr(500), Fees: idr(500), Interest: idr(0), Principal: idr(0),
+			Reference: "TRX1", PaidAt: paidAt, RecordedAt: paidAt,
+		}}
+		require.NoError(t, repo.Update(ln, nil))
+
+		fetched, err := repo.GetByID(ln.ID)
+		require.NoError(t, err)
+		assert.Equal(t, terms, fetched.Terms)
+		require.Len(t, fetched.Installments, 6)
+		for i := range insts {
+			assert.True(t, insts[i].DueDate.Equal(fetched.Installments[i].DueDate))
+			fetched.Installments[i].DueDate = insts[i].DueDate
+		}
+		assert.Equal(t, insts, fetched.Installments)
+		assert.Equal(t, ln.Outstanding, fetched.Outstanding)
+		require.Len(t, fetched.Repayments, 1)
+		assert.Equal(t, "TRX1", fetched.Repayments[0].Reference)
+		assert.Equal(t, idr(500), fetched.Repayments[0].Fees)
+		assert.True(t, paidAt.Equal(fetched.Repayments[0].PaidAt))
+	})
+
+	t.Run("Update replaces investors", func(t *testing.T) {
+		ln := &Loan{BorrowerID: "B004", PrincipalAmount: idr(1000)}
+		require.NoError(t, repo.Create(ln, nil))
+
+		ln.Investors = []Investor{{ID: "INV1", Amount: idr(400)}, {ID: "INV2", Amount: idr(600)}}
+		require.NoError(t, repo.Update(ln, nil))
+		ln.Investors = ln.Investors[:1]
+		require.NoError(t, repo.Update(ln, nil))
+
+		fetched, err := repo.GetByID(ln.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []Investor{{ID: "INV1", Amount: idr(400), Status: Committed}}, fetched.Investors)
+	})
+
+	t.Run("Round-trips closure and released investors", func(t *testing.T) {
+		closedAt := time.Date(2025, 7, 30, 0, 0, 0, 0, time.UTC)
+		ln := &Loan{BorrowerID: "B008", PrincipalAmount: idr(1000)}
+		require.NoError(t, repo.Create(ln, nil))
+
+		ln.State = Cancelled
+		ln.Closure = &Closure{Reason: "duplicate application", StaffID: "EMP008", ClosedAt: closedAt}
+		ln.Investors = []Investor{{ID: "INV1", Amount: idr(400), Status: Released}}
+		require.NoError(t, repo.Update(ln, nil))
+
+		fetched, err := repo.GetByID(ln.ID)
+		require.NoError(t, err)
+		assert.Equal(t, Cancelled, fetched.State)
+		require.NotNil(t, fetched.Closure)
+		assert.Equal(t, "duplicate application", fetched.Closure.Reason)
+		assert.Equal(t, "EMP008", fetched.Closure.StaffID)
+		assert.True(t, closedAt.Equal(fetched.Closure.ClosedAt))
+		assert.Equal(t, Released, fetched.Investors[0].Status)
+	})
+}
+
+// testLoanRepository is the behaviour every LoanRepository implementation must satisfy.
+func TestLoanRepository_Totals(t *testing.T) {
+	for name, repo := range map[string]LoanRepository{
+		"in memory": NewInMemoryLoanRepository(),
+		"SQL":       newSQLiteRepository(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			totals, err := repo.Totals()
+			require.NoError(t, err)
+			assert.Empty(t, totals)
 
+			store := func(state LoanState, principal, invested money.Money, outstanding *Balance) {
+				ln := &Loan{BorrowerID: "B020", PrincipalAmount: principal}
+				require.NoError(t, repo.Create(ln, nil))
+				ln.State, ln.TotalInvested, ln.Outstanding = state, invested, outstanding
+				require.NoError(t, repo.Update(ln, nil))
+			}
+			usd := money.FromMajor(100, money.USD)
+			store(Approved, idr(1000), idr(250), nil)
+			store(Approved, idr(3000), idr(0), nil)
+			store(Approved, usd, money.Zero(money.USD), nil)
+			store(Disbursed, idr(2000), idr(2000), &Balance{Fees: idr(0), Interest: idr(100), Principal: idr(1500)})
+			store(Proposed, idr(500), idr(0), nil)
+
+			totals, err = repo.Totals()
+			require.NoError(t, err)
+			assert.Equal(t, []LoanTotals{
+				{State: Approved, Currency: money.IDR, Count: 2, Principal: idr(4000), Invested: idr(250), Outstanding: idr(0)},
+				{State: Approved, Currency: money.USD, Count: 1, Principal: usd, Invested: money.Zero(money.USD), Outstanding: money.Zero(money.USD)},
+				{State: Disbursed, Currency: money.IDR, Count: 1, Principal: idr(2000), Invested: idr(2000), Outstanding: idr(1500)},
+				{State: Proposed, Currency: money.IDR, Count: 1, Principal: idr(500), Invested: idr(0), Outstanding: idr(0)},
+			}, totals)
+		})
+	}
+}
+
+func testLoanRepository(t *testing.T, repo LoanRepository) {
 	t.Run("Create and GetByID", func(t *testing.T) {
-		ln := &Loan{BorrowerID: "B001", PrincipalAmount: 12345}
-		err := repo.Create(ln)
+		ln := &Loan{BorrowerID: "B001", PrincipalAmount: idr(12345)}
+		err := repo.Create(ln, nil)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, ln.ID)
 
@@ -26,10 +183,10 @@ func TestInMemoryLoanRepository(t *testing.T) {
 	})
 
 	t.Run("Update existing loan", func(t *testing.T) {
-		ln := &Loan{BorrowerID: "B002", PrincipalAmount: 1000}
-		_ = repo.Create(ln)
+		ln := &Loan{BorrowerID: "B002", PrincipalAmount: idr(1000)}
+		_ = repo.Create(ln, nil)
 		ln.Rate = 99
-		err := repo.Update(ln)
+		err := repo.Update(ln, nil)
 		assert.NoError(t, err)
 
 		updated, _ := repo.GetByID(ln.ID)
@@ -37,13 +194,108 @@ func TestInMemoryLoanRepository(t *testing.T) {
 	})
 
 	t.Run("Update non-existent loan", func(t *testing.T) {
-		err := repo.Update(&Loan{ID: "fake-id"})
+		err := repo.Update(&Loan{ID: "fake-id"}, nil)
 		assert.Error(t, err)
 	})
 
+	t.Run("Update bumps version", func(t *testing.T) {
+		ln := &Loan{BorrowerID: "B005", PrincipalAmount: idr(1000)}
+		assert.NoError(t, repo.Create(ln, nil))
+		assert.Equal(t, int64(1), ln.Version)
+
+		assert.NoError(t, repo.Update(ln, nil))
+		assert.Equal(t, int64(2), ln.Version)
+
+		fetched, _ := repo.GetByID(ln.ID)
+		assert.Equal(t, int64(2), fetched.Version)
+	})
+
+	t.Run("Update with stale version conflicts", func(t *testing.T) {
+		ln := &Loan{BorrowerID: "B006", PrincipalAmount: idr(1000)}
+		assert.NoError(t, repo.Create(ln, nil))
+
+		first, _ := repo.GetByID(ln.ID)
+		second, _ := repo.GetByID(ln.ID)
+
+		first.Rate = 5
+		assert.NoError(t, repo.Update(first, nil))
+
+		second.Rate = 7
+		err := repo.Update(second, nil)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		var conflict *ConflictError
+		if assert.ErrorAs(t, err, &conflict) {
+			assert.Equal(t, int64(1), conflict.Expected)
+			assert.Equal(t, int64(2), conflict.Actual)
+		}
+
+		stored, _ := repo.GetByID(ln.ID)
+		assert.Equal(t, 5.0, stored.Rate)
+	})
+
 	t.Run("List all loans", func(t *testing.T) {
 		list, err := repo.List()
 		assert.NoError(t, err)
 		assert.GreaterOrEqual(t, len(list), 1)
 	})
+	t.Run("List loans overdue for funding", func(t *testing.T) {
+		at := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+		withDeadline := func(state LoanState, deadline time.Time) *Loan {
+			ln := &Loan{BorrowerID: "B009", PrincipalAmount: idr(1000)}
+			require.NoError(t, repo.Create(ln, nil))
+			ln.State = state
+			ln.FundingDeadline = &deadline
+			require.NoError(t, repo.Update(ln, nil))
+			return ln
+		}
+		later := withDeadline(Approved, at)
+		earlier := withDeadline(Approved, at.Add(-time.Hour))
+		withDeadline(Approved, at.Add(time.Second))
+		withDeadline(Invested, at.Add(-time.Hour))
+
+		overdue, err := repo.ListFundingOverdue(at)
+		require.NoError(t, err)
+		require.Len(t, overdue, 2)
+		assert.Equal(t, earlier.ID, overdue[0].ID)
+		assert.Equal(t, later.ID, overdue[1].ID)
+		assert.True(t, at.Equal(*overdue[1].FundingDeadline))
+	})
+	t.Run("Update stores outbox messages with the loan", func(t *testing.T) {
+		ln := &Loan{BorrowerID: "B010", PrincipalAmount: idr(1000)}
+		require.NoError(t, repo.Create(ln, nil))
+		stale, _ := repo.GetByID(ln.ID)
+
+		sent, err := outbox.New("test.sent", map[string]string{"loan_id": ln.ID}, time.Now())
+		require.NoError(t, err)
+		require.NoError(t, repo.Update(ln, nil, sent))
+
+		lost, err := outbox.New("test.lost", map[string]string{"loan_id": ln.ID}, time.Now())
+		require.NoError(t, err)
+		assert.ErrorIs(t, repo.Update(stale, nil, lost), ErrVersionConflict)
+
+		_, err = repo.Outbox().Get(sent.ID)
+		assert.NoError(t, err)
+		_, err = repo.Outbox().Get(lost.ID)
+		assert.ErrorIs(t, err, outbox.ErrMessageNotFound, "rolled back together with the loan")
+	})
+	t.Run("Create and Update append audit events with the loan", func(t *testing.T) {
+		ln := &Loan{BorrowerID: "B011", PrincipalAmount: idr(1000)}
+		require.NoError(t, repo.Create(ln, &audit.Event{ID: "E1", Action: audit.Created, NewState: string(Proposed)}))
+		stale, _ := repo.GetByID(ln.ID)
+
+		ln.State = Approved
+		require.NoError(t, repo.Update(ln, &audit.Event{ID: "E2", Action: audit.Approved, NewState: string(Approved)}))
+		stale.State = Rejected
+		assert.ErrorIs(t, repo.Update(stale, &audit.Event{ID: "E3", Action: audit.Rejected}), ErrVersionConflict)
+
+		events, err := repo.Audit().ListByLoan(ln.ID)
+		require.NoError(t, err)
+		require.Len(t, events, 2, "the event of the rejected update is rolled back with it")
+		assert.Equal(t, "E1", events[0].ID)
+		assert.Equal(t, int64(1), events[0].LoanVersion)
+		assert.Equal(t, "E2", events[1].ID)
+		assert.Equal(t, ln.ID, events[1].LoanID)
+		assert.Equal(t, int64(2), events[1].LoanVersion)
+	})
 }
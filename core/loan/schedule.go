@@ -0,0 +1,245 @@
+package loan
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"loan-service/core/money"
+)
+
+// RepaymentMethod selects how a loan's installments are computed.
+type RepaymentMethod string
+
+const (
+	// FlatMethod charges interest on the original principal and repays it in equal monthly installments.
+	FlatMethod RepaymentMethod = "flat"
+
+	// EffectiveMethod charges interest on the outstanding balance with equal monthly installments (annuity).
+	EffectiveMethod RepaymentMethod = "effective"
+
+	// WeeklyMethod charges flat interest and repays it in equal weekly installments.
+	WeeklyMethod RepaymentMethod = "weekly"
+)
+
+// MaxTenor caps the number of installments a loan can be repaid in.
+const MaxTenor = 520
+
+// MaxRate caps the yearly interest rate and the ROI of a loan, in %.
+const MaxRate = 1000
+
+const (
+	monthsPerYear = 12
+	weeksPerYear  = 52
+)
+
+// RepaymentTerms describes how the borrower repays the loan. Rate is read as a yearly percentage.
+type RepaymentTerms struct {
+	Method         RepaymentMethod `json:"repayment_method"` // How installments are computed
+	Tenor          int             `json:"tenor"`            // Number of installments (months, or weeks for WeeklyMethod)
+	InstallmentFee money.Money     `json:"installment_fee"`  // Flat fee added to every installment (zero if none)
+}
+
+// DefaultRepaymentTerms are used when a loan is created without explicit terms.
+var DefaultRepaymentTerms = RepaymentTerms{Method: FlatMethod, Tenor: 12}
+
+// Validate checks the terms are complete and supported.
+func (t RepaymentTerms) Validate() error {
+	switch t.Method {
+	case FlatMethod, EffectiveMethod, WeeklyMethod:
+	default:
+		return fmt.Errorf("unsupported repayment method: %q", t.Method)
+	}
+	if t.Tenor < 1 || t.Tenor > MaxTenor {
+		return fmt.Errorf("tenor must be between 1 and %d installments", MaxTenor)
+	}
+	if t.InstallmentFee.IsNegative() {
+		return errors.New("installment fee must not be negative")
+	}
+	return nil
+}
+
+// Installment is a single scheduled repayment.
+type Installment struct {
+	Number    int         `json:"number"`    // 1-based position in the schedule
+	DueDate   time.Time   `json:"due_date"`  // Date the installment is due
+	Principal money.Money `json:"principal"` // Principal part of the installment
+	Interest  money.Money `json:"interest"`  // Interest part of the installment
+	Fee       money.Money `json:"fee"`       // Fee part of the installment
+	Amount    money.Money `json:"amount"`    // Principal + interest + fee
+	Balance   money.Money `json:"balance"`   // Principal still outstanding after this installment
+}
+
+// Schedule is the repayment plan of a disbursed loan.
+type Schedule struct {
+	LoanID         string         `json:"loan_id"`
+	Terms          RepaymentTerms `json:"terms"`
+	Rate           float64        `json:"rate"`
+	Installments   []Installment  `json:"installments"`
+	TotalPrincipal money.Money    `json:"total_principal"`
+	TotalInterest  money.Money    `json:"total_interest"`
+	TotalFees      money.Money    `json:"total_fees"`
+	TotalAmount    money.Money    `json:"total_amount"`
+}
+
+// ErrScheduleNotAvailable is returned when asking for the schedule of a loan that has not been disbursed yet.
+var ErrScheduleNotAvailable = errors.New("repayment schedule is only available once the loan is disbursed")
+
+// GenerateInstallments builds the amortization schedule for a principal at a yearly rate (in %),
+// with the first installment due one period after start.
+//
+// All amounts are exact: interest is rounded half to even to the minor unit, and the principal
+// parts always add up to the principal. Flat and weekly schedules spread principal and interest
+// evenly, giving leftover minor units to the earliest installments. Effective schedules use
+// a rounded annuity payment and let the last installment absorb the rounding difference.
+func GenerateInstallments(principal money.Money, rate float64, terms RepaymentTerms, start time.Time) ([]Installment, error) {
+	if err := terms.Validate(); err != nil {
+		return nil, err
+	}
+	if principal.IsNegative() {
+		return nil, errors.New("principal amount must not be negative")
+	}
+	if !terms.InstallmentFee.SameCurrency(principal) {
+		return nil, errors.New("installment fee must be in the loan currency")
+	}
+	if rate < 0 {
+		return nil, errors.New("rate must not be negative")
+	}
+
+	var (
+		principals []money.Money
+		interests  []money.Money
+		err        error
+	)
+	switch terms.Method {
+	case FlatMethod:
+		principals, interests, err = flatSplit(principal, rate, terms.Tenor, monthsPerYear)
+	case WeeklyMethod:
+		principals, interests, err = flatSplit(principal, rate, terms.Tenor, weeksPerYear)
+	case EffectiveMethod:
+		principals, interests, err = annuitySplit(principal, rate, terms.Tenor)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("interest is too large: %w", err)
+	}
+
+	// Every part is at most the total, so once the total is known to fit, no sum of parts can overflow
+	fee := money.New(terms.InstallmentFee.MinorUnits(), principal.Currency())
+	installments := make([]Installment, terms.Tenor)
+	balance := principal
+	total := money.Zero(principal.Currency())
+	for i := range installments {
+		amount, err := principals[i].CheckedAdd(interests[i])
+		if err == nil {
+			amount, err = amount.CheckedAdd(fee)
+		}
+		if err == nil {
+			total, err = total.CheckedAdd(amount)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("installments are too large: %w", err)
+		}
+		balance = balance.Sub(principals[i])
+		installments[i] = Installment{
+			Number:    i + 1,
+			DueDate:   dueDate(start, terms.Method, i+1),
+			Principal: principals[i],
+			Interest:  interests[i],
+			Fee:       fee,
+			Amount:    amount,
+			Balance:   balance,
+		}
+	}
+	return installments, nil
+}
+
+// newSchedule summarises the stored installments of a loan.
+func newSchedule(loan *Loan) *Schedule {
+	currency := loan.PrincipalAmount.Currency()
+	s := &Schedule{
+		LoanID:         loan.ID,
+		Terms:          loan.Terms,
+		Rate:           loan.Rate,
+		Installments:   loan.Installments,
+		TotalPrincipal: money.Zero(currency),
+		TotalInterest:  money.Zero(currency),
+		TotalFees:      money.Zero(currency),
+		TotalAmount:    money.Zero(currency),
+	}
+	for _, inst := range loan.Installments {
+		s.TotalPrincipal = s.TotalPrincipal.Add(inst.Principal)
+		s.TotalInterest = s.TotalInterest.Add(inst.Interest)
+		s.TotalFees = s.TotalFees.Add(inst.Fee)
+		s.TotalAmount = s.TotalAmount.Add(inst.Amount)
+	}
+	return s
+}
+
+// flatSplit charges interest on the full principal for the whole tenor and spreads both evenly.
+func flatSplit(principal money.Money, rate float64, tenor, periodsPerYear int) (principals, interests []money.Money, err error) {
+	// total interest = principal × rate/100 × tenor/periodsPerYear
+	factor := new(big.Rat).Mul(money.PercentRat(rate), big.NewRat(int64(tenor), int64(periodsPerYear)))
+	totalInterest, err := principal.CheckedMulRat(factor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	weights := make([]int64, tenor)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return principal.Allocate(weights), totalInterest.Allocate(weights), nil
+}
+
+// annuitySplit computes equal monthly payments with interest on the declining balance.
+func annuitySplit(principal money.Money, rate float64, tenor int) (principals, interests []money.Money, err error) {
+	periodRate := new(big.Rat).Quo(money.PercentRat(rate), big.NewRat(monthsPerYear, 1))
+
+	// payment = P × r / (1 − (1 + r)^−n); with r = 0 it degenerates into P / n.
+	var payment money.Money
+	if periodRate.Sign() == 0 {
+		payment = principal.MulRat(big.NewRat(1, int64(tenor))) // A share of the principal always fits
+	} else {
+		growth := new(big.Rat).SetInt64(1)
+		onePlusR := new(big.Rat).Add(big.NewRat(1, 1), periodRate)
+		for i := 0; i < tenor; i++ {
+			growth.Mul(growth, onePlusR)
+		}
+		discount := new(big.Rat).Sub(big.NewRat(1, 1), new(big.Rat).Inv(growth))
+		if payment, err = principal.CheckedMulRat(new(big.Rat).Quo(periodRate, discount)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	principals = make([]money.Money, tenor)
+	interests = make([]money.Money, tenor)
+	balance := principal
+	for i := 0; i < tenor; i++ {
+		// The interest of a period is less than the payment, so it fits whenever the payment does
+		interests[i] = balance.MulRat(periodRate)
+		if i == tenor-1 {
+			principals[i] = balance
+		} else {
+			principals[i] = payment.Sub(interests[i])
+		}
+		balance = balance.Sub(principals[i])
+	}
+	return principals, interests, nil
+}
+
+// dueDate returns the due date of the n-th installment.
+// Monthly dates keep the start day, clamped to the end of shorter months (Jan 31 → Feb 28 → Mar 31).
+func dueDate(start time.Time, method RepaymentMethod, n int) time.Time {
+	if method == WeeklyMethod {
+		return start.AddDate(0, 0, 7*n)
+	}
+
+	y, m, d := start.Date()
+	firstOfMonth := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, start.Location())
+	if last := firstOfMonth.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	hh, mm, ss := start.Clock()
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), d, hh, mm, ss, start.Nanosecond(), start.Location())
+}
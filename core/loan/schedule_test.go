@@ -0,0 +1,105 @@
+package loan
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+)
+
+func TestGenerateInstallments(t *testing.T) {
+	start := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Flat monthly", func(t *testing.T) {
+		// 1,000,000 at 12% p.a. over 12 months → 120,000 interest, 10,000 per month.
+		insts, err := GenerateInstallments(idr(1000000), 12, RepaymentTerms{Method: FlatMethod, Tenor: 12}, start)
+		require.NoError(t, err)
+		require.Len(t, insts, 12)
+
+		assert.Equal(t, money.MustParse("83333.34", money.IDR), insts[0].Principal)
+		assert.Equal(t, money.MustParse("83333.33", money.IDR), insts[11].Principal)
+		assert.Equal(t, idr(10000), insts[0].Interest)
+		assert.Equal(t, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), insts[0].DueDate, "clamped to month end")
+		assert.Equal(t, time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC), insts[1].DueDate)
+		assert.Equal(t, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), insts[11].DueDate)
+		assert.Equal(t, 1, insts[0].Number)
+		assertScheduleTotals(t, insts, idr(1000000), idr(120000))
+	})
+
+	t.Run("Weekly flat", func(t *testing.T) {
+		// 500,000 at 26% p.a. over 50 weeks → 500,000 × 0.26 × 50/52 = 125,000 interest.
+		insts, err := GenerateInstallments(idr(500000), 26, RepaymentTerms{Method: WeeklyMethod, Tenor: 50}, start)
+		require.NoError(t, err)
+		require.Len(t, insts, 50)
+
+		assert.Equal(t, idr(10000), insts[0].Principal)
+		assert.Equal(t, idr(2500), insts[0].Interest)
+		assert.Equal(t, start.AddDate(0, 0, 7), insts[0].DueDate)
+		assert.Equal(t, start.AddDate(0, 0, 350), insts[49].DueDate)
+		assertScheduleTotals(t, insts, idr(500000), idr(125000))
+	})
+
+	t.Run("Effective annuity", func(t *testing.T) {
+		// 1,000,000 at 12% p.a. → 1% per month; the annuity payment is 88,848.79.
+		insts, err := GenerateInstallments(idr(1000000), 12, RepaymentTerms{Method: EffectiveMethod, Tenor: 12}, start)
+		require.NoError(t, err)
+		require.Len(t, insts, 12)
+
+		assert.Equal(t, idr(10000), insts[0].Interest)
+		assert.Equal(t, money.MustParse("88848.79", money.IDR), insts[0].Amount)
+		assert.Equal(t, money.MustParse("88848.79", money.IDR), insts[5].Amount)
+		assert.True(t, insts[11].Interest.Cmp(insts[0].Interest) < 0, "interest declines with the balance")
+		assert.True(t, insts[11].Balance.IsZero())
+
+		var totalPrincipal = money.Zero(money.IDR)
+		for _, inst := range insts {
+			totalPrincipal = totalPrincipal.Add(inst.Principal)
+		}
+		assert.Equal(t, idr(1000000), totalPrincipal)
+	})
+
+	t.Run("Zero rate", func(t *testing.T) {
+		insts, err := GenerateInstallments(idr(1200), 0, RepaymentTerms{Method: EffectiveMethod, Tenor: 12}, start)
+		require.NoError(t, err)
+		assertScheduleTotals(t, insts, idr(1200), idr(0))
+	})
+
+	t.Run("Invalid terms", func(t *testing.T) {
+		_, err := GenerateInstallments(idr(1000), 10, RepaymentTerms{Method: "balloon", Tenor: 12}, start)
+		assert.Error(t, err)
+		_, err = GenerateInstallments(idr(1000), 10, RepaymentTerms{Method: FlatMethod, Tenor: 0}, start)
+		assert.Error(t, err)
+		_, err = GenerateInstallments(idr(1000), -1, RepaymentTerms{Method: FlatMethod, Tenor: 12}, start)
+		assert.Error(t, err)
+	})
+
+	t.Run("Amounts that do not fit fail instead of panicking", func(t *testing.T) {
+		largest := money.New(math.MaxInt64, money.IDR)
+		for _, method := range []RepaymentMethod{FlatMethod, WeeklyMethod, EffectiveMethod} {
+			_, err := GenerateInstallments(largest, 100, RepaymentTerms{Method: method, Tenor: 12}, start)
+			assert.ErrorIs(t, err, money.ErrOverflow, method)
+		}
+
+		_, err := GenerateInstallments(idr(1000), 0, RepaymentTerms{Method: FlatMethod, Tenor: 12, InstallmentFee: largest}, start)
+		assert.ErrorIs(t, err, money.ErrOverflow, "a fee that fits alone")
+		_, err = GenerateInstallments(money.New(math.MaxInt64/2, money.IDR), 0,
+			RepaymentTerms{Method: FlatMethod, Tenor: 1, InstallmentFee: money.New(math.MaxInt64/2+2, money.IDR)}, start)
+		assert.ErrorIs(t, err, money.ErrOverflow, "principal and fee that fit alone")
+	})
+}
+
+func assertScheduleTotals(t *testing.T, insts []Installment, principal, interest money.Money) {
+	t.Helper()
+	totalPrincipal, totalInterest := money.Zero(money.IDR), money.Zero(money.IDR)
+	for _, inst := range insts {
+		totalPrincipal = totalPrincipal.Add(inst.Principal)
+		totalInterest = totalInterest.Add(inst.Interest)
+		assert.Equal(t, inst.Principal.Add(inst.Interest), inst.Amount)
+	}
+	assert.Equal(t, principal, totalPrincipal)
+	assert.Equal(t, interest, totalInterest)
+	assert.True(t, insts[len(insts)-1].Balance.IsZero())
+}
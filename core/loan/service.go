@@ -1,49 +1,153 @@
 package loan
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"loan-service/core/audit"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
 )
 
 // EmailSender defines the interface for sending email notifications.
 // You can implement this using SMTP, external APIs, or mock logs.
+//
+// There is one method per lifecycle event. Each call sends a single email to the event's Recipient;
+// the service queues one event per recipient in the outbox (see DeliverNotification).
 type EmailSender interface {
-	SendInvestorNotification(investorID, agreementLink string) error
+	SendLoanApproved(event LoanApproved) error
+	SendInvestmentReceived(event InvestmentReceived) error
+	SendLoanFunded(event LoanFunded) error
+	SendLoanDisbursed(event LoanDisbursed) error
+	SendFundingExpired(event FundingExpired) error
 }
 
 // LoanService provides core logic for managing loan lifecycle operations.
+//
+// Every operation that changes an existing loan runs its read-check-write sequence
+// while holding a per-loan lock, so concurrent requests against the same loan
+// (e.g. several investors funding it at once) are applied one after another.
 type LoanService struct {
-	repo  LoanRepository
-	email EmailSender
+	repo       LoanRepository
+	email      EmailSender
+	payouts    PayoutRepository
+	events     EventPublisher
+	investors  InvestorRegistry
+	borrowers  BorrowerRegistry
+	documents  DocumentStore
+	agreements AgreementGenerator
+	now        func() time.Time
+	locks      keyedMutex
 }
 
 // NewLoanService creates a new instance of LoanService.
-func NewLoanService(repo LoanRepository, email EmailSender) *LoanService {
-	return &LoanService{
-		repo:  repo,
-		email: email,
+func NewLoanService(repo LoanRepository, email EmailSender, opts ...ServiceOption) *LoanService {
+	s := &LoanService{
+		repo:    repo,
+		email:   email,
+		payouts: NewInMemoryPayoutRepository(),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// CreateLoan creates a new loan with the given parameters.
-func (s *LoanService) CreateLoan(borrowerID string, principal float64, rate float64, roi float64) (*Loan, error) {
+// CreateLoan creates a new loan with the given parameters. Invalid parameters fail with an error matching ErrInvalidLoan.
+// The currency of the principal becomes the loan currency (money.DefaultCurrency if it has none).
+// Repayment terms can be set with WithRepaymentTerms.
+// With a borrower registry the borrower must be registered and eligible for the loan.
+func (s *LoanService) CreateLoan(borrowerID string, principal money.Money, rate float64, roi float64, opts ...Option) (*Loan, error) {
+	if !principal.IsPositive() {
+		return nil, fmt.Errorf("%w: principal amount must be positive", ErrInvalidLoan)
+	}
+	if err := checkRate("rate", rate); err != nil {
+		return nil, err
+	}
+	if err := checkRate("roi", roi); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	terms := DefaultRepaymentTerms
+	if o.terms != nil {
+		terms = *o.terms
+	}
+	currency := principal.Currency()
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if err := terms.Validate(); err != nil {
+		return nil, err
+	}
+	if c := terms.InstallmentFee.Currency(); c != "" && c != currency {
+		return nil, fmt.Errorf("installment fee currency %s does not match loan currency %s", c, currency)
+	}
+	terms.InstallmentFee = money.New(terms.InstallmentFee.MinorUnits(), currency)
+	principal = money.New(principal.MinorUnits(), currency)
+	// Disbursement generates the schedule for good; make sure it can, before any investor commits money
+	if _, err := GenerateInstallments(principal, rate, terms, s.now()); err != nil {
+		return nil, fmt.Errorf("%w: repayment schedule: %w", ErrInvalidLoan, err)
+	}
+
+	if s.borrowers != nil {
+		unlock := s.locks.Lock(borrowerLock(borrowerID))
+		defer unlock()
+		if err := s.checkBorrower(borrowerID, principal); err != nil {
+			return nil, err
+		}
+	}
+
 	loan := &Loan{
 		BorrowerID:      borrowerID,
 		PrincipalAmount: principal,
 		Rate:            rate,
 		ROI:             roi,
+		TotalInvested:   money.Zero(currency),
+		Terms:           terms,
+		State:           Proposed,
+	}
+	event, err := s.auditEvent(loan, change{action: audit.Created, actor: o.actorOr(borrowerID), payload: map[string]any{
+		"borrower_id": borrowerID, "principal_amount": loan.PrincipalAmount, "rate": rate, "roi": roi, "repayment_terms": terms,
+	}})
+	if err != nil {
+		return nil, err
 	}
-	if err := s.repo.Create(loan); err != nil {
+	if err := s.repo.Create(loan, event); err != nil {
 		return nil, err
 	}
 	return loan, nil
 }
 
+// checkRate rejects a yearly percentage that is negative, not a number or over MaxRate. Such a rate would
+// only fail once the loan is funded, when the repayment schedule is generated at disbursement.
+func checkRate(name string, pct float64) error {
+	if math.IsNaN(pct) || pct < 0 || pct > MaxRate {
+		return fmt.Errorf("%w: %s must be between 0 and %d%%", ErrInvalidLoan, name, MaxRate)
+	}
+	return nil
+}
+
 // ApproveLoan moves a loan to Approved state after validating the input data.
-func (s *LoanService) ApproveLoan(loanID string, approval Approval) (*Loan, error) {
+// WithFundingDeadline sets when the loan expires if it is not fully funded by then.
+// With a document store the photo proof must be the ID of an uploaded document.
+// With an agreement generator the agreement letter is generated and linked before the borrower is notified.
+func (s *LoanService) ApproveLoan(loanID string, approval Approval, opts ...Option) (_ *Loan, err error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
 	loan, err := s.repo.GetByID(loanID)
 	if err != nil {
 		return nil, err
 	}
+	o := newOptions(opts)
+	if err := o.checkVersion(loan); err != nil {
+		return nil, err
+	}
 
 	if err := ValidateTransition(loan.State, Approved); err != nil {
 		return nil, err
@@ -53,55 +157,375 @@ func (s *LoanService) ApproveLoan(loanID string, approval Approval) (*Loan, erro
 	if approval.PhotoProofURL == "" || approval.ValidatorID == "" || approval.ApprovalDate.IsZero() {
 		return nil, errors.New("missing approval fields")
 	}
+	if err := s.checkDocuments(documentRef{"photo_proof_url", approval.PhotoProofURL}); err != nil {
+		return nil, err
+	}
+
+	if deadline := o.fundingDeadline; deadline != nil {
+		if !deadline.After(s.now()) {
+			return nil, errors.New("funding deadline must be in the future")
+		}
+		loan.FundingDeadline = deadline
+	}
 
+	c := change{action: audit.Approved, actor: o.actorOr(approval.ValidatorID), from: loan.State, payload: map[string]any{
+		"approval": approval, "funding_deadline": o.fundingDeadline,
+	}}
 	loan.State = Approved
 	loan.Approval = &approval
+	if err := s.writeAgreement(loan); err != nil {
+		return nil, err
+	}
+	defer func(link string) {
+		if err != nil {
+			s.discardAgreement(link)
+		}
+	}(loan.AgreementLetterURL)
 
-	return s.updateLoan(loan)
+	messages, err := s.notify(ApprovedNotification, LoanApproved{notification(loan, borrower(loan))})
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.publish(loan, nil, ApprovedEvent)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateLoan(loan, c, append(messages, events...)...)
 }
 
-// InvestLoan adds a new investor to a loan. If fully funded, it moves to Invested state and sends notifications.
-func (s *LoanService) InvestLoan(loanID string, investor Investor) (*Loan, error) {
+// InvestLoan adds a new investor to a loan and queues a confirmation for them. If the loan is now
+// fully funded, it moves to Invested state and the approving staff and every investor are notified.
+// With an InvestorRegistry, the investment must also pass its checks (see WithInvestorRegistry).
+func (s *LoanService) InvestLoan(loanID string, investor Investor, opts ...Option) (_ *Loan, err error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
 	loan, err := s.repo.GetByID(loanID)
 	if err != nil {
 		return nil, err
 	}
+	o := newOptions(opts)
+	if err := o.checkVersion(loan); err != nil {
+		return nil, err
+	}
 
 	if loan.State != Approved && loan.State != Invested {
-		return nil, errors.New("loan must be in approved or invested state to accept investments")
+		return nil, ErrNotOpenForInvestment
 	}
 
-	// Check if adding this investment exceeds principal
-	if loan.TotalInvested+investor.Amount > loan.PrincipalAmount {
-		return nil, errors.New("investment exceeds loan principal")
+	if !investor.Amount.IsPositive() {
+		return nil, errors.New("investment amount must be positive")
+	}
+	if investor.Amount.Currency() != loan.PrincipalAmount.Currency() {
+		return nil, fmt.Errorf("investment currency %s does not match loan currency %s",
+			investor.Amount.Currency(), loan.PrincipalAmount.Currency())
 	}
 
+	// Check against what is left before adding, so an absurd amount cannot overflow the total
+	if investor.Amount.Cmp(loan.PrincipalAmount.Sub(loan.TotalInvested)) > 0 {
+		return nil, ErrOverInvestment
+	}
+	total := loan.TotalInvested.Add(investor.Amount)
+	if s.investors != nil {
+		unlockInvestor := s.locks.Lock(investorLock(investor.ID))
+		defer unlockInvestor()
+		if err := s.checkInvestor(loan, investor); err != nil {
+			return nil, err
+		}
+	}
+
+	c := change{action: audit.Invested, actor: o.actorOr(investor.ID), from: loan.State, payload: investor}
+
 	// Add investor
+	investor.Status = Committed
 	loan.Investors = append(loan.Investors, investor)
-	loan.TotalInvested += investor.Amount
+	loan.TotalInvested = total
 
-	// Move to Invested if fully funded
-	if loan.TotalInvested == loan.PrincipalAmount {
+	// Move to Invested if fully funded. Amounts are exact, so equality is reliable.
+	funded := loan.TotalInvested.Equal(loan.PrincipalAmount)
+	if funded {
 		if err := ValidateTransition(loan.State, Invested); err != nil {
 			return nil, err
 		}
 		loan.State = Invested
+		// Reissue the agreement letter with the final list of investors. Once the loan is stored, the
+		// approval letter is superseded and dropped.
+		previous := loan.AgreementLetterURL
+		if err := s.writeAgreement(loan); err != nil {
+			return nil, err
+		}
+		defer func(link string) {
+			if err != nil {
+				s.discardAgreement(link)
+			} else if previous != link {
+				s.discardAgreement(previous)
+			}
+		}(loan.AgreementLetterURL)
+	}
+
+	// Notifications are sent once the loan update is committed
+	messages, err := s.notify(InvestmentNotification, InvestmentReceived{
+		Notification: notification(loan, Recipient{Role: InvestorRecipient, ID: investor.ID}),
+		Amount:       investor.Amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if funded {
+		var events []any
+		for _, to := range append([]Recipient{{Role: StaffRecipient, ID: loan.Approval.ValidatorID}}, investors(loan)...) {
+			events = append(events, LoanFunded{notification(loan, to)})
+		}
+		fundedMessages, err := s.notify(FundedNotification, events...)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, fundedMessages...)
+	}
+
+	types := []EventType{InvestedEvent}
+	if funded {
+		types = append(types, FundedEvent)
+	}
+	events, err := s.publish(loan, &investor, types...)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateLoan(loan, c, append(messages, events...)...)
+}
+
+// WithdrawInvestment takes back everything an investor has committed to a loan that is still Approved.
+// The investments stay on record with status Withdrawn.
+func (s *LoanService) WithdrawInvestment(loanID, investorID string, opts ...Option) (*Loan, error) {
+	return s.reduceInvestment(loanID, investorID, nil, opts)
+}
+
+// ReduceInvestment lowers an investor's commitment to a loan that is still Approved by amount.
+// Reducing by the whole commitment is the same as withdrawing it.
+func (s *LoanService) ReduceInvestment(loanID, investorID string, amount money.Money, opts ...Option) (*Loan, error) {
+	return s.reduceInvestment(loanID, investorID, &amount, opts)
+}
+
+// reduceInvestment withdraws amount (everything if nil) from the investor's commitments, newest first.
+// Investments taken back in full are marked Withdrawn; for a partial one, the taken part is split off
+// into a new Withdrawn entry so the history of what was invested is kept.
+func (s *LoanService) reduceInvestment(loanID, investorID string, amount *money.Money, opts []Option) (*Loan, error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
+	loan, err := s.repo.GetByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	if err := o.checkVersion(loan); err != nil {
+		return nil, err
+	}
 
-		// Notify all investors
-		for _, inv := range loan.Investors {
-			_ = s.email.SendInvestorNotification(inv.ID, loan.AgreementLetterURL)
+	if loan.State != Approved {
+		return nil, errors.New("investments can only be withdrawn while the loan is approved")
+	}
+
+	currency := loan.PrincipalAmount.Currency()
+	committed := money.Zero(currency)
+	for _, inv := range loan.Investors {
+		if inv.ID == investorID && inv.isCommitted() {
+			committed = committed.Add(inv.Amount)
 		}
 	}
+	if committed.IsZero() {
+		return nil, fmt.Errorf("%w: investor %s in loan %s", ErrInvestmentNotFound, investorID, loanID)
+	}
 
-	return s.updateLoan(loan)
+	remaining := committed
+	if amount != nil {
+		if !amount.IsPositive() {
+			return nil, errors.New("reduction amount must be positive")
+		}
+		if amount.Currency() != currency {
+			return nil, fmt.Errorf("reduction currency %s does not match loan currency %s", amount.Currency(), currency)
+		}
+		if amount.Cmp(committed) > 0 {
+			return nil, fmt.Errorf("reduction of %s exceeds committed amount of %s", *amount, committed)
+		}
+		remaining = *amount
+	}
+
+	for i := len(loan.Investors) - 1; i >= 0 && remaining.IsPositive(); i-- {
+		inv := &loan.Investors[i]
+		if inv.ID != investorID || !inv.isCommitted() {
+			continue
+		}
+		if inv.Amount.Cmp(remaining) <= 0 {
+			inv.Status = Withdrawn
+			remaining = remaining.Sub(inv.Amount)
+			continue
+		}
+		inv.Amount = inv.Amount.Sub(remaining)
+		loan.Investors = append(loan.Investors, Investor{ID: investorID, Amount: remaining, Status: Withdrawn})
+		remaining = money.Zero(currency)
+	}
+
+	loan.TotalInvested = money.Zero(currency)
+	for _, inv := range loan.Investors {
+		if inv.isCommitted() {
+			loan.TotalInvested = loan.TotalInvested.Add(inv.Amount)
+		}
+	}
+
+	c := change{action: audit.InvestmentWithdrawn, actor: o.actorOr(investorID), from: loan.State,
+		payload: map[string]any{"investor_id": investorID, "amount": committed}}
+	if amount != nil {
+		c.action = audit.InvestmentReduced
+		c.payload = map[string]any{"investor_id": investorID, "amount": *amount}
+	}
+	return s.updateLoan(loan, c)
+}
+
+// RejectLoan turns down a proposed loan. Both a reason and the rejecting staff member are required.
+func (s *LoanService) RejectLoan(loanID string, closure Closure, opts ...Option) (*Loan, error) {
+	if closure.Reason == "" || closure.StaffID == "" {
+		return nil, errors.New("missing rejection fields")
+	}
+	return s.closeLoan(loanID, Rejected, closure, opts)
+}
+
+// CancelLoan withdraws a proposed or approved loan on behalf of a staff member.
+// Any investments already made in a partially funded loan are released.
+func (s *LoanService) CancelLoan(loanID string, closure Closure, opts ...Option) (*Loan, error) {
+	if closure.StaffID == "" {
+		return nil, errors.New("missing cancellation fields")
+	}
+	return s.closeLoan(loanID, Cancelled, closure, opts)
+}
+
+// ExpireLoan closes an approved loan that did not get fully funded in time and releases its investments.
+// The staff member is optional, since expiry is normally not triggered by a person.
+func (s *LoanService) ExpireLoan(loanID string, closure Closure, opts ...Option) (*Loan, error) {
+	if closure.Reason == "" {
+		closure.Reason = "funding period ended"
+	}
+	return s.closeLoan(loanID, Expired, closure, opts)
+}
+
+// ExpireOverdueLoans expires every approved loan whose funding deadline has passed and returns them.
+// Loans that got fully funded in the meantime are left alone. It is meant to be run periodically,
+// see ExpiryScheduler.
+//
+// A loan that cannot be expired does not hold up the others: the expired loans are returned together
+// with the errors of the loans that failed, joined.
+func (s *LoanService) ExpireOverdueLoans() ([]*Loan, error) {
+	now := s.now()
+	overdue, err := s.repo.ListFundingOverdue(now)
+	if err != nil {
+		return nil, fmt.Errorf("list overdue loans: %w", err)
+	}
+
+	var (
+		expired []*Loan
+		errs    []error
+	)
+	for _, candidate := range overdue {
+		loan, err := s.expireIfOverdue(candidate.ID, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expire loan %s: %w", candidate.ID, err))
+			continue
+		}
+		if loan != nil {
+			expired = append(expired, loan)
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+// expireIfOverdue re-checks the deadline under the loan lock and expires the loan if it still applies.
+// It returns nil without error when there was nothing to do.
+func (s *LoanService) expireIfOverdue(loanID string, now time.Time) (*Loan, error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
+	loan, err := s.repo.GetByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	if !loan.fundingOverdue(now) {
+		return nil, nil
+	}
+	return s.close(loan, Expired, Closure{Reason: "funding deadline passed", ClosedAt: now}, SystemActor)
+}
+
+// closeLoan moves a loan into one of the closed states, records why and releases investor commitments.
+func (s *LoanService) closeLoan(loanID string, to LoanState, closure Closure, opts []Option) (*Loan, error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
+	loan, err := s.repo.GetByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	if err := o.checkVersion(loan); err != nil {
+		return nil, err
+	}
+	return s.close(loan, to, closure, o.actorOr(closure.StaffID))
+}
+
+// close does the work of closeLoan on a loan loaded under its lock.
+// When a loan expires, a notification is queued for each investor whose money was released.
+func (s *LoanService) close(loan *Loan, to LoanState, closure Closure, actor string) (*Loan, error) {
+	if err := ValidateTransition(loan.State, to); err != nil {
+		return nil, err
+	}
+
+	if closure.ClosedAt.IsZero() {
+		closure.ClosedAt = s.now()
+	}
+	if actor == "" {
+		actor = SystemActor
+	}
+	c := change{action: closeActions[to], actor: actor, from: loan.State, payload: closure}
+	loan.State = to
+	loan.Closure = &closure
+
+	released := investors(loan)
+	for i, inv := range loan.Investors {
+		if inv.isCommitted() {
+			loan.Investors[i].Status = Released
+		}
+	}
+	loan.TotalInvested = money.Zero(loan.PrincipalAmount.Currency())
+
+	var messages []outbox.Message
+	if to == Expired {
+		var events []any
+		for _, inv := range released {
+			events = append(events, FundingExpired{notification(loan, inv)})
+		}
+		var err error
+		if messages, err = s.notify(ExpiredNotification, events...); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.updateLoan(loan, c, messages...)
 }
 
 // DisburseLoan moves a loan to Disbursed state and stores agreement and field officer info.
-func (s *LoanService) DisburseLoan(loanID string, disb Disbursement, agreementLink string) (*Loan, error) {
+// The repayment schedule is generated from the loan terms, starting at the disbursement date.
+// With a document store the agreement file and letter must be IDs of uploaded documents.
+// An empty agreementLink keeps the letter generated earlier.
+func (s *LoanService) DisburseLoan(loanID string, disb Disbursement, agreementLink string, opts ...Option) (*Loan, error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
 	loan, err := s.repo.GetByID(loanID)
 	if err != nil {
 		return nil, err
 	}
+	o := newOptions(opts)
+	if err := o.checkVersion(loan); err != nil {
+		return nil, err
+	}
 
 	if err := ValidateTransition(loan.State, Disbursed); err != nil {
 		return nil, err
@@ -110,12 +534,126 @@ func (s *LoanService) DisburseLoan(loanID string, disb Disbursement, agreementLi
 	if disb.AgreementFile == "" || disb.FieldOfficerID == "" || disb.DisbursementDate.IsZero() {
 		return nil, errors.New("missing disbursement fields")
 	}
+	if err := s.checkDocuments(
+		documentRef{"agreement_letter_file", disb.AgreementFile},
+		documentRef{"agreement_letter_link", agreementLink},
+	); err != nil {
+		return nil, err
+	}
 
+	terms := loan.Terms
+	if terms == (RepaymentTerms{}) {
+		terms = DefaultRepaymentTerms
+	}
+	installments, err := GenerateInstallments(loan.PrincipalAmount, loan.Rate, terms, disb.DisbursementDate)
+	if err != nil {
+		return nil, fmt.Errorf("generate repayment schedule: %w", err)
+	}
+
+	c := change{action: audit.Disbursed, actor: o.actorOr(disb.FieldOfficerID), from: loan.State, payload: map[string]any{
+		"disbursement": disb, "agreement_letter_link": agreementLink,
+	}}
 	loan.Disbursement = &disb
-	loan.AgreementLetterURL = agreementLink
+	if agreementLink != "" {
+		loan.AgreementLetterURL = agreementLink
+	}
 	loan.State = Disbursed
+	loan.Terms = terms
+	loan.Installments = installments
+	outstanding := scheduledBalance(installments, loan.PrincipalAmount.Currency())
+	loan.Outstanding = &outstanding
 
-	return s.updateLoan(loan)
+	events := []any{LoanDisbursed{notification(loan, borrower(loan))}}
+	for _, inv := range investors(loan) {
+		events = append(events, LoanDisbursed{notification(loan, inv)})
+	}
+	messages, err := s.notify(DisbursedNotification, events...)
+	if err != nil {
+		return nil, err
+	}
+	published, err := s.publish(loan, nil, DisbursedEvent)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateLoan(loan, c, append(messages, published...)...)
+}
+
+// RecordRepayment applies a borrower payment to a disbursed loan.
+//
+// The amount is allocated to outstanding fees first, then interest, then principal, and the
+// split is stored on the repayment. Paying more than is outstanding is rejected. Once nothing
+// is outstanding the loan moves to Repaid.
+//
+// The repayment is distributed to the loan's investors pro rata (see distribute). The resulting payouts
+// are queued in the outbox together with the repayment and saved when the message is delivered, so they
+// are never lost once the repayment is recorded. Payout IDs are deterministic, so re-saving them is harmless.
+func (s *LoanService) RecordRepayment(loanID string, repayment Repayment, opts ...Option) (*Loan, error) {
+	unlock := s.locks.Lock(loanID)
+	defer unlock()
+
+	loan, err := s.repo.GetByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	if err := o.checkVersion(loan); err != nil {
+		return nil, err
+	}
+
+	if loan.State != Disbursed || loan.Outstanding == nil {
+		return nil, errors.New("loan must be in disbursed state to accept repayments")
+	}
+	if repayment.PaidAt.IsZero() {
+		return nil, errors.New("missing repayment date")
+	}
+	if !repayment.Amount.IsPositive() {
+		return nil, errors.New("repayment amount must be positive")
+	}
+	if repayment.Amount.Currency() != loan.PrincipalAmount.Currency() {
+		return nil, fmt.Errorf("repayment currency %s does not match loan currency %s",
+			repayment.Amount.Currency(), loan.PrincipalAmount.Currency())
+	}
+	if owed := loan.Outstanding.Total(); repayment.Amount.Cmp(owed) > 0 {
+		return nil, fmt.Errorf("repayment of %s exceeds outstanding balance of %s", repayment.Amount, owed)
+	}
+
+	outstanding, split := allocate(*loan.Outstanding, repayment.Amount)
+	split.ID = uuid.NewString()
+	split.Reference = repayment.Reference
+	split.PaidAt = repayment.PaidAt
+	split.RecordedAt = s.now()
+
+	c := change{action: audit.RepaymentRecorded, actor: o.actorOr(loan.BorrowerID), from: loan.State, payload: split}
+	loan.Outstanding = &outstanding
+	loan.Repayments = append(loan.Repayments, split)
+
+	if outstanding.IsZero() {
+		if err := ValidateTransition(loan.State, Repaid); err != nil {
+			return nil, err
+		}
+		loan.State = Repaid
+	}
+
+	var messages []outbox.Message
+	if payouts := distribute(loan, split); len(payouts) > 0 {
+		if messages, err = s.notify(PayoutMessage, payouts); err != nil {
+			return nil, err
+		}
+	}
+	return s.updateLoan(loan, c, messages...)
+}
+
+// ListPayoutsByLoan returns the investor payouts made from a loan's repayments.
+func (s *LoanService) ListPayoutsByLoan(loanID string) ([]Payout, error) {
+	if _, err := s.repo.GetByID(loanID); err != nil {
+		return nil, err
+	}
+	return s.payouts.ListByLoan(loanID)
+}
+
+// ListPayoutsByInvestor returns every payout an investor has received, across all loans.
+func (s *LoanService) ListPayoutsByInvestor(investorID string) ([]Payout, error) {
+	return s.payouts.ListByInvestor(investorID)
 }
 
 // GetLoan retrieves a loan by its ID.
@@ -123,15 +661,81 @@ func (s *LoanService) GetLoan(id string) (*Loan, error) {
 	return s.repo.GetByID(id)
 }
 
+// GetSchedule returns the repayment schedule of a disbursed loan.
+func (s *LoanService) GetSchedule(loanID string) (*Schedule, error) {
+	loan, err := s.repo.GetByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	if len(loan.Installments) == 0 {
+		return nil, ErrScheduleNotAvailable
+	}
+	return newSchedule(loan), nil
+}
+
 // ListLoans returns all loans in the system.
 func (s *LoanService) ListLoans() ([]*Loan, error) {
 	return s.repo.List()
 }
 
-// updateLoan updates the loan and saves it via repository.
-func (s *LoanService) updateLoan(loan *Loan) (*Loan, error) {
-	if err := s.repo.Update(loan); err != nil {
+// SearchLoans returns one page of the loans matching query, together with how many match in total.
+func (s *LoanService) SearchLoans(query LoanQuery) (*LoanPage, error) {
+	return s.repo.Search(query)
+}
+
+// GetHistory returns the audit trail of a loan, oldest event first.
+func (s *LoanService) GetHistory(loanID string) ([]audit.Event, error) {
+	if _, err := s.repo.GetByID(loanID); err != nil {
+		return nil, err
+	}
+	return s.repo.Audit().ListByLoan(loanID)
+}
+
+// SystemActor is recorded as the actor of changes nobody in particular made, such as expiry.
+const SystemActor = "system"
+
+// closeActions maps each closed state to the audit action recorded when entering it.
+var closeActions = map[LoanState]audit.Action{
+	Rejected:  audit.Rejected,
+	Cancelled: audit.Cancelled,
+	Expired:   audit.Expired,
+}
+
+// change describes a loan mutation for the audit trail.
+type change struct {
+	action  audit.Action
+	actor   string
+	from    LoanState // State before the change
+	payload any       // Input of the change; stored as JSON
+}
+
+// updateLoan saves the loan via the repository, together with the audit event recording the change
+// and any outbox messages, in one atomic write.
+func (s *LoanService) updateLoan(loan *Loan, c change, messages ...outbox.Message) (*Loan, error) {
+	event, err := s.auditEvent(loan, c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(loan, event, messages...); err != nil {
 		return nil, err
 	}
 	return loan, nil
 }
+
+// auditEvent builds the audit event of a change about to be stored. The repository fills in the
+// loan ID and version as it stores the change.
+func (s *LoanService) auditEvent(loan *Loan, c change) (*audit.Event, error) {
+	payload, err := json.Marshal(c.payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit payload: %w", err)
+	}
+	return &audit.Event{
+		ID:            uuid.NewString(),
+		Action:        c.action,
+		Actor:         c.actor,
+		PreviousState: string(c.from),
+		NewState:      string(loan.State),
+		Payload:       payload,
+		OccurredAt:    s.now(),
+	}, nil
+}
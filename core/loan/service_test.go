@@ -2,34 +2,101 @@ package loan
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/audit"
+	borrowers "loan-service/core/borrower"
+	"loan-service/core/investor"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
 )
 
+// sentEmail is one email recorded by mockEmailSender.
+type sentEmail struct {
+	kind  string // Notification kind, e.g. FundedNotification
+	event any    // The event passed to the sender
+	Notification
+}
+
 // mockEmailSender simulates an email sender for testing purposes.
 type mockEmailSender struct {
-	sentTo []string
+	sent     []sentEmail
+	failures map[string]int // number of upcoming sends of each kind that fail
 }
 
-func (m *mockEmailSender) SendInvestorNotification(investorID, agreementLink string) error {
-	m.sentTo = append(m.sentTo, investorID)
+func (m *mockEmailSender) send(kind string, event any, n Notification) error {
+	if m.failures[kind] > 0 {
+		m.failures[kind]--
+		return errors.New("smtp unavailable")
+	}
+	m.sent = append(m.sent, sentEmail{kind: kind, event: event, Notification: n})
 	return nil
 }
 
+func (m *mockEmailSender) SendLoanApproved(e LoanApproved) error {
+	return m.send(ApprovedNotification, e, e.Notification)
+}
+
+func (m *mockEmailSender) SendInvestmentReceived(e InvestmentReceived) error {
+	return m.send(InvestmentNotification, e, e.Notification)
+}
+
+func (m *mockEmailSender) SendLoanFunded(e LoanFunded) error {
+	return m.send(FundedNotification, e, e.Notification)
+}
+
+func (m *mockEmailSender) SendLoanDisbursed(e LoanDisbursed) error {
+	return m.send(DisbursedNotification, e, e.Notification)
+}
+
+func (m *mockEmailSender) SendFundingExpired(e FundingExpired) error {
+	return m.send(ExpiredNotification, e, e.Notification)
+}
+
+// to returns the IDs of the recipients with the given role of every email of the given kind, in send order.
+func (m *mockEmailSender) to(kind string, role RecipientRole) []string {
+	var ids []string
+	for _, e := range m.sent {
+		if e.kind == kind && e.Recipient.Role == role {
+			ids = append(ids, e.Recipient.ID)
+		}
+	}
+	return ids
+}
+
 // errorRepo mocks repo with update failure
 type errorRepo struct{}
 
-func (e *errorRepo) Create(*Loan) error { return nil }
+func (e *errorRepo) Create(*Loan, *audit.Event) error { return nil }
 func (e *errorRepo) GetByID(id string) (*Loan, error) {
 	return &Loan{
 		ID:    id,
 		State: Proposed,
 	}, nil
 }
-func (e *errorRepo) Update(*Loan) error     { return errors.New("forced update error") }
-func (e *errorRepo) List() ([]*Loan, error) { return nil, nil }
+func (e *errorRepo) Update(*Loan, *audit.Event, ...outbox.Message) error {
+	return errors.New("forced update error")
+}
+func (e *errorRepo) List() ([]*Loan, error)                        { return nil, nil }
+func (e *errorRepo) Search(LoanQuery) (*LoanPage, error)           { return &LoanPage{}, nil }
+func (e *errorRepo) ListFundingOverdue(time.Time) ([]*Loan, error) { return nil, nil }
+func (e *errorRepo) Totals() ([]LoanTotals, error)                 { return nil, nil }
+func (e *errorRepo) Outbox() outbox.Store                          { return outbox.NewInMemoryStore() }
+func (e *errorRepo) Audit() audit.Repository                       { return audit.NewInMemoryRepository() }
+
+// deliverNotifications runs the outbox dispatcher once, as the background worker would.
+func deliverNotifications(t *testing.T, svc *LoanService) {
+	t.Helper()
+	_, err := outbox.NewDispatcher(svc.repo.Outbox(), svc.DeliverNotification, time.Second, outbox.WithClock(svc.now)).DispatchDue()
+	assert.NoError(t, err)
+}
 
 func setupTestService() (*LoanService, *mockEmailSender) {
 	repo := NewInMemoryLoanRepository()
@@ -44,18 +111,19 @@ func TestCreateLoan(t *testing.T) {
 	tests := []struct {
 		name        string
 		borrowerID  string
-		principal   float64
+		principal   int64
 		expectError bool
 	}{
 		{"Valid loan", "B001", 5000000, false},
-		{"Zero principal", "B002", 0, false}, // Allowed in current logic, may change if validated
+		{"Zero principal", "B002", 0, true},
+		{"Negative principal", "B003", -1000, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ln, err := svc.CreateLoan(tt.borrowerID, tt.principal, 10, 12)
+			ln, err := svc.CreateLoan(tt.borrowerID, idr(tt.principal), 10, 12)
 			if tt.expectError {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, ErrInvalidLoan)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.borrowerID, ln.BorrowerID)
@@ -64,6 +132,54 @@ func TestCreateLoan(t *testing.T) {
 	}
 }
 
+func TestCreateLoan_RateAndROI(t *testing.T) {
+	svc, _ := setupTestService()
+
+	tests := []struct {
+		name    string
+		rate    float64
+		roi     float64
+		wantErr string
+	}{
+		{"Zero", 0, 0, ""},
+		{"Highest", MaxRate, MaxRate, ""},
+		{"Negative rate", -5, 8, "rate must be between 0 and 1000%"},
+		{"Negative ROI", 10, -1, "roi must be between 0 and 1000%"},
+		{"Rate over the cap", 1e30, 8, "rate must be between 0 and 1000%"},
+		{"Infinite ROI", 10, math.Inf(1), "roi must be between 0 and 1000%"},
+		{"Rate is not a number", math.NaN(), 8, "rate must be between 0 and 1000%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateLoan("B004", idr(1000), tt.rate, tt.roi)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidLoan)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCreateLoan_ScheduleMustFit(t *testing.T) {
+	svc, _ := setupTestService()
+	huge := money.New(math.MaxInt64/2, money.IDR)
+
+	_, err := svc.CreateLoan("B005", huge, MaxRate, 8)
+	assert.ErrorIs(t, err, ErrInvalidLoan)
+	assert.ErrorIs(t, err, money.ErrOverflow)
+
+	ln, err := svc.CreateLoan("B005", idr(1000000), MaxRate, 8, WithRepaymentTerms(RepaymentTerms{Method: EffectiveMethod, Tenor: MaxTenor}))
+	require.NoError(t, err)
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP005", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV005", Amount: idr(1000000)})
+	require.NoError(t, err)
+	_, err = svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO005", DisbursementDate: time.Now()}, "")
+	assert.NoError(t, err, "an accepted loan can always be disbursed")
+}
+
 func TestApproveLoan(t *testing.T) {
 	svc, _ := setupTestService()
 
@@ -91,7 +207,7 @@ func TestApproveLoan(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ln, _ := svc.CreateLoan("B003", 4000000, 10, 12)
+			ln, _ := svc.CreateLoan("B003", idr(4000000), 10, 12)
 			_, err := svc.ApproveLoan(ln.ID, tt.approval)
 			if tt.shouldFail {
 				assert.Error(t, err)
@@ -106,41 +222,55 @@ func TestInvestLoan(t *testing.T) {
 	svc, email := setupTestService()
 
 	t.Run("Fully funded triggers notification", func(t *testing.T) {
-		ln, _ := svc.CreateLoan("B004", 1000000, 10, 10)
+		ln, _ := svc.CreateLoan("B004", idr(1000000), 10, 10)
 		svc.ApproveLoan(ln.ID, Approval{
 			PhotoProofURL: "proof",
 			ValidatorID:   "EMP001",
 			ApprovalDate:  time.Now(),
 		})
 
-		_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV001", Amount: 1000000})
+		_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV001", Amount: idr(1000000)})
 		assert.NoError(t, err)
-		assert.Equal(t, 1, len(email.sentTo))
+		assert.Empty(t, email.sent, "sent by the outbox dispatcher, not inline")
+
+		deliverNotifications(t, svc)
+		assert.Equal(t, []string{"INV001"}, email.to(FundedNotification, InvestorRecipient))
+		assert.Equal(t, []string{"EMP001"}, email.to(FundedNotification, StaffRecipient))
 	})
 
 	t.Run("Overfund should fail", func(t *testing.T) {
-		ln, _ := svc.CreateLoan("B005", 2000000, 10, 10)
+		ln, _ := svc.CreateLoan("B005", idr(2000000), 10, 10)
 		svc.ApproveLoan(ln.ID, Approval{
 			PhotoProofURL: "proof",
 			ValidatorID:   "EMP002",
 			ApprovalDate:  time.Now(),
 		})
 
-		_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV999", Amount: 2500000})
+		_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV999", Amount: idr(2500000)})
 		assert.Error(t, err)
 	})
+
+	t.Run("Amount too large to add is rejected, not overflowed", func(t *testing.T) {
+		ln, _ := svc.CreateLoan("B006", idr(2000000), 10, 10)
+		svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP002", ApprovalDate: time.Now()})
+		_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV001", Amount: idr(1000)})
+		require.NoError(t, err)
+
+		_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV002", Amount: money.New(math.MaxInt64, money.IDR)})
+		assert.ErrorIs(t, err, ErrOverInvestment)
+	})
 }
 
 func TestDisburseLoan(t *testing.T) {
 	svc, _ := setupTestService()
 
-	ln, _ := svc.CreateLoan("B006", 1500000, 10, 10)
+	ln, _ := svc.CreateLoan("B006", idr(1500000), 10, 10)
 	svc.ApproveLoan(ln.ID, Approval{
 		PhotoProofURL: "proof",
 		ValidatorID:   "EMP777",
 		ApprovalDate:  time.Now(),
 	})
-	svc.InvestLoan(ln.ID, Investor{ID: "INV", Amount: 1500000})
+	svc.InvestLoan(ln.ID, Investor{ID: "INV", Amount: idr(1500000)})
 
 	tests := []struct {
 		name         string
@@ -202,7 +332,7 @@ func TestLoanService_UpdateFailures(t *testing.T) {
 		{
 			"InvestLoan update failure",
 			func() error {
-				inv := Investor{ID: "INV01", Amount: 1000}
+				inv := Investor{ID: "INV01", Amount: idr(1000)}
 				_, err := svc.InvestLoan("LOAN002", inv)
 				return err
 			},
@@ -228,3 +358,1071 @@ func TestLoanService_UpdateFailures(t *testing.T) {
 		})
 	}
 }
+
+func TestInvestLoan_Concurrent(t *testing.T) {
+	const (
+		principal   = 100000
+		investments = 500
+		amount      = 1000
+	)
+
+	repos := map[string]func(t *testing.T) LoanRepository{
+		"in-memory": func(*testing.T) LoanRepository { return NewInMemoryLoanRepository() },
+		"sql":       func(t *testing.T) LoanRepository { return newSQLiteRepository(t) },
+	}
+
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			email := &mockEmailSender{}
+			svc := NewLoanService(newRepo(t), email)
+
+			ln, err := svc.CreateLoan("B100", idr(principal), 10, 10)
+			assert.NoError(t, err)
+			_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP100", ApprovalDate: time.Now()})
+			assert.NoError(t, err)
+
+			var (
+				wg        sync.WaitGroup
+				succeeded atomic.Int64
+			)
+			for i := 0; i < investments; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if _, err := svc.InvestLoan(ln.ID, Investor{ID: fmt.Sprintf("INV%03d", i), Amount: idr(amount)}); err == nil {
+						succeeded.Add(1)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			final, err := svc.GetLoan(ln.ID)
+			assert.NoError(t, err)
+
+			sum := money.Zero(money.IDR)
+			for _, inv := range final.Investors {
+				sum = sum.Add(inv.Amount)
+			}
+			assert.Equal(t, int64(principal/amount), succeeded.Load())
+			assert.Len(t, final.Investors, principal/amount)
+			assert.Equal(t, idr(principal), final.TotalInvested)
+			assert.Equal(t, final.TotalInvested, sum)
+			assert.Equal(t, Invested, final.State)
+		})
+	}
+}
+
+func TestInvestLoan_NonPositiveAmount(t *testing.T) {
+	svc, _ := setupTestService()
+	ln, _ := svc.CreateLoan("B101", idr(1000), 10, 10)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP101", ApprovalDate: time.Now()})
+
+	_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV", Amount: idr(-500)})
+	assert.Error(t, err)
+}
+
+func TestLoanService_IfVersion(t *testing.T) {
+	svc, _ := setupTestService()
+	approval := Approval{PhotoProofURL: "proof", ValidatorID: "EMP102", ApprovalDate: time.Now()}
+
+	t.Run("Stale version is rejected", func(t *testing.T) {
+		ln, _ := svc.CreateLoan("B102", idr(1000), 10, 10)
+		_, err := svc.ApproveLoan(ln.ID, approval, IfVersion(ln.Version+1))
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		unchanged, _ := svc.GetLoan(ln.ID)
+		assert.Equal(t, Proposed, unchanged.State)
+	})
+
+	t.Run("Current version is accepted", func(t *testing.T) {
+		ln, _ := svc.CreateLoan("B103", idr(1000), 10, 10)
+		approved, err := svc.ApproveLoan(ln.ID, approval, IfVersion(ln.Version))
+		assert.NoError(t, err)
+		assert.Equal(t, ln.Version+1, approved.Version)
+
+		_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV", Amount: idr(500)}, IfVersion(ln.Version))
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+}
+
+func TestInvestLoan_FractionalAmountsFullyFund(t *testing.T) {
+	svc, email := setupTestService()
+	ln, _ := svc.CreateLoan("B104", money.MustParse("1", money.IDR), 10, 10)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP104", ApprovalDate: time.Now()})
+
+	// Ten investments of 0.10 add up to 1.00 exactly; with float64 the sum would be 0.9999999999999999.
+	var err error
+	for i := 0; i < 10; i++ {
+		ln, err = svc.InvestLoan(ln.ID, Investor{ID: fmt.Sprintf("INV%d", i), Amount: money.MustParse("0.1", money.IDR)})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, Invested, ln.State)
+	deliverNotifications(t, svc)
+	assert.Len(t, email.to(FundedNotification, InvestorRecipient), 10)
+}
+
+func TestInvestLoan_CurrencyMismatch(t *testing.T) {
+	svc, _ := setupTestService()
+	ln, _ := svc.CreateLoan("B105", idr(1000), 10, 10)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP105", ApprovalDate: time.Now()})
+
+	_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV", Amount: money.FromMajor(10, money.USD)})
+	assert.Error(t, err)
+}
+
+func TestInvestLoan_InvestorRegistry(t *testing.T) {
+	registry := investor.NewService(investor.NewInMemoryRepository())
+	for _, id := range []string{"INV110", "INV111"} {
+		_, err := registry.Register(id, id, "")
+		require.NoError(t, err)
+	}
+	_, _ = registry.SetKYCStatus("INV110", investor.Verified)
+	exposureCap, minTicket, maxTicket := idr(3000), idr(100), idr(2000)
+	_, err := registry.SetLimits("INV110", investor.Limits{ExposureCap: &exposureCap, MinTicket: &minTicket, MaxTicket: &maxTicket})
+	require.NoError(t, err)
+
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithInvestorRegistry(registry))
+	approved := func() *Loan {
+		ln, err := svc.CreateLoan("B110", idr(5000), 10, 10)
+		require.NoError(t, err)
+		ln, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP110", ApprovalDate: time.Now()})
+		require.NoError(t, err)
+		return ln
+	}
+	first, second := approved(), approved()
+	invest := func(ln *Loan, investorID string, amount int64) error {
+		_, err := svc.InvestLoan(ln.ID, Investor{ID: investorID, Amount: idr(amount)})
+		return err
+	}
+	var limitErr *investor.LimitError
+
+	assert.ErrorIs(t, invest(first, "INV999", 500), investor.ErrInvestorNotFound)
+	assert.ErrorIs(t, invest(first, "INV111", 500), investor.ErrNotVerified)
+	require.ErrorAs(t, invest(first, "INV110", 50), &limitErr)
+	assert.Equal(t, investor.MinTicket, limitErr.Limit)
+
+	require.NoError(t, invest(first, "INV110", 1500))
+	require.NoError(t, invest(first, "INV110", 50), "the minimum applies to the whole ticket")
+	require.ErrorAs(t, invest(first, "INV110", 500), &limitErr)
+	assert.Equal(t, investor.MaxTicket, limitErr.Limit)
+	assert.Equal(t, idr(2050), limitErr.Amount)
+
+	require.NoError(t, invest(second, "INV110", 1450))
+	require.ErrorAs(t, invest(second, "INV110", 100), &limitErr)
+	assert.Equal(t, investor.ExposureCap, limitErr.Limit)
+	assert.Equal(t, idr(3100), limitErr.Amount)
+
+	stored, err := svc.GetLoan(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, idr(1450), stored.TotalInvested, "rejected investments leave the loan alone")
+
+	_, err = svc.WithdrawInvestment(first.ID, "INV110")
+	require.NoError(t, err)
+	assert.NoError(t, invest(second, "INV110", 100), "withdrawn investments no longer count towards exposure")
+}
+
+// slowRegistry takes a while to approve investments, as a remote registry would, so that concurrent
+// investments overlap.
+type slowRegistry struct {
+	InvestorRegistry
+}
+
+func (r slowRegistry) CheckInvestment(investorID string, ticket, exposure money.Money) error {
+	time.Sleep(5 * time.Millisecond)
+	return r.InvestorRegistry.CheckInvestment(investorID, ticket, exposure)
+}
+
+func TestInvestLoan_ExposureCapUnderConcurrency(t *testing.T) {
+	registry := investor.NewService(investor.NewInMemoryRepository())
+	_, err := registry.Register("INV130", "INV130", "")
+	require.NoError(t, err)
+	_, _ = registry.SetKYCStatus("INV130", investor.Verified)
+	exposureCap := idr(3000)
+	_, err = registry.SetLimits("INV130", investor.Limits{ExposureCap: &exposureCap})
+	require.NoError(t, err)
+
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithInvestorRegistry(slowRegistry{registry}))
+	var loans []*Loan
+	for i := 0; i < 10; i++ {
+		ln, err := svc.CreateLoan("B130", idr(5000), 10, 10)
+		require.NoError(t, err)
+		_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP130", ApprovalDate: time.Now()})
+		require.NoError(t, err)
+		loans = append(loans, ln)
+	}
+
+	// Each loan on its own is within the cap, together they are far over it.
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int64
+	)
+	for _, ln := range loans {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if _, err := svc.InvestLoan(id, Investor{ID: "INV130", Amount: idr(1000)}); err == nil {
+				succeeded.Add(1)
+			}
+		}(ln.ID)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(3), succeeded.Load())
+	exposure, err := svc.exposure("INV130", money.IDR, "")
+	require.NoError(t, err)
+	assert.Equal(t, exposureCap, exposure)
+}
+
+func TestCreateLoan_BorrowerRegistry(t *testing.T) {
+	registry := borrowers.NewService(borrowers.NewInMemoryRepository(), borrowers.WithPolicy(borrowers.Policy{SingleLoanInProgress: true}))
+	_, err := registry.Register("B120", "3171000000000120", borrowers.Profile{Name: "Siti"})
+	require.NoError(t, err)
+	limit := idr(10000)
+	_, err = registry.SetCreditLimit("B120", &limit)
+	require.NoError(t, err)
+
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithBorrowerRegistry(registry))
+	var limitErr *borrowers.CreditLimitError
+
+	_, err = svc.CreateLoan("B999", idr(1000), 10, 10)
+	assert.ErrorIs(t, err, borrowers.ErrBorrowerNotFound)
+	_, err = svc.CreateLoan("B120", idr(10001), 10, 10)
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, idr(10001), limitErr.Outstanding)
+
+	ln, err := svc.CreateLoan("B120", idr(6000), 10, 10)
+	require.NoError(t, err)
+	_, err = svc.CreateLoan("B120", idr(1000), 10, 10)
+	assert.ErrorIs(t, err, borrowers.ErrLoanInProgress, "the first loan is not disbursed yet")
+
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP120", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV120", Amount: idr(6000)})
+	require.NoError(t, err)
+	_, err = svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO120", DisbursementDate: time.Now()}, "https://link.pdf")
+	require.NoError(t, err)
+	active, err := svc.ActiveLoans("B120")
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+
+	_, err = svc.CreateLoan("B120", idr(4001), 10, 10)
+	require.ErrorAs(t, err, &limitErr, "the disbursed loan still counts towards the limit")
+	assert.Equal(t, idr(10001), limitErr.Outstanding)
+
+	_, err = svc.RecordRepayment(ln.ID, Repayment{Amount: idr(3600), PaidAt: time.Now()})
+	require.NoError(t, err)
+	second, err := svc.CreateLoan("B120", idr(6000), 10, 10)
+	require.NoError(t, err, "repaid principal frees credit")
+
+	_, err = svc.RejectLoan(second.ID, Closure{Reason: "duplicate", StaffID: "EMP120"})
+	require.NoError(t, err)
+	_, err = svc.CreateLoan("B120", idr(1000), 10, 10)
+	assert.NoError(t, err, "closed loans are no longer in progress")
+}
+
+// documentSet is a DocumentStore holding the listed document IDs.
+type documentSet map[string]bool
+
+func (d documentSet) Exists(id string) (bool, error) { return d[id], nil }
+
+func TestApproveAndDisburse_DocumentStore(t *testing.T) {
+	docs := documentSet{"proof-1": true, "signed-1": true, "letter-1": true}
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithDocumentStore(docs))
+	ln, err := svc.CreateLoan("B130", idr(1000), 10, 10)
+	require.NoError(t, err)
+
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "https://example.com/proof.jpg", ValidatorID: "EMP130", ApprovalDate: time.Now()})
+	assert.ErrorIs(t, err, ErrUnknownDocument)
+	assert.ErrorContains(t, err, `photo_proof_url refers to "https://example.com/proof.jpg"`)
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof-1", ValidatorID: "EMP130", ApprovalDate: time.Now()})
+	require.NoError(t, err)
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV130", Amount: idr(1000)})
+	require.NoError(t, err)
+
+	disb := Disbursement{AgreementFile: "signed-2", FieldOfficerID: "FO130", DisbursementDate: time.Now()}
+	_, err = svc.DisburseLoan(ln.ID, disb, "letter-1")
+	assert.ErrorContains(t, err, "agreement_letter_file")
+	disb.AgreementFile = "signed-1"
+	_, err = svc.DisburseLoan(ln.ID, disb, "letter-2")
+	assert.ErrorContains(t, err, "agreement_letter_link")
+	ln, err = svc.DisburseLoan(ln.ID, disb, "letter-1")
+	require.NoError(t, err)
+	assert.Equal(t, "letter-1", ln.AgreementLetterURL)
+}
+
+// recordingGenerator is an AgreementGenerator remembering the investors of every letter it wrote
+// and the links of the letters it discarded.
+type recordingGenerator struct {
+	letters   [][]Investor
+	discarded []string
+	fail      bool
+}
+
+func (g *recordingGenerator) Generate(loan *Loan) (string, error) {
+	if g.fail {
+		return "", errors.New("disk full")
+	}
+	g.letters = append(g.letters, loan.Commitments())
+	return fmt.Sprintf("https://docs.example.com/%s/v%d.pdf", loan.ID, len(g.letters)), nil
+}
+
+func (g *recordingGenerator) Discard(link string) error {
+	g.discarded = append(g.discarded, link)
+	return nil
+}
+
+func TestAgreementLetter(t *testing.T) {
+	gen := &recordingGenerator{}
+	email := &mockEmailSender{}
+	svc := NewLoanService(NewInMemoryLoanRepository(), email, WithAgreementGenerator(gen))
+	ln, err := svc.CreateLoan("B140", idr(3000), 10, 8)
+	require.NoError(t, err)
+	approval := Approval{PhotoProofURL: "proof", ValidatorID: "EMP140", ApprovalDate: time.Now()}
+
+	ln, err = svc.ApproveLoan(ln.ID, approval)
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v1.pdf", ln.AgreementLetterURL)
+	assert.Equal(t, [][]Investor{nil}, gen.letters, "the first letter has no investors yet")
+
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV140", Amount: idr(1000)})
+	require.NoError(t, err)
+	assert.Len(t, gen.letters, 1, "partial investments keep the letter")
+	ln, err = svc.InvestLoan(ln.ID, Investor{ID: "INV141", Amount: idr(2000)})
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v2.pdf", ln.AgreementLetterURL)
+	assert.Equal(t, []Investor{
+		{ID: "INV140", Amount: idr(1000), Status: Committed},
+		{ID: "INV141", Amount: idr(2000), Status: Committed},
+	}, gen.letters[1])
+
+	deliverNotifications(t, svc)
+	for _, sent := range email.sent {
+		switch sent.kind {
+		case ApprovedNotification:
+			assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v1.pdf", sent.Loan.AgreementLetterURL)
+		case FundedNotification:
+			assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v2.pdf", sent.Loan.AgreementLetterURL, sent.Recipient.ID)
+		}
+	}
+	assert.Len(t, email.to(FundedNotification, InvestorRecipient), 2)
+
+	ln, err = svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed", FieldOfficerID: "FO140", DisbursementDate: time.Now()}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v2.pdf", ln.AgreementLetterURL, "disbursement keeps the generated letter")
+
+	t.Run("Approval fails when the letter cannot be generated", func(t *testing.T) {
+		gen.fail = true
+		defer func() { gen.fail = false }()
+		other, err := svc.CreateLoan("B141", idr(3000), 10, 8)
+		require.NoError(t, err)
+
+		_, err = svc.ApproveLoan(other.ID, approval)
+		assert.ErrorContains(t, err, "generate agreement letter: disk full")
+		stored, err := svc.GetLoan(other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, Proposed, stored.State)
+	})
+
+	assert.Equal(t, []string{"https://docs.example.com/" + ln.ID + "/v1.pdf"}, gen.discarded,
+		"the approval letter is dropped once the funded loan is stored")
+}
+
+func TestAgreementLetter_DiscardedWhenTheLoanIsNotStored(t *testing.T) {
+	gen := &recordingGenerator{}
+	repo := &failingUpdates{InMemoryLoanRepository: NewInMemoryLoanRepository(), ids: map[string]bool{}}
+	svc := NewLoanService(repo, &mockEmailSender{}, WithAgreementGenerator(gen))
+	ln, err := svc.CreateLoan("B142", idr(3000), 10, 8)
+	require.NoError(t, err)
+	approval := Approval{PhotoProofURL: "proof", ValidatorID: "EMP142", ApprovalDate: time.Now()}
+
+	repo.ids[ln.ID] = true
+	_, err = svc.ApproveLoan(ln.ID, approval)
+	assert.ErrorContains(t, err, "row is corrupt")
+	assert.Equal(t, []string{"https://docs.example.com/" + ln.ID + "/v1.pdf"}, gen.discarded)
+
+	repo.ids[ln.ID] = false
+	ln, err = svc.ApproveLoan(ln.ID, approval)
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v2.pdf", ln.AgreementLetterURL)
+
+	repo.ids[ln.ID] = true
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV142", Amount: idr(3000)})
+	assert.ErrorContains(t, err, "row is corrupt")
+	assert.Equal(t, []string{
+		"https://docs.example.com/" + ln.ID + "/v1.pdf",
+		"https://docs.example.com/" + ln.ID + "/v3.pdf",
+	}, gen.discarded, "only the new letter is discarded")
+	stored, err := svc.GetLoan(ln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.example.com/"+ln.ID+"/v2.pdf", stored.AgreementLetterURL)
+}
+
+func TestGetSchedule(t *testing.T) {
+	svc, _ := setupTestService()
+	terms := RepaymentTerms{Method: WeeklyMethod, Tenor: 50}
+
+	_, err := svc.CreateLoan("B106", idr(1000), 10, 10, WithRepaymentTerms(RepaymentTerms{Method: "balloon", Tenor: 1}))
+	assert.Error(t, err)
+
+	ln, err := svc.CreateLoan("B106", idr(500000), 26, 20, WithRepaymentTerms(terms))
+	assert.NoError(t, err)
+	assert.Equal(t, terms.Method, ln.Terms.Method)
+	assert.Equal(t, terms.Tenor, ln.Terms.Tenor)
+	assert.Equal(t, money.Zero(money.IDR), ln.Terms.InstallmentFee)
+
+	_, err = svc.GetSchedule(ln.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotAvailable)
+
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP106", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV", Amount: idr(500000)})
+	disbursedAt := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	_, err = svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO106", DisbursementDate: disbursedAt}, "https://link.pdf")
+	assert.NoError(t, err)
+
+	schedule, err := svc.GetSchedule(ln.ID)
+	assert.NoError(t, err)
+	assert.Len(t, schedule.Installments, 50)
+	assert.Equal(t, disbursedAt.AddDate(0, 0, 7), schedule.Installments[0].DueDate)
+	assert.Equal(t, idr(500000), schedule.TotalPrincipal)
+	assert.Equal(t, idr(125000), schedule.TotalInterest)
+	assert.Equal(t, idr(625000), schedule.TotalAmount)
+
+	_, err = svc.GetSchedule("missing")
+	assert.ErrorIs(t, err, ErrLoanNotFound)
+}
+
+// disbursedLoan walks a new loan through approval, funding and disbursement.
+func disbursedLoan(t *testing.T, svc *LoanService, principal int64, terms RepaymentTerms) *Loan {
+	t.Helper()
+	ln, err := svc.CreateLoan("B200", idr(principal), 12, 10, WithRepaymentTerms(terms))
+	assert.NoError(t, err)
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP200", ApprovalDate: time.Now()})
+	assert.NoError(t, err)
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV200", Amount: idr(principal)})
+	assert.NoError(t, err)
+	ln, err = svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO200", DisbursementDate: time.Now()}, "https://link.pdf")
+	assert.NoError(t, err)
+	return ln
+}
+
+func TestRecordRepayment(t *testing.T) {
+	svc, _ := setupTestService()
+	paidAt := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Disbursement sets the outstanding balance", func(t *testing.T) {
+		ln := disbursedLoan(t, svc, 1200000, RepaymentTerms{Method: FlatMethod, Tenor: 12, InstallmentFee: idr(1000)})
+		assert.Equal(t, &Balance{Fees: idr(12000), Interest: idr(144000), Principal: idr(1200000)}, ln.Outstanding)
+	})
+
+	t.Run("Allocates fees, interest, then principal until repaid", func(t *testing.T) {
+		ln := disbursedLoan(t, svc, 1200000, RepaymentTerms{Method: FlatMethod, Tenor: 12, InstallmentFee: idr(1000)})
+
+		ln, err := svc.RecordRepayment(ln.ID, Repayment{Amount: idr(20000), PaidAt: paidAt, Reference: "TRX1"})
+		assert.NoError(t, err)
+		assert.Equal(t, Disbursed, ln.State)
+		assert.Len(t, ln.Repayments, 1)
+		rep := ln.Repayments[0]
+		assert.NotEmpty(t, rep.ID)
+		assert.Equal(t, "TRX1", rep.Reference)
+		assert.Equal(t, idr(12000), rep.Fees)
+		assert.Equal(t, idr(8000), rep.Interest)
+		assert.Equal(t, idr(0), rep.Principal)
+		assert.Equal(t, &Balance{Fees: idr(0), Interest: idr(136000), Principal: idr(1200000)}, ln.Outstanding)
+
+		_, err = svc.RecordRepayment(ln.ID, Repayment{Amount: idr(2000000), PaidAt: paidAt})
+		assert.Error(t, err, "overpayment is rejected")
+
+		ln, err = svc.RecordRepayment(ln.ID, Repayment{Amount: idr(1336000), PaidAt: paidAt})
+		assert.NoError(t, err)
+		assert.Equal(t, Repaid, ln.State)
+		assert.True(t, ln.Outstanding.IsZero())
+		assert.Equal(t, idr(136000), ln.Repayments[1].Interest)
+		assert.Equal(t, idr(1200000), ln.Repayments[1].Principal)
+
+		_, err = svc.RecordRepayment(ln.ID, Repayment{Amount: idr(1), PaidAt: paidAt})
+		assert.Error(t, err, "repaid loans accept no more repayments")
+	})
+
+	t.Run("Rejects invalid repayments", func(t *testing.T) {
+		ln := disbursedLoan(t, svc, 1000, RepaymentTerms{Method: FlatMethod, Tenor: 1})
+
+		_, err := svc.RecordRepayment(ln.ID, Repayment{Amount: idr(0), PaidAt: paidAt})
+		assert.Error(t, err)
+		_, err = svc.RecordRepayment(ln.ID, Repayment{Amount: idr(10)})
+		assert.Error(t, err)
+		_, err = svc.RecordRepayment(ln.ID, Repayment{Amount: money.FromMajor(10, money.USD), PaidAt: paidAt})
+		assert.Error(t, err)
+
+		proposed, _ := svc.CreateLoan("B201", idr(1000), 10, 10)
+		_, err = svc.RecordRepayment(proposed.ID, Repayment{Amount: idr(10), PaidAt: paidAt})
+		assert.Error(t, err)
+	})
+}
+
+func TestRecordRepayment_DistributesToInvestors(t *testing.T) {
+	svc, _ := setupTestService()
+
+	ln, _ := svc.CreateLoan("B202", idr(3000), 12, 6, WithRepaymentTerms(RepaymentTerms{Method: FlatMethod, Tenor: 1}))
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP202", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV-A", Amount: idr(1000)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV-B", Amount: idr(2000)})
+	_, err := svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO202", DisbursementDate: time.Now()}, "https://link.pdf")
+	assert.NoError(t, err)
+
+	// One month at 12% p.a. is 30 interest; investors earn ROI/Rate = half of it.
+	ln, err = svc.RecordRepayment(ln.ID, Repayment{Amount: idr(3030), PaidAt: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, Repaid, ln.State)
+
+	payouts, err := svc.ListPayoutsByLoan(ln.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, payouts, "saved by the outbox dispatcher, together with the repayment")
+	deliverNotifications(t, svc)
+
+	payouts, err = svc.ListPayoutsByLoan(ln.ID)
+	assert.NoError(t, err)
+	assert.Len(t, payouts, 2)
+
+	byInvestor, err := svc.ListPayoutsByInvestor("INV-B")
+	assert.NoError(t, err)
+	if assert.Len(t, byInvestor, 1) {
+		assert.Equal(t, idr(2000), byInvestor[0].Principal)
+		assert.Equal(t, idr(10), byInvestor[0].Return)
+		assert.Equal(t, ln.Repayments[0].ID, byInvestor[0].RepaymentID)
+	}
+
+	_, err = svc.ListPayoutsByLoan("missing")
+	assert.ErrorIs(t, err, ErrLoanNotFound)
+}
+
+// failingPayouts is a PayoutRepository whose Save fails until it is told to work.
+type failingPayouts struct {
+	*InMemoryPayoutRepository
+	broken bool
+}
+
+func (f *failingPayouts) Save(payouts []Payout) error {
+	if f.broken {
+		return errors.New("payout store unavailable")
+	}
+	return f.InMemoryPayoutRepository.Save(payouts)
+}
+
+func TestRecordRepayment_PayoutsSurviveFailures(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	payouts := &failingPayouts{InMemoryPayoutRepository: NewInMemoryPayoutRepository(), broken: true}
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithPayoutRepository(payouts), WithClock(clock.Now))
+	ln := disbursedLoan(t, svc, 1000, RepaymentTerms{Method: FlatMethod, Tenor: 1})
+
+	_, err := svc.RecordRepayment(ln.ID, Repayment{Amount: idr(500), PaidAt: clock.Now()})
+	require.NoError(t, err, "the repayment is recorded whatever happens to the payouts")
+
+	deliverNotifications(t, svc)
+	pending, err := svc.ListNotifications(outbox.Pending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, PayoutMessage, pending[0].Kind)
+	assert.Equal(t, 1, pending[0].Attempts)
+
+	payouts.broken = false
+	clock.Advance(time.Hour)
+	deliverNotifications(t, svc)
+	saved, err := svc.ListPayoutsByLoan(ln.ID)
+	require.NoError(t, err)
+	assert.Len(t, saved, 1)
+}
+
+func TestRejectLoan(t *testing.T) {
+	svc, _ := setupTestService()
+
+	ln, _ := svc.CreateLoan("B300", idr(1000), 10, 8)
+
+	_, err := svc.RejectLoan(ln.ID, Closure{StaffID: "EMP300"})
+	assert.EqualError(t, err, "missing rejection fields")
+
+	rejected, err := svc.RejectLoan(ln.ID, Closure{Reason: "incomplete documents", StaffID: "EMP300"})
+	assert.NoError(t, err)
+	assert.Equal(t, Rejected, rejected.State)
+	assert.Equal(t, "incomplete documents", rejected.Closure.Reason)
+	assert.False(t, rejected.Closure.ClosedAt.IsZero())
+
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP300", ApprovalDate: time.Now()})
+	assert.Error(t, err, "rejected loans are final")
+
+	approved, _ := svc.CreateLoan("B301", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(approved.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP301", ApprovalDate: time.Now()})
+	_, err = svc.RejectLoan(approved.ID, Closure{Reason: "too late", StaffID: "EMP301"})
+	assert.Error(t, err, "only proposals can be rejected")
+}
+
+func TestCancelLoan_ReleasesInvestments(t *testing.T) {
+	svc, _ := setupTestService()
+
+	ln, _ := svc.CreateLoan("B310", idr(3000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP310", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(1000)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(500)})
+
+	_, err := svc.CancelLoan(ln.ID, Closure{Reason: "borrower withdrew"})
+	assert.EqualError(t, err, "missing cancellation fields")
+
+	cancelled, err := svc.CancelLoan(ln.ID, Closure{Reason: "borrower withdrew", StaffID: "EMP310"})
+	assert.NoError(t, err)
+	assert.Equal(t, Cancelled, cancelled.State)
+	assert.True(t, cancelled.TotalInvested.IsZero())
+	assert.Len(t, cancelled.Investors, 2, "released investments stay on record")
+	for _, inv := range cancelled.Investors {
+		assert.Equal(t, Released, inv.Status)
+	}
+
+	_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV3", Amount: idr(100)})
+	assert.Error(t, err)
+
+	proposed, _ := svc.CreateLoan("B311", idr(1000), 10, 8)
+	cancelled, err = svc.CancelLoan(proposed.ID, Closure{StaffID: "EMP311"})
+	assert.NoError(t, err)
+	assert.Equal(t, Cancelled, cancelled.State)
+
+	funded, _ := svc.CreateLoan("B312", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(funded.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP312", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(funded.ID, Investor{ID: "INV1", Amount: idr(1000)})
+	_, err = svc.CancelLoan(funded.ID, Closure{StaffID: "EMP312"})
+	assert.Error(t, err, "fully funded loans cannot be cancelled")
+}
+
+func TestExpireLoan(t *testing.T) {
+	svc, _ := setupTestService()
+
+	ln, _ := svc.CreateLoan("B320", idr(2000), 10, 8)
+	_, err := svc.ExpireLoan(ln.ID, Closure{})
+	assert.Error(t, err, "only approved loans expire")
+
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP320", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(500)})
+
+	expired, err := svc.ExpireLoan(ln.ID, Closure{}, IfVersion(3))
+	assert.NoError(t, err)
+	assert.Equal(t, Expired, expired.State)
+	assert.Equal(t, "funding period ended", expired.Closure.Reason)
+	assert.Empty(t, expired.Closure.StaffID)
+	assert.True(t, expired.TotalInvested.IsZero())
+	assert.Equal(t, Released, expired.Investors[0].Status)
+
+	_, err = svc.ExpireLoan("missing", Closure{})
+	assert.ErrorIs(t, err, ErrLoanNotFound)
+}
+
+// fakeClock is a manually advanced clock for tests that depend on the current time.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestExpireOverdueLoans(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	email := &mockEmailSender{}
+	svc := NewLoanService(NewInMemoryLoanRepository(), email, WithClock(clock.Now))
+	approval := Approval{PhotoProofURL: "proof", ValidatorID: "EMP400", ApprovalDate: clock.Now()}
+
+	ln, _ := svc.CreateLoan("B400", idr(3000), 10, 8)
+	_, err := svc.ApproveLoan(ln.ID, approval, WithFundingDeadline(clock.Now()))
+	assert.EqualError(t, err, "funding deadline must be in the future")
+
+	deadline := clock.Now().Add(48 * time.Hour)
+	ln, err = svc.ApproveLoan(ln.ID, approval, WithFundingDeadline(deadline))
+	assert.NoError(t, err)
+	assert.True(t, deadline.Equal(*ln.FundingDeadline))
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(1000)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(500)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(200)})
+
+	funded, _ := svc.CreateLoan("B401", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(funded.ID, approval, WithFundingDeadline(deadline))
+	_, _ = svc.InvestLoan(funded.ID, Investor{ID: "INV3", Amount: idr(1000)})
+
+	open, _ := svc.CreateLoan("B402", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(open.ID, approval)
+
+	expired, err := svc.ExpireOverdueLoans()
+	assert.NoError(t, err)
+	assert.Empty(t, expired, "deadline not reached yet")
+
+	clock.Advance(48 * time.Hour)
+	expired, err = svc.ExpireOverdueLoans()
+	assert.NoError(t, err)
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, ln.ID, expired[0].ID)
+		assert.Equal(t, Expired, expired[0].State)
+		assert.Equal(t, "funding deadline passed", expired[0].Closure.Reason)
+		assert.True(t, clock.Now().Equal(expired[0].Closure.ClosedAt))
+		assert.True(t, expired[0].TotalInvested.IsZero())
+	}
+	deliverNotifications(t, svc)
+	assert.Equal(t, []string{"INV1", "INV2"}, email.to(ExpiredNotification, InvestorRecipient), "each investor is told once")
+
+	stored, _ := svc.GetLoan(funded.ID)
+	assert.Equal(t, Invested, stored.State)
+	stored, _ = svc.GetLoan(open.ID)
+	assert.Equal(t, Approved, stored.State)
+
+	expired, err = svc.ExpireOverdueLoans()
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
+// failingUpdates is an in-memory repository that refuses to update the loans in it.
+type failingUpdates struct {
+	*InMemoryLoanRepository
+	ids map[string]bool
+}
+
+func (f *failingUpdates) Update(loan *Loan, event *audit.Event, messages ...outbox.Message) error {
+	if f.ids[loan.ID] {
+		return errors.New("row is corrupt")
+	}
+	return f.InMemoryLoanRepository.Update(loan, event, messages...)
+}
+
+func TestExpireOverdueLoans_FailureDoesNotStopOthers(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	repo := &failingUpdates{InMemoryLoanRepository: NewInMemoryLoanRepository(), ids: map[string]bool{}}
+	svc := NewLoanService(repo, &mockEmailSender{}, WithClock(clock.Now))
+	approval := Approval{PhotoProofURL: "proof", ValidatorID: "EMP401", ApprovalDate: clock.Now()}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ln, _ := svc.CreateLoan(fmt.Sprintf("B41%d", i), idr(1000), 10, 8)
+		_, err := svc.ApproveLoan(ln.ID, approval, WithFundingDeadline(clock.Now().Add(time.Duration(i+1)*time.Hour)))
+		require.NoError(t, err)
+		ids = append(ids, ln.ID)
+	}
+	repo.ids[ids[0]] = true // The earliest deadline, so it comes up first
+
+	clock.Advance(24 * time.Hour)
+	expired, err := svc.ExpireOverdueLoans()
+	assert.ErrorContains(t, err, "expire loan "+ids[0]+": row is corrupt")
+	require.Len(t, expired, 2)
+	assert.Equal(t, ids[1], expired[0].ID)
+	assert.Equal(t, ids[2], expired[1].ID)
+}
+
+func TestWithdrawInvestment(t *testing.T) {
+	svc, _ := setupTestService()
+
+	ln, _ := svc.CreateLoan("B500", idr(3000), 10, 8)
+	_, err := svc.WithdrawInvestment(ln.ID, "INV1")
+	assert.Error(t, err, "loan not approved yet")
+
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP500", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(1000)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(500)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(200)})
+
+	_, err = svc.WithdrawInvestment(ln.ID, "INV9")
+	assert.ErrorIs(t, err, ErrInvestmentNotFound)
+
+	ln, err = svc.WithdrawInvestment(ln.ID, "INV1")
+	assert.NoError(t, err)
+	assert.Equal(t, Approved, ln.State)
+	assert.Equal(t, idr(500), ln.TotalInvested)
+	assert.Equal(t, []CommitmentStatus{Withdrawn, Committed, Withdrawn},
+		[]CommitmentStatus{ln.Investors[0].Status, ln.Investors[1].Status, ln.Investors[2].Status})
+
+	_, err = svc.WithdrawInvestment(ln.ID, "INV1")
+	assert.ErrorIs(t, err, ErrInvestmentNotFound, "nothing left to withdraw")
+
+	// The freed amount can be taken by someone else and completes funding.
+	ln, err = svc.InvestLoan(ln.ID, Investor{ID: "INV3", Amount: idr(2500)})
+	assert.NoError(t, err)
+	assert.Equal(t, Invested, ln.State)
+
+	_, err = svc.WithdrawInvestment(ln.ID, "INV2")
+	assert.EqualError(t, err, "investments can only be withdrawn while the loan is approved")
+}
+
+func TestReduceInvestment(t *testing.T) {
+	svc, _ := setupTestService()
+
+	ln, _ := svc.CreateLoan("B510", idr(3000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP510", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(1000)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(300)})
+
+	tests := []struct {
+		name   string
+		amount money.Money
+		errMsg string
+	}{
+		{"Zero", idr(0), "reduction amount must be positive"},
+		{"Other currency", money.FromMajor(10, money.USD), "reduction currency USD does not match loan currency IDR"},
+		{"More than committed", idr(1301), "reduction of IDR 1301.00 exceeds committed amount of IDR 1300.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ReduceInvestment(ln.ID, "INV1", tt.amount)
+			assert.EqualError(t, err, tt.errMsg)
+		})
+	}
+
+	// Newest investment first: the 300 is withdrawn whole, 200 is split off the 1000.
+	ln, err := svc.ReduceInvestment(ln.ID, "INV1", idr(500))
+	assert.NoError(t, err)
+	assert.Equal(t, idr(800), ln.TotalInvested)
+	assert.Equal(t, []Investor{
+		{ID: "INV1", Amount: idr(800), Status: Committed},
+		{ID: "INV1", Amount: idr(300), Status: Withdrawn},
+		{ID: "INV1", Amount: idr(200), Status: Withdrawn},
+	}, ln.Investors)
+
+	ln, err = svc.ReduceInvestment(ln.ID, "INV1", idr(800))
+	assert.NoError(t, err)
+	assert.True(t, ln.TotalInvested.IsZero())
+	assert.Equal(t, Withdrawn, ln.Investors[0].Status)
+}
+
+func TestGetHistory(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithClock(clock.Now))
+
+	ln, _ := svc.CreateLoan("B600", idr(1000), 12, 10, WithRepaymentTerms(RepaymentTerms{Method: FlatMethod, Tenor: 1}))
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP600", ApprovalDate: clock.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(400)})
+	_, _ = svc.WithdrawInvestment(ln.ID, "INV1")
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(1000)}, WithActor("ops-console"))
+	_, _ = svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.jpg", FieldOfficerID: "FO600", DisbursementDate: clock.Now()}, "https://link.pdf")
+	_, err := svc.RecordRepayment(ln.ID, Repayment{Amount: idr(1010), PaidAt: clock.Now()})
+	assert.NoError(t, err)
+
+	_, err = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP600", ApprovalDate: clock.Now()})
+	assert.Error(t, err, "failed calls leave no trace")
+
+	history, err := svc.GetHistory(ln.ID)
+	assert.NoError(t, err)
+
+	type row struct {
+		action   audit.Action
+		actor    string
+		from, to string
+	}
+	var rows []row
+	for i, e := range history {
+		assert.Equal(t, int64(i+1), e.LoanVersion)
+		assert.Equal(t, ln.ID, e.LoanID)
+		assert.True(t, clock.Now().Equal(e.OccurredAt))
+		rows = append(rows, row{e.Action, e.Actor, e.PreviousState, e.NewState})
+	}
+	assert.Equal(t, []row{
+		{audit.Created, "B600", "", "proposed"},
+		{audit.Approved, "EMP600", "proposed", "approved"},
+		{audit.Invested, "INV1", "approved", "approved"},
+		{audit.InvestmentWithdrawn, "INV1", "approved", "approved"},
+		{audit.Invested, "ops-console", "approved", "invested"},
+		{audit.Disbursed, "FO600", "invested", "disbursed"},
+		{audit.RepaymentRecorded, "B600", "disbursed", "repaid"},
+	}, rows)
+	assert.JSONEq(t, `{"investor_id":"INV1","amount":{"amount":"400.00","currency":"IDR"}}`, string(history[3].Payload))
+
+	_, err = svc.GetHistory("missing")
+	assert.ErrorIs(t, err, ErrLoanNotFound)
+}
+
+func TestGetHistory_Expiry(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithClock(clock.Now))
+
+	ln, _ := svc.CreateLoan("B610", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP610", ApprovalDate: clock.Now()},
+		WithFundingDeadline(clock.Now().Add(time.Hour)))
+	clock.Advance(time.Hour)
+	_, _ = svc.ExpireOverdueLoans()
+
+	history, _ := svc.GetHistory(ln.ID)
+	last := history[len(history)-1]
+	assert.Equal(t, audit.Expired, last.Action)
+	assert.Equal(t, SystemActor, last.Actor)
+	assert.Equal(t, "approved", last.PreviousState)
+	assert.Equal(t, "expired", last.NewState)
+}
+
+func TestInvestLoan_NotificationOutbox(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)}
+	email := &mockEmailSender{failures: map[string]int{FundedNotification: 4}}
+	svc := NewLoanService(NewInMemoryLoanRepository(), email, WithClock(clock.Now))
+	dispatcher := outbox.NewDispatcher(svc.repo.Outbox(), svc.DeliverNotification, time.Second,
+		outbox.WithClock(clock.Now), outbox.WithMaxAttempts(2))
+
+	ln, _ := svc.CreateLoan("B700", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP700", ApprovalDate: clock.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(400)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(100)})
+	_, _ = svc.WithdrawInvestment(ln.ID, "INV2")
+	_, err := svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(600)})
+	assert.NoError(t, err)
+
+	pending, _ := svc.ListNotifications(outbox.Pending)
+	funded := 0
+	for _, msg := range pending {
+		if msg.Kind == FundedNotification {
+			funded++
+		}
+	}
+	assert.Equal(t, 2, funded, "one message for the approving staff and one per committed investor")
+
+	// Two failed attempts exhaust the funded messages; the others are delivered straight away.
+	_, _ = dispatcher.DispatchDue()
+	clock.Advance(time.Second)
+	_, _ = dispatcher.DispatchDue()
+	failed, _ := svc.ListNotifications(outbox.Failed)
+	if assert.Len(t, failed, 2) {
+		assert.Equal(t, FundedNotification, failed[1].Kind)
+		assert.Equal(t, "smtp unavailable", failed[1].LastError)
+	}
+	assert.Empty(t, email.to(FundedNotification, InvestorRecipient))
+	assert.Equal(t, []string{"INV1", "INV2", "INV1"}, email.to(InvestmentNotification, InvestorRecipient))
+
+	// Staff re-trigger the investor's message and the next run delivers it.
+	_, err = svc.RetryNotification(failed[1].ID)
+	assert.NoError(t, err)
+	_, err = svc.RetryNotification(failed[1].ID)
+	assert.ErrorIs(t, err, outbox.ErrNotFailed)
+
+	delivered, err := dispatcher.DispatchDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{"INV1"}, email.to(FundedNotification, InvestorRecipient))
+
+	_, err = svc.RetryNotification("missing")
+	assert.ErrorIs(t, err, outbox.ErrMessageNotFound)
+}
+
+func TestLifecycleNotifications(t *testing.T) {
+	svc, email := setupTestService()
+	approvedAt := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	ln, _ := svc.CreateLoan("B800", idr(1000), 10, 8)
+	_, err := svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP800", ApprovalDate: approvedAt})
+	assert.NoError(t, err)
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(600)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(300)})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(100)})
+	disbursed, err := svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.pdf", FieldOfficerID: "EMP801", DisbursementDate: approvedAt.AddDate(0, 0, 7)}, "https://example.com/agreement.pdf")
+	assert.NoError(t, err)
+
+	assert.Empty(t, email.sent, "nothing is sent before the outbox is dispatched")
+	deliverNotifications(t, svc)
+
+	type sent struct {
+		kind string
+		to   Recipient
+	}
+	var got []sent
+	for _, e := range email.sent {
+		got = append(got, sent{e.kind, e.Recipient})
+	}
+	borrower := Recipient{Role: BorrowerRecipient, ID: "B800"}
+	inv1 := Recipient{Role: InvestorRecipient, ID: "INV1"}
+	inv2 := Recipient{Role: InvestorRecipient, ID: "INV2"}
+	assert.Equal(t, []sent{
+		{ApprovedNotification, borrower},
+		{InvestmentNotification, inv1},
+		{InvestmentNotification, inv2},
+		{InvestmentNotification, inv1},
+		{FundedNotification, Recipient{Role: StaffRecipient, ID: "EMP800"}},
+		{FundedNotification, inv1},
+		{FundedNotification, inv2},
+		{DisbursedNotification, borrower},
+		{DisbursedNotification, inv1},
+		{DisbursedNotification, inv2},
+	}, got)
+
+	// Every event carries the loan as stored by the change that triggered it.
+	assert.Equal(t, Approved, email.sent[0].Loan.State)
+	assert.Equal(t, "EMP800", email.sent[0].Loan.Approval.ValidatorID)
+	if received, ok := email.sent[2].event.(InvestmentReceived); assert.True(t, ok) {
+		assert.True(t, idr(300).Equal(received.Amount))
+		assert.True(t, idr(900).Equal(received.Loan.TotalInvested))
+	}
+	assert.Equal(t, Invested, email.sent[4].Loan.State)
+	assert.Len(t, email.sent[4].Loan.Investors, 3)
+	last := email.sent[len(email.sent)-1].Loan
+	assert.Equal(t, Disbursed, last.State)
+	assert.Equal(t, disbursed.Version, last.Version)
+	assert.Equal(t, "https://example.com/agreement.pdf", last.AgreementLetterURL)
+	assert.NotNil(t, last.Outstanding)
+}
+
+func TestDeliverNotification_UnknownKind(t *testing.T) {
+	svc, _ := setupTestService()
+	msg, _ := outbox.New("loan.unknown", LoanApproved{}, time.Now())
+	assert.EqualError(t, svc.DeliverNotification(msg), `unknown notification kind "loan.unknown"`)
+
+	msg, _ = outbox.New(FundedNotification, "not an event", time.Now())
+	assert.ErrorContains(t, svc.DeliverNotification(msg), "decode loan.funded notification")
+}
+
+// recordingPublisher is an EventPublisher that keeps every event it is given.
+type recordingPublisher struct {
+	events []Event
+}
+
+func (p *recordingPublisher) Publish(event Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestEventPublishing(t *testing.T) {
+	publisher := &recordingPublisher{}
+	svc := NewLoanService(NewInMemoryLoanRepository(), &mockEmailSender{}, WithEventPublisher(publisher))
+
+	ln, _ := svc.CreateLoan("B900", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP900", ApprovalDate: time.Now()})
+	_, _ = svc.InvestLoan(ln.ID, Investor{ID: "INV1", Amount: idr(400)})
+	invested, _ := svc.InvestLoan(ln.ID, Investor{ID: "INV2", Amount: idr(600)})
+	_, err := svc.DisburseLoan(ln.ID, Disbursement{AgreementFile: "signed.pdf", FieldOfficerID: "EMP901", DisbursementDate: time.Now()}, "link")
+	assert.NoError(t, err)
+
+	assert.Empty(t, publisher.events, "events go through the outbox")
+	deliverNotifications(t, svc)
+
+	var types []EventType
+	for _, e := range publisher.events {
+		types = append(types, e.Type)
+		assert.NotEmpty(t, e.ID)
+		assert.Equal(t, ln.ID, e.Loan.ID)
+	}
+	assert.Equal(t, []EventType{ApprovedEvent, InvestedEvent, InvestedEvent, FundedEvent, DisbursedEvent}, types)
+
+	if assert.NotNil(t, publisher.events[2].Investment) {
+		assert.Equal(t, "INV2", publisher.events[2].Investment.ID)
+	}
+	assert.Nil(t, publisher.events[3].Investment)
+	assert.Equal(t, Invested, publisher.events[3].Loan.State)
+	assert.Equal(t, invested.Version, publisher.events[3].Loan.Version)
+	assert.Equal(t, Disbursed, publisher.events[4].Loan.State)
+}
+
+func TestEventPublishing_Disabled(t *testing.T) {
+	svc, _ := setupTestService()
+	ln, _ := svc.CreateLoan("B901", idr(1000), 10, 8)
+	_, _ = svc.ApproveLoan(ln.ID, Approval{PhotoProofURL: "proof", ValidatorID: "EMP900", ApprovalDate: time.Now()})
+
+	pending, _ := svc.ListNotifications(outbox.Pending)
+	for _, msg := range pending {
+		assert.NotEqual(t, EventMessage, msg.Kind, "no events are queued without a publisher")
+	}
+
+	msg, _ := outbox.New(EventMessage, Event{Type: ApprovedEvent}, time.Now())
+	assert.EqualError(t, svc.DeliverNotification(msg), "no event publisher configured")
+}
@@ -0,0 +1,85 @@
+package loan
+
+import (
+	"database/sql"
+	"fmt"
+
+	"loan-service/core/money"
+	"loan-service/database"
+)
+
+// SQLPayoutRepository stores payouts in the investor_payouts table.
+type SQLPayoutRepository struct {
+	db *database.DB
+}
+
+// NewSQLPayoutRepository creates a payout repository on top of an already migrated database.
+func NewSQLPayoutRepository(db *database.DB) *SQLPayoutRepository {
+	return &SQLPayoutRepository{db: db}
+}
+
+// Save stores payouts in one transaction, skipping any whose ID already exists.
+func (r *SQLPayoutRepository) Save(payouts []Payout) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for i, p := range payouts {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO investor_payouts
+			(id, loan_id, repayment_id, investor_id, position, currency, principal_minor, return_minor, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO NOTHING`),
+			p.ID, p.LoanID, p.RepaymentID, p.InvestorID, i, string(p.Amount.Currency()),
+			p.Principal.MinorUnits(), p.Return.MinorUnits(), p.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("insert payout: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListByLoan returns the payouts of a loan in the order they were recorded.
+func (r *SQLPayoutRepository) ListByLoan(loanID string) ([]Payout, error) {
+	return r.list(`loan_id = ?`, loanID)
+}
+
+// ListByInvestor returns the payouts of an investor in the order they were recorded.
+func (r *SQLPayoutRepository) ListByInvestor(investorID string) ([]Payout, error) {
+	return r.list(`investor_id = ?`, investorID)
+}
+
+func (r *SQLPayoutRepository) list(where string, args ...any) ([]Payout, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT id, loan_id, repayment_id, investor_id, currency, principal_minor, return_minor, created_at
+		FROM investor_payouts WHERE `+where+` ORDER BY created_at, repayment_id, position`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query payouts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	payouts := []Payout{}
+	for rows.Next() {
+		p, err := scanPayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, rows.Err()
+}
+
+func scanPayout(rows *sql.Rows) (Payout, error) {
+	var (
+		p              Payout
+		currency       string
+		principal, ret int64
+	)
+	if err := rows.Scan(&p.ID, &p.LoanID, &p.RepaymentID, &p.InvestorID, &currency, &principal, &ret, &p.CreatedAt); err != nil {
+		return Payout{}, fmt.Errorf("scan payout: %w", err)
+	}
+	cur := money.Currency(currency)
+	p.Principal = money.New(principal, cur)
+	p.Return = money.New(ret, cur)
+	p.Amount = p.Principal.Add(p.Return)
+	return p, nil
+}
@@ -0,0 +1,602 @@
+package loan
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"loan-service/core/audit"
+	"loan-service/core/money"
+	"loan-service/core/outbox"
+	"loan-service/database"
+)
+
+// SQLLoanRepository persists loans through database/sql.
+// A loan is stored in normalized tables: loans, loan_approvals, loan_disbursements, loan_closures,
+// loan_investors, loan_installments and loan_repayments.
+// It works on both SQLite and Postgres; see the database package for schema migrations.
+type SQLLoanRepository struct {
+	db *database.DB
+}
+
+// NewSQLLoanRepository creates a repository on top of an already migrated database.
+func NewSQLLoanRepository(db *database.DB) *SQLLoanRepository {
+	return &SQLLoanRepository{db: db}
+}
+
+// childTables hold rows owned by a loan; they are rewritten on every update.
+var childTables = []string{
+	"loan_approvals", "loan_disbursements", "loan_closures", "loan_investors", "loan_installments", "loan_repayments",
+}
+
+// Create inserts a new loan and its audit event, and assigns it a unique ID.
+func (r *SQLLoanRepository) Create(loan *Loan, event *audit.Event) error {
+	loan.ID = uuid.NewString()
+	now := r.now()
+	loan.CreatedAt = now
+	loan.UpdatedAt = now
+	loan.State = Proposed
+	loan.Version = 1
+
+	cols, vals := loanColumns(loan)
+	cols = append(cols, "id", "version", "created_at", "updated_at")
+	vals = append(vals, loan.ID, loan.Version, loan.CreatedAt, loan.UpdatedAt)
+
+	return r.inTx(func(tx *sql.Tx) error {
+		query := `INSERT INTO loans (` + strings.Join(cols, ", ") + `) VALUES (?` + strings.Repeat(", ?", len(cols)-1) + `)`
+		if _, err := tx.Exec(r.db.Dialect.Rebind(query), vals...); err != nil {
+			return fmt.Errorf("insert loan: %w", err)
+		}
+		if err := r.saveChildren(tx, loan); err != nil {
+			return err
+		}
+		return r.appendTx(tx, loan.ID, loan.Version, event)
+	})
+}
+
+// GetByID retrieves a loan by its ID. Returns error if not found.
+func (r *SQLLoanRepository) GetByID(id string) (*Loan, error) {
+	loans, err := r.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(loans) == 0 {
+		return nil, ErrLoanNotFound
+	}
+	return loans[0], nil
+}
+
+// Update updates an existing loan together with all of its child rows, the audit event and the given
+// outbox messages, in one transaction.
+// The write is conditional on the stored version, so concurrent writers from other processes are detected too.
+func (r *SQLLoanRepository) Update(loan *Loan, event *audit.Event, messages ...outbox.Message) error {
+	updatedAt := r.now()
+
+	cols, vals := loanColumns(loan)
+	assignments := make([]string, len(cols))
+	for i, col := range cols {
+		assignments[i] = col + " = ?"
+	}
+	vals = append(vals, updatedAt, loan.ID, loan.Version)
+
+	err := r.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(r.db.Dialect.Rebind(`UPDATE loans SET `+strings.Join(assignments, ", ")+
+			`, version = version + 1, updated_at = ? WHERE id = ? AND version = ?`), vals...)
+		if err != nil {
+			return fmt.Errorf("update loan: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return r.updateMiss(tx, loan)
+		}
+
+		for _, table := range childTables {
+			if _, err := tx.Exec(r.db.Dialect.Rebind(`DELETE FROM `+table+` WHERE loan_id = ?`), loan.ID); err != nil {
+				return fmt.Errorf("clear %s: %w", table, err)
+			}
+		}
+		if err := r.saveChildren(tx, loan); err != nil {
+			return err
+		}
+		if err := r.appendTx(tx, loan.ID, loan.Version+1, event); err != nil {
+			return err
+		}
+		return outbox.InsertTx(tx, r.db.Dialect, messages)
+	})
+	if err != nil {
+		return err
+	}
+
+	loan.Version++
+	loan.UpdatedAt = updatedAt
+	return nil
+}
+
+// updateMiss explains why a conditional UPDATE touched no rows.
+func (r *SQLLoanRepository) updateMiss(tx *sql.Tx, loan *Loan) error {
+	var version int64
+	err := tx.QueryRow(r.db.Dialect.Rebind(`SELECT version FROM loans WHERE id = ?`), loan.ID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w for update", ErrLoanNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("load loan version: %w", err)
+	}
+	return &ConflictError{LoanID: loan.ID, Expected: loan.Version, Actual: version}
+}
+
+// appendTx writes event, if any, for the loan stored at version in tx.
+func (r *SQLLoanRepository) appendTx(tx *sql.Tx, loanID string, version int64, event *audit.Event) error {
+	if event == nil {
+		return nil
+	}
+	event.LoanID, event.LoanVersion = loanID, version
+	return audit.InsertTx(tx, r.db.Dialect, *event)
+}
+
+// Outbox returns the store for messages written by Update, in the same database.
+func (r *SQLLoanRepository) Outbox() outbox.Store {
+	return outbox.NewSQLStore(r.db)
+}
+
+// Audit returns the trail of events written by Create and Update, in the same database.
+func (r *SQLLoanRepository) Audit() audit.Repository {
+	return audit.NewSQLRepository(r.db)
+}
+
+// List returns all loans ordered by creation time.
+func (r *SQLLoanRepository) List() ([]*Loan, error) {
+	return r.query(`ORDER BY created_at, id`)
+}
+
+// Search returns a page of the loans matching query. Filtering, ordering and paging happen in the
+// database, using keyset pagination on the sort column and ID.
+func (r *SQLLoanRepository) Search(query LoanQuery) (*LoanPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	conds, args := searchFilters(query)
+
+	var total int
+	if err := r.db.QueryRow(r.db.Dialect.Rebind(`SELECT COUNT(*) FROM loans`+where(conds)), args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("count loans: %w", err)
+	}
+
+	column, op, dir := "created_at", ">", "ASC"
+	if query.Sort == SortByPrincipal {
+		column = "principal_minor"
+	}
+	if query.Descending {
+		op, dir = "<", "DESC"
+	}
+	if query.Cursor != "" {
+		c, err := query.after()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := c.key()
+		if t, ok := key.(time.Time); ok {
+			key = t.UTC()
+		}
+		conds = append(conds, fmt.Sprintf(`(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))`, column, op))
+		args = append(args, key, key, c.ID)
+	}
+
+	clause := fmt.Sprintf(`%s ORDER BY %s %s, id %s LIMIT ?`, where(conds), column, dir, dir)
+	loans, err := r.query(clause, append(args, query.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	page := &LoanPage{Loans: []*Loan{}, Total: total}
+	if len(loans) > query.Limit {
+		loans = loans[:query.Limit]
+		page.NextCursor = query.cursorAfter(loans[len(loans)-1])
+	}
+	page.Loans = append(page.Loans, loans...)
+	return page, nil
+}
+
+// searchFilters turns the filters of query into SQL conditions on the loans table and their arguments.
+func searchFilters(query LoanQuery) ([]string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if len(query.States) > 0 {
+		conds = append(conds, `state IN (?`+strings.Repeat(", ?", len(query.States)-1)+`)`)
+		for _, state := range query.States {
+			args = append(args, string(state))
+		}
+	}
+	if query.BorrowerID != "" {
+		conds = append(conds, `borrower_id = ?`)
+		args = append(args, query.BorrowerID)
+	}
+	if query.InvestorID != "" {
+		conds = append(conds, `id IN (SELECT loan_id FROM loan_investors WHERE investor_id = ?)`)
+		args = append(args, query.InvestorID)
+	}
+	if query.DocumentID != "" {
+		conds = append(conds, `(agreement_letter_link = ? OR agreement_letter_link LIKE ? ESCAPE '\'
+			OR id IN (SELECT loan_id FROM loan_approvals WHERE photo_proof_url = ?)
+			OR id IN (SELECT loan_id FROM loan_disbursements WHERE agreement_letter_file = ?))`)
+		args = append(args, query.DocumentID, "%"+likeEscaper.Replace(LetterLink("", query.DocumentID)),
+			query.DocumentID, query.DocumentID)
+	}
+	if p := query.Party; p != nil {
+		visible := []string{`1 = 0`} // Matches nothing when the party has neither ID
+		if p.BorrowerID != "" {
+			visible = append(visible, `borrower_id = ?`)
+			args = append(args, p.BorrowerID)
+		}
+		if p.InvestorID != "" {
+			visible = append(visible, `state = ?`, `id IN (SELECT loan_id FROM loan_investors WHERE investor_id = ?)`)
+			args = append(args, string(Approved), p.InvestorID)
+		}
+		conds = append(conds, `(`+strings.Join(visible, ` OR `)+`)`)
+	}
+	if query.CreatedFrom != nil {
+		conds = append(conds, `created_at >= ?`)
+		args = append(args, query.CreatedFrom.UTC())
+	}
+	if query.CreatedTo != nil {
+		conds = append(conds, `created_at < ?`)
+		args = append(args, query.CreatedTo.UTC())
+	}
+	if m := query.MinAmount; m != nil {
+		conds = append(conds, `currency = ? AND principal_minor >= ?`)
+		args = append(args, string(m.Currency()), m.MinorUnits())
+	}
+	if m := query.MaxAmount; m != nil {
+		conds = append(conds, `currency = ? AND principal_minor <= ?`)
+		args = append(args, string(m.Currency()), m.MinorUnits())
+	}
+	return conds, args
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, for use with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// where joins conditions into a WHERE clause; it is empty without conditions.
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `)
+}
+
+// ListFundingOverdue returns approved loans whose funding deadline has passed at the given time.
+func (r *SQLLoanRepository) ListFundingOverdue(at time.Time) ([]*Loan, error) {
+	return r.query(`WHERE state = ? AND funding_deadline IS NOT NULL AND funding_deadline <= ? ORDER BY funding_deadline, id`,
+		string(Approved), at.UTC())
+}
+
+// Totals sums up the stored loans by state and currency, ordered by state and currency. The sums are
+// computed by the database in a single query.
+func (r *SQLLoanRepository) Totals() ([]LoanTotals, error) {
+	var totals []LoanTotals
+	err := r.each(`SELECT state, currency, COUNT(*), COALESCE(SUM(principal_minor), 0),
+		COALESCE(SUM(total_invested_minor), 0), COALESCE(SUM(outstanding_principal_minor), 0)
+		FROM loans GROUP BY state, currency ORDER BY state, currency`, nil, func(rows *sql.Rows) error {
+		var t LoanTotals
+		var state, currency string
+		var principal, invested, outstanding int64
+		if err := rows.Scan(&state, &currency, &t.Count, &principal, &invested, &outstanding); err != nil {
+			return err
+		}
+		t.State, t.Currency = LoanState(state), money.Currency(currency)
+		t.Principal = money.New(principal, t.Currency)
+		t.Invested = money.New(invested, t.Currency)
+		t.Outstanding = money.New(outstanding, t.Currency)
+		totals = append(totals, t)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("total loans: %w", err)
+	}
+	return totals, nil
+}
+
+// loanColumns maps the mutable fields of a loan onto columns of the loans table.
+// It is shared by INSERT and UPDATE so both always write the same set of columns.
+func loanColumns(loan *Loan) ([]string, []any) {
+	var outFees, outInterest, outPrincipal any
+	if b := loan.Outstanding; b != nil {
+		outFees, outInterest, outPrincipal = b.Fees.MinorUnits(), b.Interest.MinorUnits(), b.Principal.MinorUnits()
+	}
+	var deadline any
+	if d := loan.FundingDeadline; d != nil {
+		deadline = d.UTC()
+	}
+
+	cols := []string{
+		"borrower_id", "currency", "principal_minor", "rate", "roi", "agreement_letter_link", "state",
+		"total_invested_minor", "repayment_method", "tenor", "installment_fee_minor",
+		"outstanding_fees_minor", "outstanding_interest_minor", "outstanding_principal_minor",
+		"funding_deadline",
+	}
+	vals := []any{
+		loan.BorrowerID, string(currencyOf(loan)), loan.PrincipalAmount.MinorUnits(), loan.Rate, loan.ROI, loan.AgreementLetterURL, string(loan.State),
+		loan.TotalInvested.MinorUnits(), string(loan.Terms.Method), loan.Terms.Tenor, loan.Terms.InstallmentFee.MinorUnits(),
+		outFees, outInterest, outPrincipal,
+		deadline,
+	}
+	return cols, vals
+}
+
+// loanSelect lists the loans columns read by scanLoan, in order.
+const loanSelect = `id, borrower_id, currency, principal_minor, rate, roi, agreement_letter_link, state,
+	total_invested_minor, repayment_method, tenor, installment_fee_minor,
+	outstanding_fees_minor, outstanding_interest_minor, outstanding_principal_minor,
+	funding_deadline, version, created_at, updated_at`
+
+// scanLoan reads a row selected with loanSelect.
+func scanLoan(rows *sql.Rows) (*Loan, error) {
+	var (
+		l                                  Loan
+		state, currency, method            string
+		principal, totalInvested, fee      int64
+		outFees, outInterest, outPrincipal sql.NullInt64
+		deadline                           sql.NullTime
+	)
+	if err := rows.Scan(&l.ID, &l.BorrowerID, &currency, &principal, &l.Rate, &l.ROI, &l.AgreementLetterURL, &state,
+		&totalInvested, &method, &l.Terms.Tenor, &fee,
+		&outFees, &outInterest, &outPrincipal,
+		&deadline, &l.Version, &l.CreatedAt, &l.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("scan loan: %w", err)
+	}
+
+	cur := money.Currency(currency)
+	l.State = LoanState(state)
+	l.PrincipalAmount = money.New(principal, cur)
+	l.TotalInvested = money.New(totalInvested, cur)
+	l.Terms.Method = RepaymentMethod(method)
+	l.Terms.InstallmentFee = money.New(fee, cur)
+	if outFees.Valid && outInterest.Valid && outPrincipal.Valid {
+		l.Outstanding = &Balance{
+			Fees:      money.New(outFees.Int64, cur),
+			Interest:  money.New(outInterest.Int64, cur),
+			Principal: money.New(outPrincipal.Int64, cur),
+		}
+	}
+	if deadline.Valid {
+		d := deadline.Time
+		l.FundingDeadline = &d
+	}
+	return &l, nil
+}
+
+// saveChildren writes the rows that hang off the loans table.
+func (r *SQLLoanRepository) saveChildren(tx *sql.Tx, loan *Loan) error {
+	if a := loan.Approval; a != nil {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO loan_approvals
+			(loan_id, photo_proof_url, field_validator_id, approval_date) VALUES (?, ?, ?, ?)`),
+			loan.ID, a.PhotoProofURL, a.ValidatorID, a.ApprovalDate.UTC()); err != nil {
+			return fmt.Errorf("insert approval: %w", err)
+		}
+	}
+
+	if d := loan.Disbursement; d != nil {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO loan_disbursements
+			(loan_id, agreement_letter_file, field_officer_id, disbursement_date) VALUES (?, ?, ?, ?)`),
+			loan.ID, d.AgreementFile, d.FieldOfficerID, d.DisbursementDate.UTC()); err != nil {
+			return fmt.Errorf("insert disbursement: %w", err)
+		}
+	}
+
+	if cl := loan.Closure; cl != nil {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO loan_closures
+			(loan_id, reason, staff_id, closed_at) VALUES (?, ?, ?, ?)`),
+			loan.ID, cl.Reason, cl.StaffID, cl.ClosedAt.UTC()); err != nil {
+			return fmt.Errorf("insert closure: %w", err)
+		}
+	}
+
+	for i, inv := range loan.Investors {
+		status := inv.Status
+		if status == "" {
+			status = Committed
+		}
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO loan_investors
+			(loan_id, position, investor_id, amount_minor, status) VALUES (?, ?, ?, ?, ?)`),
+			loan.ID, i, inv.ID, inv.Amount.MinorUnits(), string(status)); err != nil {
+			return fmt.Errorf("insert investor: %w", err)
+		}
+	}
+
+	for _, inst := range loan.Installments {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO loan_installments
+			(loan_id, number, due_date, principal_minor, interest_minor, fee_minor, balance_minor) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			loan.ID, inst.Number, inst.DueDate.UTC(), inst.Principal.MinorUnits(), inst.Interest.MinorUnits(),
+			inst.Fee.MinorUnits(), inst.Balance.MinorUnits()); err != nil {
+			return fmt.Errorf("insert installment: %w", err)
+		}
+	}
+
+	for i, rep := range loan.Repayments {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO loan_repayments
+			(id, loan_id, position, amount_minor, fees_minor, interest_minor, principal_minor, reference, paid_at, recorded_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			rep.ID, loan.ID, i, rep.Amount.MinorUnits(), rep.Fees.MinorUnits(), rep.Interest.MinorUnits(),
+			rep.Principal.MinorUnits(), rep.Reference, rep.PaidAt.UTC(), rep.RecordedAt.UTC()); err != nil {
+			return fmt.Errorf("insert repayment: %w", err)
+		}
+	}
+	return nil
+}
+
+// query loads loans matching the given clause (appended to the base SELECT) along with their child rows.
+func (r *SQLLoanRepository) query(clause string, args ...any) ([]*Loan, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT `+loanSelect+` FROM loans `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query loans: %w", err)
+	}
+
+	var loans []*Loan
+	for rows.Next() {
+		l, err := scanLoan(rows)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		loans = append(loans, l)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, l := range loans {
+		if err := r.loadChildren(l); err != nil {
+			return nil, err
+		}
+	}
+	return loans, nil
+}
+
+// loadChildren populates approval, disbursement, closure, investors, installments and repayments of a loan.
+func (r *SQLLoanRepository) loadChildren(loan *Loan) error {
+	currency := loan.PrincipalAmount.Currency()
+
+	var a Approval
+	err := r.db.QueryRow(r.db.Dialect.Rebind(`SELECT photo_proof_url, field_validator_id, approval_date
+		FROM loan_approvals WHERE loan_id = ?`), loan.ID).Scan(&a.PhotoProofURL, &a.ValidatorID, &a.ApprovalDate)
+	switch {
+	case err == nil:
+		loan.Approval = &a
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load approval: %w", err)
+	}
+
+	var d Disbursement
+	err = r.db.QueryRow(r.db.Dialect.Rebind(`SELECT agreement_letter_file, field_officer_id, disbursement_date
+		FROM loan_disbursements WHERE loan_id = ?`), loan.ID).Scan(&d.AgreementFile, &d.FieldOfficerID, &d.DisbursementDate)
+	switch {
+	case err == nil:
+		loan.Disbursement = &d
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load disbursement: %w", err)
+	}
+
+	var cl Closure
+	err = r.db.QueryRow(r.db.Dialect.Rebind(`SELECT reason, staff_id, closed_at
+		FROM loan_closures WHERE loan_id = ?`), loan.ID).Scan(&cl.Reason, &cl.StaffID, &cl.ClosedAt)
+	switch {
+	case err == nil:
+		loan.Closure = &cl
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("load closure: %w", err)
+	}
+
+	err = r.each(`SELECT investor_id, amount_minor, status FROM loan_investors WHERE loan_id = ? ORDER BY position`,
+		[]any{loan.ID}, func(rows *sql.Rows) error {
+			var (
+				inv    Investor
+				amount int64
+				status string
+			)
+			if err := rows.Scan(&inv.ID, &amount, &status); err != nil {
+				return err
+			}
+			inv.Amount = money.New(amount, currency)
+			inv.Status = CommitmentStatus(status)
+			loan.Investors = append(loan.Investors, inv)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("load investors: %w", err)
+	}
+
+	err = r.each(`SELECT number, due_date, principal_minor, interest_minor, fee_minor, balance_minor
+		FROM loan_installments WHERE loan_id = ? ORDER BY number`,
+		[]any{loan.ID}, func(rows *sql.Rows) error {
+			var (
+				inst                              Installment
+				principal, interest, fee, balance int64
+			)
+			if err := rows.Scan(&inst.Number, &inst.DueDate, &principal, &interest, &fee, &balance); err != nil {
+				return err
+			}
+			inst.Principal = money.New(principal, currency)
+			inst.Interest = money.New(interest, currency)
+			inst.Fee = money.New(fee, currency)
+			inst.Amount = inst.Principal.Add(inst.Interest).Add(inst.Fee)
+			inst.Balance = money.New(balance, currency)
+			loan.Installments = append(loan.Installments, inst)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("load installments: %w", err)
+	}
+
+	err = r.each(`SELECT id, amount_minor, fees_minor, interest_minor, principal_minor, reference, paid_at, recorded_at
+		FROM loan_repayments WHERE loan_id = ? ORDER BY position`,
+		[]any{loan.ID}, func(rows *sql.Rows) error {
+			var (
+				rep                               Repayment
+				amount, fees, interest, principal int64
+			)
+			if err := rows.Scan(&rep.ID, &amount, &fees, &interest, &principal, &rep.Reference, &rep.PaidAt, &rep.RecordedAt); err != nil {
+				return err
+			}
+			rep.Amount = money.New(amount, currency)
+			rep.Fees = money.New(fees, currency)
+			rep.Interest = money.New(interest, currency)
+			rep.Principal = money.New(principal, currency)
+			loan.Repayments = append(loan.Repayments, rep)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("load repayments: %w", err)
+	}
+	return nil
+}
+
+// each runs a query and calls fn for every row.
+func (r *SQLLoanRepository) each(query string, args []any, fn func(rows *sql.Rows) error) error {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// inTx runs fn inside a transaction, committing on success and rolling back otherwise.
+func (r *SQLLoanRepository) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// currencyOf returns the loan currency, falling back to the default for loans created without one.
+func currencyOf(loan *Loan) money.Currency {
+	if c := loan.PrincipalAmount.Currency(); c != "" {
+		return c
+	}
+	return money.DefaultCurrency
+}
+
+// now returns the current time normalised to what every supported database can store without loss.
+func (r *SQLLoanRepository) now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -8,16 +8,23 @@ import "fmt"
 //   - Proposed  → Approved
 //   - Approved  → Invested
 //   - Invested  → Disbursed
+//   - Disbursed → Repaid
+//   - Proposed  → Rejected
+//   - Proposed  → Cancelled
+//   - Approved  → Cancelled
+//   - Approved  → Expired
 //
-// Backward or invalid transitions are not allowed.
+// Backward or invalid transitions are not allowed. Repaid, Rejected, Cancelled and Expired are terminal.
 func CanTransition(from, to LoanState) bool {
 	switch from {
 	case Proposed:
-		return to == Approved
+		return to == Approved || to == Rejected || to == Cancelled
 	case Approved:
-		return to == Invested
+		return to == Invested || to == Cancelled || to == Expired
 	case Invested:
 		return to == Disbursed
+	case Disbursed:
+		return to == Repaid
 	default:
 		return false
 	}
@@ -15,8 +15,21 @@ func TestLoanStateTransitions(t *testing.T) {
 		{"Proposed to Approved", Proposed, Approved, true},
 		{"Approved to Invested", Approved, Invested, true},
 		{"Invested to Disbursed", Invested, Disbursed, true},
+		{"Disbursed to Repaid", Disbursed, Repaid, true},
+		{"Invested to Repaid", Invested, Repaid, false},
+		{"Repaid to Disbursed", Repaid, Disbursed, false},
 		{"Proposed to Disbursed", Proposed, Disbursed, false},
 		{"Disbursed to Proposed", Disbursed, Proposed, false},
+		{"Proposed to Rejected", Proposed, Rejected, true},
+		{"Approved to Rejected", Approved, Rejected, false},
+		{"Proposed to Cancelled", Proposed, Cancelled, true},
+		{"Approved to Cancelled", Approved, Cancelled, true},
+		{"Invested to Cancelled", Invested, Cancelled, false},
+		{"Approved to Expired", Approved, Expired, true},
+		{"Proposed to Expired", Proposed, Expired, false},
+		{"Rejected to Approved", Rejected, Approved, false},
+		{"Cancelled to Approved", Cancelled, Approved, false},
+		{"Expired to Invested", Expired, Invested, false},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,63 @@
+package loan
+
+import "loan-service/core/money"
+
+// Stats is a snapshot of the loan book, for monitoring.
+type Stats struct {
+	Loans       map[LoanState]int              // Number of loans in each state, including those with none
+	Outstanding map[money.Currency]money.Money // Principal still owed on disbursed loans
+	Funding     map[money.Currency]Funding     // Loans raising money from investors, i.e. Approved
+}
+
+// Funding sums up the loans of one currency that are open for investment.
+type Funding struct {
+	Principal money.Money // What the loans are raising in total
+	Committed money.Money // What investors have committed to them so far
+}
+
+// LoanTotals sums up the loans in one state and currency.
+type LoanTotals struct {
+	State       LoanState
+	Currency    money.Currency
+	Count       int
+	Principal   money.Money // Principal of the loans
+	Invested    money.Money // Money committed by investors
+	Outstanding money.Money // Principal still owed, zero unless the loans are being repaid
+}
+
+// Stats counts loans by state and totals the outstanding principal and funding progress by currency.
+// It reads the totals the repository keeps per state and currency, not the loans themselves.
+func (s *LoanService) Stats() (*Stats, error) {
+	totals, err := s.repo.Totals()
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{
+		Loans:       make(map[LoanState]int, len(AllStates)),
+		Outstanding: make(map[money.Currency]money.Money),
+		Funding:     make(map[money.Currency]Funding),
+	}
+	for _, state := range AllStates {
+		stats.Loans[state] = 0
+	}
+	for _, t := range totals {
+		stats.Loans[t.State] += t.Count
+		switch t.State {
+		case Disbursed:
+			total, ok := stats.Outstanding[t.Currency]
+			if !ok {
+				total = money.Zero(t.Currency)
+			}
+			stats.Outstanding[t.Currency] = total.Add(t.Outstanding)
+		case Approved:
+			f, ok := stats.Funding[t.Currency]
+			if !ok {
+				f = Funding{Principal: money.Zero(t.Currency), Committed: money.Zero(t.Currency)}
+			}
+			f.Principal = f.Principal.Add(t.Principal)
+			f.Committed = f.Committed.Add(t.Invested)
+			stats.Funding[t.Currency] = f
+		}
+	}
+	return stats, nil
+}
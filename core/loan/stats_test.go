@@ -0,0 +1,53 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/money"
+)
+
+func TestStats(t *testing.T) {
+	svc, _ := setupTestService()
+	approval := Approval{PhotoProofURL: "proof", ValidatorID: "EMP001", ApprovalDate: time.Now()}
+
+	stats, err := svc.Stats()
+	require.NoError(t, err)
+	assert.Len(t, stats.Loans, len(AllStates))
+	assert.Zero(t, stats.Loans[Proposed])
+	assert.Empty(t, stats.Outstanding)
+	assert.Empty(t, stats.Funding)
+
+	_, err = svc.CreateLoan("B001", idr(1000000), 10, 8)
+	require.NoError(t, err)
+	for _, principal := range []money.Money{idr(2000000), idr(3000000), money.FromMajor(500, money.USD)} {
+		ln, err := svc.CreateLoan("B002", principal, 10, 8)
+		require.NoError(t, err)
+		_, err = svc.ApproveLoan(ln.ID, approval)
+		require.NoError(t, err)
+		_, err = svc.InvestLoan(ln.ID, Investor{ID: "INV001", Amount: principal.Percent(25)})
+		require.NoError(t, err)
+	}
+	repaying := disbursedLoan(t, svc, 1200000, RepaymentTerms{Method: FlatMethod, Tenor: 12})
+	_, err = svc.RecordRepayment(repaying.ID, Repayment{Amount: idr(300000), PaidAt: time.Now()})
+	require.NoError(t, err)
+	disbursedLoan(t, svc, 800000, RepaymentTerms{Method: FlatMethod, Tenor: 12})
+
+	stats, err = svc.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, map[LoanState]int{
+		Proposed: 1, Approved: 3, Invested: 0, Disbursed: 2, Repaid: 0, Rejected: 0, Cancelled: 0, Expired: 0,
+	}, stats.Loans)
+
+	outstanding := stats.Outstanding[money.IDR]
+	assert.True(t, outstanding.Cmp(idr(2000000)) < 0, "repayments reduce the outstanding principal")
+	assert.True(t, outstanding.Cmp(idr(800000)) > 0)
+	assert.Len(t, stats.Outstanding, 1)
+
+	assert.Equal(t, map[money.Currency]Funding{
+		money.IDR: {Principal: idr(5000000), Committed: idr(1250000)},
+		money.USD: {Principal: money.FromMajor(500, money.USD), Committed: money.FromMajor(125, money.USD)},
+	}, stats.Funding)
+}
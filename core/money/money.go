@@ -0,0 +1,451 @@
+// Package money implements an exact fixed-point representation of monetary amounts.
+//
+// An amount is stored as an integer number of minor units (e.g. sen for IDR, cents for USD)
+// together with its currency, so adding, subtracting and comparing amounts is always exact.
+//
+// Rounding rules:
+//
+//  1. Parsing never rounds. A decimal with more fractional digits than the currency's
+//     minor unit allows (e.g. "10.005" IDR) is rejected with ErrPrecision.
+//  2. Applying a percentage (interest, ROI) rounds the result to the nearest minor unit,
+//     with ties going to the even neighbour (banker's rounding). See Money.Percent.
+//  3. Splitting an amount never creates or loses money. The shares from Money.Allocate always
+//     sum to the original amount; leftover minor units go to the largest fractional remainders
+//     first and, on ties, to the earliest share.
+//  4. Arithmetic between two different currencies is a programming error and panics;
+//     inputs must be checked with SameCurrency at the boundary. The zero Money has no
+//     currency and is compatible with every currency.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+const (
+	// IDR is the Indonesian Rupiah.
+	IDR Currency = "IDR"
+
+	// USD is the US Dollar.
+	USD Currency = "USD"
+
+	// SGD is the Singapore Dollar.
+	SGD Currency = "SGD"
+
+	// JPY is the Japanese Yen, which has no minor unit.
+	JPY Currency = "JPY"
+)
+
+// DefaultCurrency is assumed when an amount is given without a currency.
+const DefaultCurrency = IDR
+
+// exponents holds the number of minor-unit digits of each supported currency.
+var exponents = map[Currency]int{
+	IDR: 2,
+	USD: 2,
+	SGD: 2,
+	JPY: 0,
+}
+
+var (
+	// ErrPrecision is returned when an amount has more fractional digits than its currency allows.
+	ErrPrecision = errors.New("amount has more decimal places than the currency allows")
+
+	// ErrOverflow is returned when an amount does not fit into 64-bit minor units.
+	ErrOverflow = errors.New("amount is out of range")
+)
+
+// ParseCurrency validates a currency code. An empty code yields DefaultCurrency.
+func ParseCurrency(code string) (Currency, error) {
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	c := Currency(strings.ToUpper(code))
+	if _, ok := exponents[c]; !ok {
+		return "", fmt.Errorf("unsupported currency: %s", code)
+	}
+	return c, nil
+}
+
+// Exponent returns the number of minor-unit digits of the currency.
+func (c Currency) Exponent() int {
+	return exponents[c]
+}
+
+// Money is an exact amount of a single currency.
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+// New creates an amount from minor units, e.g. New(150, USD) is USD 1.50.
+func New(minor int64, currency Currency) Money {
+	return Money{amount: minor, currency: currency}
+}
+
+// FromMajor creates an amount from whole major units, e.g. FromMajor(5000000, IDR) is IDR 5,000,000.00.
+func FromMajor(major int64, currency Currency) Money {
+	return Money{amount: major * pow10(currency.Exponent()), currency: currency}
+}
+
+// Zero returns a zero amount of the given currency.
+func Zero(currency Currency) Money {
+	return Money{currency: currency}
+}
+
+// Parse reads a plain decimal string such as "1500000", "-12.5" or "99.99" in the given currency.
+// Exponents, thousands separators and excess fractional digits are rejected.
+func Parse(s string, currency Currency) (Money, error) {
+	exp := currency.Exponent()
+	if _, ok := exponents[currency]; !ok {
+		return Money{}, fmt.Errorf("unsupported currency: %s", currency)
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(s, "-")
+	whole, frac, hasFrac := strings.Cut(digits, ".")
+	if whole == "" || (hasFrac && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid amount: %q", s)
+	}
+	if len(frac) > exp {
+		if strings.TrimRight(frac[exp:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q (%s allows %d)", ErrPrecision, s, currency, exp)
+		}
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	if neg {
+		minor = -minor
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for tests and constants.
+func MustParse(s string, currency Currency) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MinorUnits returns the amount in minor units.
+func (m Money) MinorUnits() int64 {
+	return m.amount
+}
+
+// Currency returns the currency of the amount.
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.amount > 0
+}
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+// SameCurrency reports whether m and o can be combined. The zero Money is compatible with any currency.
+func (m Money) SameCurrency(o Money) bool {
+	return m.currency == o.currency || m.currency == "" || o.currency == ""
+}
+
+// Add returns m + o. It panics with ErrOverflow if the sum does not fit; amounts that come from
+// requests must be added with CheckedAdd, or checked against a bound first.
+func (m Money) Add(o Money) Money {
+	sum, err := m.CheckedAdd(o)
+	if err != nil {
+		panic(err)
+	}
+	return sum
+}
+
+// CheckedAdd returns m + o, or ErrOverflow if the sum does not fit into 64-bit minor units.
+func (m Money) CheckedAdd(o Money) (Money, error) {
+	c := m.mustMatch(o)
+	sum := m.amount + o.amount
+	if (sum > m.amount) != (o.amount > 0) {
+		return Money{}, ErrOverflow
+	}
+	return Money{amount: sum, currency: c}, nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money {
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{amount: -m.amount, currency: m.currency}
+}
+
+// Cmp compares m and o and returns -1, 0 or +1.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.amount < o.amount:
+		return -1
+	case m.amount > o.amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Equal reports whether m and o are the same amount. Zero amounts are equal regardless of currency.
+func (m Money) Equal(o Money) bool {
+	return m.SameCurrency(o) && m.amount == o.amount
+}
+
+// Percent returns pct percent of m, rounded half to even to the currency's minor unit.
+// For example IDR 100.05 at 10% is IDR 10.00 (10.005 rounds to the even 10.00).
+func (m Money) Percent(pct float64) Money {
+	return m.MulRat(PercentRat(pct))
+}
+
+// PercentRat converts a percentage into the exact fraction it denotes, e.g. 12.5 → 1/8.
+// The float is read through its shortest decimal representation, so 10.1 becomes exactly 101/1000.
+func PercentRat(pct float64) *big.Rat {
+	return new(big.Rat).Quo(ratFromFloat(pct), big.NewRat(100, 1))
+}
+
+// MulRat returns m multiplied by r, rounded half to even to the currency's minor unit. It panics with
+// ErrOverflow if the product does not fit; factors that come from requests must go through CheckedMulRat.
+func (m Money) MulRat(r *big.Rat) Money {
+	product, err := m.CheckedMulRat(r)
+	if err != nil {
+		panic(err)
+	}
+	return product
+}
+
+// CheckedMulRat returns m multiplied by r like MulRat, or ErrOverflow if the product does not fit
+// into 64-bit minor units.
+func (m Money) CheckedMulRat(r *big.Rat) (Money, error) {
+	product, err := roundHalfEven(new(big.Rat).Mul(new(big.Rat).SetInt64(m.amount), r))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{amount: product, currency: m.currency}, nil
+}
+
+// Allocate splits m into len(weights) shares proportional to the weights.
+//
+// The shares always add up to m exactly. Each share first receives the rounded-down
+// proportional amount; the minor units left over are then handed out one at a time to the
+// shares with the largest fractional remainder, with ties going to the lower index.
+// Zero or negative weights receive nothing. If all weights are zero, every share is zero.
+func (m Money) Allocate(weights []int64) []Money {
+	shares := make([]Money, len(weights))
+	for i := range shares {
+		shares[i] = Money{currency: m.currency}
+	}
+
+	total := new(big.Int)
+	for _, w := range weights {
+		if w > 0 {
+			total.Add(total, big.NewInt(w))
+		}
+	}
+	if total.Sign() == 0 || len(weights) == 0 {
+		return shares
+	}
+
+	sign := int64(1)
+	amount := m.amount
+	if amount < 0 {
+		sign, amount = -1, -amount
+	}
+
+	remainders := make([]*big.Int, len(weights))
+	distributed := int64(0)
+	for i, w := range weights {
+		remainders[i] = new(big.Int)
+		if w <= 0 {
+			continue
+		}
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(amount), big.NewInt(w)), total, new(big.Int))
+		shares[i].amount = q.Int64()
+		remainders[i] = r
+		distributed += q.Int64()
+	}
+
+	for left := amount - distributed; left > 0; left-- {
+		best := -1
+		for i, w := range weights {
+			if w <= 0 {
+				continue
+			}
+			if best == -1 || remainders[i].Cmp(remainders[best]) > 0 {
+				best = i
+			}
+		}
+		shares[best].amount++
+		remainders[best] = new(big.Int).Sub(remainders[best], total)
+	}
+
+	for i := range shares {
+		shares[i].amount *= sign
+	}
+	return shares
+}
+
+// Decimal formats the amount as a plain decimal string with exactly the currency's number of fractional digits.
+func (m Money) Decimal() string {
+	exp := m.currency.Exponent()
+	abs := m.amount
+	sign := ""
+	if abs < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(absUint(abs), 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats the amount with its currency, e.g. "IDR 5000000.00".
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Decimal()
+	}
+	return string(m.currency) + " " + m.Decimal()
+}
+
+// jsonMoney is the wire representation of Money. The amount is a string so clients never see a float.
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"amount":"5000000.00","currency":"IDR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency := m.currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{Amount: Money{amount: m.amount, currency: currency}.Decimal(), Currency: currency})
+}
+
+// UnmarshalJSON accepts the object form produced by MarshalJSON, or a bare number or
+// numeric string which is interpreted in DefaultCurrency. Numbers are parsed exactly, never via float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var obj jsonMoney
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &obj.Amount); err != nil {
+		return fmt.Errorf("invalid amount: %s", data)
+	}
+
+	currency, err := ParseCurrency(obj.Currency)
+	if err != nil {
+		return err
+	}
+	parsed, err := Parse(obj.Amount.String(), currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) mustMatch(o Money) Currency {
+	if !m.SameCurrency(o) {
+		panic(fmt.Sprintf("money: currency mismatch: %s vs %s", m.currency, o.currency))
+	}
+	if m.currency == "" {
+		return o.currency
+	}
+	return m.currency
+}
+
+// ratFromFloat converts a float via its shortest decimal representation, so 10.1 becomes exactly 101/10.
+func ratFromFloat(f float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		panic(fmt.Sprintf("money: invalid percentage %v", f))
+	}
+	return r
+}
+
+// roundHalfEven rounds r to the nearest integer, with ties going to the even neighbour.
+// It fails with ErrOverflow if the result does not fit into an int64.
+func roundHalfEven(r *big.Rat) (int64, error) {
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// Compare 2*|rem| with den to decide between q and q±1.
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	switch c := twice.Cmp(den); {
+	case c > 0, c == 0 && q.Bit(0) == 1:
+		if rem.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return q.Int64(), nil
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+func absUint(n int64) uint64 {
+	if n == math.MinInt64 {
+		return uint64(math.MaxInt64) + 1
+	}
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
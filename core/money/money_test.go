@@ -0,0 +1,181 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		currency Currency
+		minor    int64
+		wantErr  error
+	}{
+		{"Whole number", "5000000", IDR, 500000000, nil},
+		{"Two decimals", "99.99", USD, 9999, nil},
+		{"One decimal", "12.5", USD, 1250, nil},
+		{"Negative", "-0.01", USD, -1, nil},
+		{"Trailing zeros beyond precision", "10.500", USD, 1050, nil},
+		{"Zero exponent currency", "1500", JPY, 1500, nil},
+		{"Too precise", "10.005", IDR, 0, ErrPrecision},
+		{"Fraction for JPY", "10.5", JPY, 0, ErrPrecision},
+		{"Overflow", "999999999999999999999", IDR, 0, ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(tt.input, tt.currency)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.minor, m.MinorUnits())
+			assert.Equal(t, tt.currency, m.Currency())
+		})
+	}
+
+	for _, bad := range []string{"", "abc", "1e6", "1,000", ".5", "5.", "--1", "1.2.3"} {
+		_, err := Parse(bad, IDR)
+		assert.Error(t, err, bad)
+	}
+
+	_, err := Parse("1", Currency("XXX"))
+	assert.Error(t, err)
+}
+
+func TestDecimal(t *testing.T) {
+	assert.Equal(t, "5000000.00", FromMajor(5000000, IDR).Decimal())
+	assert.Equal(t, "0.05", New(5, USD).Decimal())
+	assert.Equal(t, "-1.50", New(-150, USD).Decimal())
+	assert.Equal(t, "1500", New(1500, JPY).Decimal())
+	assert.Equal(t, "IDR 0.00", Zero(IDR).String())
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("100.10", IDR)
+	b := MustParse("0.90", IDR)
+
+	assert.Equal(t, MustParse("101.00", IDR), a.Add(b))
+	assert.Equal(t, MustParse("99.20", IDR), a.Sub(b))
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, 0, a.Cmp(a))
+	assert.True(t, Money{}.Add(a).Equal(a), "zero value adopts the other currency")
+
+	// Summing a thousand cents is exact, unlike float64.
+	sum := Zero(USD)
+	for i := 0; i < 1000; i++ {
+		sum = sum.Add(MustParse("0.01", USD))
+	}
+	assert.True(t, sum.Equal(MustParse("10", USD)))
+
+	assert.Panics(t, func() { a.Add(FromMajor(1, USD)) })
+	assert.False(t, a.SameCurrency(FromMajor(1, USD)))
+}
+
+func TestCheckedAdd(t *testing.T) {
+	largest := New(math.MaxInt64, IDR)
+
+	sum, err := largest.CheckedAdd(New(-1, IDR))
+	require.NoError(t, err)
+	assert.Equal(t, New(math.MaxInt64-1, IDR), sum)
+
+	_, err = largest.CheckedAdd(New(1, IDR))
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = New(math.MinInt64, IDR).CheckedAdd(New(-1, IDR))
+	assert.ErrorIs(t, err, ErrOverflow)
+	assert.PanicsWithValue(t, ErrOverflow, func() { largest.Add(New(1, IDR)) })
+}
+
+func TestCheckedMulRat(t *testing.T) {
+	largest := New(math.MaxInt64, IDR)
+
+	half, err := largest.CheckedMulRat(big.NewRat(1, 2))
+	require.NoError(t, err)
+	assert.Equal(t, New(math.MaxInt64/2+1, IDR), half, "the tie rounds to even")
+
+	_, err = largest.CheckedMulRat(big.NewRat(2, 1))
+	assert.ErrorIs(t, err, ErrOverflow)
+	assert.PanicsWithValue(t, ErrOverflow, func() { largest.MulRat(big.NewRat(2, 1)) })
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount string
+		pct    float64
+		want   string
+	}{
+		{"Exact", "1000000", 10, "100000.00"},
+		{"Tie rounds down to even", "100.05", 10, "10.00"},
+		{"Tie rounds up to even", "100.15", 10, "10.02"},
+		{"Above half rounds up", "0.07", 50, "0.04"},
+		{"Decimal percentage", "1000", 10.1, "101.00"},
+		{"Negative tie", "-100.05", 10, "-10.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MustParse(tt.amount, IDR).Percent(tt.pct)
+			assert.Equal(t, tt.want, got.Decimal())
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	t.Run("Shares sum to the total", func(t *testing.T) {
+		shares := New(100, IDR).Allocate([]int64{1, 1, 1})
+		assert.Equal(t, []Money{New(34, IDR), New(33, IDR), New(33, IDR)}, shares)
+	})
+
+	t.Run("Largest remainder wins", func(t *testing.T) {
+		// 10 split 1:2:3 → 1.67, 3.33, 5.00 → floors 1, 3, 5 with one unit left for index 0.
+		shares := New(10, IDR).Allocate([]int64{1, 2, 3})
+		assert.Equal(t, []Money{New(2, IDR), New(3, IDR), New(5, IDR)}, shares)
+	})
+
+	t.Run("Zero weights receive nothing", func(t *testing.T) {
+		shares := New(7, IDR).Allocate([]int64{0, 1, 0, 1})
+		assert.Equal(t, []Money{New(0, IDR), New(4, IDR), New(0, IDR), New(3, IDR)}, shares)
+	})
+
+	t.Run("Negative amounts", func(t *testing.T) {
+		shares := New(-5, IDR).Allocate([]int64{1, 1})
+		assert.Equal(t, []Money{New(-3, IDR), New(-2, IDR)}, shares)
+	})
+
+	t.Run("No weights", func(t *testing.T) {
+		assert.Empty(t, New(5, IDR).Allocate(nil))
+		assert.Equal(t, []Money{New(0, IDR)}, New(5, IDR).Allocate([]int64{0}))
+	})
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(MustParse("5000000.5", IDR))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"5000000.50","currency":"IDR"}`, string(b))
+
+	var m Money
+	require.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, MustParse("5000000.50", IDR), m)
+
+	require.NoError(t, json.Unmarshal([]byte(`1500000`), &m))
+	assert.Equal(t, FromMajor(1500000, DefaultCurrency), m)
+
+	require.NoError(t, json.Unmarshal([]byte(`"12.34"`), &m))
+	assert.Equal(t, New(1234, DefaultCurrency), m)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"10","currency":"usd"}`), &m))
+	assert.Equal(t, FromMajor(10, USD), m)
+
+	assert.Error(t, json.Unmarshal([]byte(`0.001`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1","currency":"XXX"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`true`), &m))
+}
@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Handler delivers a single message. Returning an error schedules another attempt.
+type Handler func(Message) error
+
+// Dispatcher periodically delivers due messages from a Store.
+//
+// A failed attempt is retried after an exponentially growing delay (1s, 2s, 4s, ... capped at
+// one hour by default) until MaxAttempts is reached, after which the message is marked Failed.
+// Messages are claimed before they are handled, so several dispatchers can share a store without
+// delivering the same message twice; a claim lapses after the lease if its dispatcher stops.
+type Dispatcher struct {
+	store       Store
+	handle      Handler
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	lease       time.Duration
+	now         func() time.Time
+}
+
+// DispatcherOption configures a Dispatcher.
+type DispatcherOption func(*Dispatcher)
+
+// WithMaxAttempts sets how many times a message is tried before it is marked Failed. Defaults to 8.
+func WithMaxAttempts(n int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay after the first failed attempt and the cap for later ones.
+func WithBackoff(base, max time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.baseDelay = base
+		d.maxDelay = max
+	}
+}
+
+// WithLease sets how long a claimed message is hidden from other dispatchers. It must be longer than
+// handling a whole batch takes. Defaults to 10 minutes.
+func WithLease(lease time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.lease = lease
+	}
+}
+
+// WithClock replaces the dispatcher's source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.now = now
+	}
+}
+
+// NewDispatcher creates a dispatcher that looks for due messages every interval.
+func NewDispatcher(store Store, handle Handler, interval time.Duration, opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		handle:      handle,
+		interval:    interval,
+		batchSize:   100,
+		maxAttempts: 8,
+		baseDelay:   time.Second,
+		maxDelay:    time.Hour,
+		lease:       10 * time.Minute,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run dispatches due messages immediately and then on every tick until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchDue(); err != nil {
+			log.Printf("[OUTBOX] %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims the messages that are due, makes one delivery attempt for each and returns how many were delivered.
+func (d *Dispatcher) DispatchDue() (int, error) {
+	now := d.now()
+	due, err := d.store.Claim(now, d.batchSize, now.Add(d.lease))
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, msg := range due {
+		if err := d.handle(msg); err != nil {
+			if err := d.fail(msg, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := d.store.MarkDelivered(msg.ID, d.now()); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// fail records a failed attempt and schedules the next one, or gives up after maxAttempts.
+func (d *Dispatcher) fail(msg Message, cause error) error {
+	attempts := msg.Attempts + 1
+	if attempts >= d.maxAttempts {
+		log.Printf("[OUTBOX] Giving up on %s message %s after %d attempts: %v", msg.Kind, msg.ID, attempts, cause)
+		return d.store.RecordFailure(msg.ID, attempts, cause.Error(), nil)
+	}
+	retryAt := d.now().Add(Backoff(d.baseDelay, d.maxDelay, attempts))
+	return d.store.RecordFailure(msg.ID, attempts, cause.Error(), &retryAt)
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed ones:
+// base after the first, doubling with every further failure, but never more than max.
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/database"
+)
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	store := NewInMemoryStore()
+	msg, _ := New("ping", nil, now)
+	require.NoError(t, store.Enqueue(msg))
+
+	var calls int
+	failUntil := 3
+	d := NewDispatcher(store, func(Message) error {
+		calls++
+		if calls < failUntil {
+			return errors.New("smtp down")
+		}
+		return nil
+	}, time.Second, WithClock(func() time.Time { return now }), WithBackoff(time.Second, time.Minute))
+
+	delivered, err := d.DispatchDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	got, _ := store.Get(msg.ID)
+	assert.Equal(t, 1, got.Attempts)
+	assert.Equal(t, now.Add(time.Second), got.NextAttemptAt)
+
+	delivered, _ = d.DispatchDue()
+	assert.Equal(t, 0, delivered, "not due again yet")
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(time.Second)
+	_, _ = d.DispatchDue()
+	got, _ = store.Get(msg.ID)
+	assert.Equal(t, 2, got.Attempts)
+	assert.Equal(t, now.Add(2*time.Second), got.NextAttemptAt, "delay doubles")
+
+	now = now.Add(2 * time.Second)
+	delivered, _ = d.DispatchDue()
+	assert.Equal(t, 1, delivered)
+	got, _ = store.Get(msg.ID)
+	assert.Equal(t, Delivered, got.Status)
+	assert.Equal(t, now, *got.DeliveredAt)
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	store := NewInMemoryStore()
+	msg, _ := New("ping", nil, now)
+	require.NoError(t, store.Enqueue(msg))
+
+	d := NewDispatcher(store, func(Message) error { return errors.New("mailbox unavailable") }, time.Second,
+		WithClock(func() time.Time { return now }), WithMaxAttempts(3), WithBackoff(time.Second, time.Second))
+
+	for i := 0; i < 5; i++ {
+		_, err := d.DispatchDue()
+		require.NoError(t, err)
+		now = now.Add(time.Second)
+	}
+
+	got, _ := store.Get(msg.ID)
+	assert.Equal(t, Failed, got.Status)
+	assert.Equal(t, 3, got.Attempts)
+	assert.Equal(t, "mailbox unavailable", got.LastError)
+}
+
+func TestDispatcher_InstancesDoNotShareMessages(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	store := NewSQLStore(db)
+
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		msg, _ := New("ping", i, now)
+		require.NoError(t, store.Enqueue(msg))
+	}
+
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+		wg    sync.WaitGroup
+	)
+	handle := func(m Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[m.ID]++
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := NewDispatcher(store, handle, time.Second).DispatchDue()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, calls, 50)
+	for id, n := range calls {
+		assert.Equal(t, 1, n, "message %s handled more than once", id)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(time.Second, 10*time.Second, 1))
+	assert.Equal(t, 2*time.Second, Backoff(time.Second, 10*time.Second, 2))
+	assert.Equal(t, 8*time.Second, Backoff(time.Second, 10*time.Second, 4))
+	assert.Equal(t, 10*time.Second, Backoff(time.Second, 10*time.Second, 5))
+	assert.Equal(t, 10*time.Second, Backoff(time.Second, 10*time.Second, 100))
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	store := NewInMemoryStore()
+	msg, _ := New("ping", nil, time.Now())
+	require.NoError(t, store.Enqueue(msg))
+
+	var (
+		mu        sync.Mutex
+		delivered []string
+	)
+	d := NewDispatcher(store, func(m Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, m.ID)
+		return nil
+	}, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		got, _ := store.Get(msg.ID)
+		return got.Status == Delivered
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{msg.ID}, delivered, "delivered exactly once")
+}
@@ -0,0 +1,216 @@
+// Package outbox implements the transactional outbox pattern for notifications.
+//
+// Messages are stored together with the change that caused them (see loan.LoanRepository.Update),
+// so a notification is never lost because sending failed, nor sent for a change that was rolled back.
+// A Dispatcher delivers them afterwards, retrying with backoff. Delivery is at least once.
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is where a message is in its delivery lifecycle.
+type Status string
+
+const (
+	// Pending messages are waiting for their next delivery attempt.
+	Pending Status = "pending"
+
+	// Delivered messages were handled successfully.
+	Delivered Status = "delivered"
+
+	// Failed messages ran out of attempts. Staff can send them again with Retry.
+	Failed Status = "failed"
+)
+
+// ErrMessageNotFound is returned when no message has the requested ID.
+var ErrMessageNotFound = errors.New("outbox message not found")
+
+// ErrNotFailed is returned when retrying a message that has not failed.
+var ErrNotFailed = errors.New("only failed messages can be retried")
+
+// Message is a notification waiting to be, or already, delivered.
+type Message struct {
+	ID            string          `json:"id"`                     // Unique identifier of the message
+	Kind          string          `json:"kind"`                   // Tells the handler how to interpret the payload
+	Payload       json.RawMessage `json:"payload"`                // Message content, as JSON
+	Status        Status          `json:"status"`                 // Delivery status
+	Attempts      int             `json:"attempts"`               // Number of failed delivery attempts so far
+	LastError     string          `json:"last_error,omitempty"`   // Error of the last failed attempt
+	NextAttemptAt time.Time       `json:"next_attempt_at"`        // Earliest time of the next attempt (pending only)
+	CreatedAt     time.Time       `json:"created_at"`             // When the message was written
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"` // When the message was delivered
+}
+
+// New creates a pending message of the given kind, due immediately.
+func New(kind string, payload any, at time.Time) (Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, fmt.Errorf("encode %s payload: %w", kind, err)
+	}
+	return Message{
+		ID:            uuid.NewString(),
+		Kind:          kind,
+		Payload:       data,
+		Status:        Pending,
+		NextAttemptAt: at,
+		CreatedAt:     at,
+	}, nil
+}
+
+// Store keeps outbox messages and their delivery state.
+//
+// Due returns up to limit pending messages whose next attempt is at or before the given time, oldest first.
+// Claim does the same but also moves their next attempt to until, so that other dispatchers sharing the
+// store skip them while they are being delivered; a message whose dispatcher died is due again at until.
+// RecordFailure stores a failed attempt; the message stays pending until retryAt, or fails for good
+// when retryAt is nil. Retry puts a failed message back in the queue with a fresh set of attempts.
+type Store interface {
+	Enqueue(messages ...Message) error
+	Due(at time.Time, limit int) ([]Message, error)
+	Claim(at time.Time, limit int, until time.Time) ([]Message, error)
+	MarkDelivered(id string, at time.Time) error
+	RecordFailure(id string, attempts int, lastError string, retryAt *time.Time) error
+	Retry(id string, at time.Time) (Message, error)
+	Get(id string) (Message, error)
+	List(status Status) ([]Message, error)
+}
+
+// InMemoryStore keeps messages in memory. Useful for development and tests.
+// Messages created at the same time are listed in the order they were enqueued.
+type InMemoryStore struct {
+	mu       sync.RWMutex
+	messages map[string]*Message
+	order    []string
+}
+
+// NewInMemoryStore creates an empty in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{messages: make(map[string]*Message)}
+}
+
+// Enqueue stores new messages.
+func (s *InMemoryStore) Enqueue(messages ...Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range messages {
+		if _, exists := s.messages[m.ID]; !exists {
+			s.order = append(s.order, m.ID)
+		}
+		s.messages[m.ID] = &m
+	}
+	return nil
+}
+
+// Due returns pending messages that are ready for another attempt.
+func (s *InMemoryStore) Due(at time.Time, limit int) ([]Message, error) {
+	due := s.filter(func(m *Message) bool { return m.Status == Pending && !m.NextAttemptAt.After(at) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Claim returns pending messages that are ready for another attempt and postpones them until the given time.
+func (s *InMemoryStore) Claim(at time.Time, limit int, until time.Time) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := s.match(func(m *Message) bool { return m.Status == Pending && !m.NextAttemptAt.After(at) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		s.messages[due[i].ID].NextAttemptAt = until
+		due[i].NextAttemptAt = until
+	}
+	return due, nil
+}
+
+// MarkDelivered records a successful delivery.
+func (s *InMemoryStore) MarkDelivered(id string, at time.Time) error {
+	return s.update(id, func(m *Message) error {
+		m.Status = Delivered
+		m.DeliveredAt = &at
+		return nil
+	})
+}
+
+// RecordFailure records a failed attempt and schedules the next one, if any.
+func (s *InMemoryStore) RecordFailure(id string, attempts int, lastError string, retryAt *time.Time) error {
+	return s.update(id, func(m *Message) error {
+		m.Attempts = attempts
+		m.LastError = lastError
+		if retryAt == nil {
+			m.Status = Failed
+			return nil
+		}
+		m.NextAttemptAt = *retryAt
+		return nil
+	})
+}
+
+// Retry puts a failed message back in the queue, due at the given time.
+func (s *InMemoryStore) Retry(id string, at time.Time) (Message, error) {
+	var retried Message
+	err := s.update(id, func(m *Message) error {
+		if m.Status != Failed {
+			return ErrNotFailed
+		}
+		m.Status = Pending
+		m.Attempts = 0
+		m.NextAttemptAt = at
+		retried = *m
+		return nil
+	})
+	return retried, err
+}
+
+// Get returns a message by ID.
+func (s *InMemoryStore) Get(id string) (Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if m, ok := s.messages[id]; ok {
+		return *m, nil
+	}
+	return Message{}, ErrMessageNotFound
+}
+
+// List returns the messages with the given status, or all of them if status is empty, oldest first.
+func (s *InMemoryStore) List(status Status) ([]Message, error) {
+	return s.filter(func(m *Message) bool { return status == "" || m.Status == status }), nil
+}
+
+func (s *InMemoryStore) update(id string, fn func(m *Message) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.messages[id]
+	if !ok {
+		return ErrMessageNotFound
+	}
+	return fn(m)
+}
+
+func (s *InMemoryStore) filter(keep func(m *Message) bool) []Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.match(keep)
+}
+
+// match returns copies of the messages keep accepts, oldest first. The caller holds s.mu.
+func (s *InMemoryStore) match(keep func(m *Message) bool) []Message {
+	result := []Message{}
+	for _, id := range s.order {
+		if m := s.messages[id]; keep(m) {
+			result = append(result, *m)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
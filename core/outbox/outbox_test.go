@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/database"
+)
+
+func TestNew(t *testing.T) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	msg, err := New("greeting", map[string]string{"to": "INV1"}, at)
+	require.NoError(t, err)
+	assert.NotEmpty(t, msg.ID)
+	assert.Equal(t, Pending, msg.Status)
+	assert.JSONEq(t, `{"to":"INV1"}`, string(msg.Payload))
+	assert.Equal(t, at, msg.NextAttemptAt)
+
+	_, err = New("broken", func() {}, at)
+	assert.Error(t, err)
+}
+
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, NewInMemoryStore())
+}
+
+func TestSQLStore(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "outbox.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testStore(t, NewSQLStore(db))
+}
+
+// testStore is the behaviour every Store implementation must satisfy.
+func testStore(t *testing.T, store Store) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	message := func(kind string, created time.Time) Message {
+		m, err := New(kind, map[string]string{"kind": kind}, created)
+		require.NoError(t, err)
+		return m
+	}
+	first, second, later := message("first", at), message("second", at.Add(time.Second)), message("later", at.Add(time.Hour))
+	require.NoError(t, store.Enqueue(later, first, second))
+
+	t.Run("Due returns ready messages oldest first", func(t *testing.T) {
+		due, err := store.Due(at.Add(time.Minute), 0)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+		assert.Equal(t, first.ID, due[0].ID)
+		assert.Equal(t, second.ID, due[1].ID)
+		assert.JSONEq(t, `{"kind":"first"}`, string(due[0].Payload))
+
+		due, err = store.Due(at.Add(time.Minute), 1)
+		require.NoError(t, err)
+		assert.Len(t, due, 1)
+	})
+
+	t.Run("Failed attempt postpones the message", func(t *testing.T) {
+		retryAt := at.Add(2 * time.Hour)
+		require.NoError(t, store.RecordFailure(first.ID, 1, "smtp down", &retryAt))
+
+		due, err := store.Due(at.Add(time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{second.ID, later.ID}, []string{due[0].ID, due[1].ID})
+
+		got, err := store.Get(first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, Pending, got.Status)
+		assert.Equal(t, 1, got.Attempts)
+		assert.Equal(t, "smtp down", got.LastError)
+		assert.True(t, retryAt.Equal(got.NextAttemptAt))
+	})
+
+	t.Run("Delivered messages are no longer due", func(t *testing.T) {
+		require.NoError(t, store.MarkDelivered(second.ID, at.Add(time.Minute)))
+
+		got, err := store.Get(second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, Delivered, got.Status)
+		require.NotNil(t, got.DeliveredAt)
+		assert.True(t, at.Add(time.Minute).Equal(*got.DeliveredAt))
+
+		delivered, err := store.List(Delivered)
+		require.NoError(t, err)
+		assert.Len(t, delivered, 1)
+	})
+
+	t.Run("Failed messages can be retried", func(t *testing.T) {
+		_, err := store.Retry(later.ID, at)
+		assert.ErrorIs(t, err, ErrNotFailed)
+
+		require.NoError(t, store.RecordFailure(later.ID, 8, "gave up", nil))
+		failed, err := store.List(Failed)
+		require.NoError(t, err)
+		require.Len(t, failed, 1)
+		assert.Equal(t, later.ID, failed[0].ID)
+
+		retried, err := store.Retry(later.ID, at.Add(3*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, Pending, retried.Status)
+		assert.Equal(t, 0, retried.Attempts)
+		assert.Equal(t, "gave up", retried.LastError, "last error is kept for reference")
+
+		due, err := store.Due(at.Add(3*time.Hour), 0)
+		require.NoError(t, err)
+		assert.Len(t, due, 2)
+	})
+
+	t.Run("Unknown message", func(t *testing.T) {
+		_, err := store.Get("missing")
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+		assert.ErrorIs(t, store.MarkDelivered("missing", at), ErrMessageNotFound)
+		_, err = store.Retry("missing", at)
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+	})
+
+	t.Run("List all", func(t *testing.T) {
+		all, err := store.List("")
+		require.NoError(t, err)
+		assert.Len(t, all, 3)
+	})
+
+	t.Run("Claimed messages are hidden until the lease ends", func(t *testing.T) {
+		lease := at.Add(4 * time.Hour)
+		claimed, err := store.Claim(at.Add(3*time.Hour), 0, lease)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+		assert.Equal(t, first.ID, claimed[0].ID)
+		assert.True(t, lease.Equal(claimed[0].NextAttemptAt))
+
+		claimed, err = store.Claim(at.Add(3*time.Hour), 0, lease)
+		require.NoError(t, err)
+		assert.Empty(t, claimed, "already claimed")
+
+		claimed, err = store.Claim(lease, 1, lease.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Len(t, claimed, 1, "claim lapsed")
+	})
+}
@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"loan-service/database"
+)
+
+// SQLStore keeps messages in the outbox_messages table.
+type SQLStore struct {
+	db *database.DB
+}
+
+// NewSQLStore creates a store on top of an already migrated database.
+func NewSQLStore(db *database.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// InsertTx writes messages as part of an existing transaction.
+// Repositories use it to store messages in the same unit of work as the change that caused them.
+func InsertTx(tx *sql.Tx, dialect database.Dialect, messages []Message) error {
+	for _, m := range messages {
+		if _, err := tx.Exec(dialect.Rebind(`INSERT INTO outbox_messages
+			(id, kind, payload, status, attempts, last_error, next_attempt_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			m.ID, m.Kind, string(m.Payload), string(m.Status), m.Attempts, m.LastError,
+			m.NextAttemptAt.UTC(), m.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("insert outbox message: %w", err)
+		}
+	}
+	return nil
+}
+
+// Enqueue stores new messages in a transaction of their own.
+func (s *SQLStore) Enqueue(messages ...Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := InsertTx(tx, s.db.Dialect, messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Due returns pending messages that are ready for another attempt.
+func (s *SQLStore) Due(at time.Time, limit int) ([]Message, error) {
+	query := `WHERE status = ? AND next_attempt_at <= ? ORDER BY created_at, id`
+	args := []any{string(Pending), at.UTC()}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return s.query(query, args...)
+}
+
+// Claim returns pending messages that are ready for another attempt and postpones them until the given time.
+// Each message is claimed with a conditional update, so of several dispatchers only one gets it.
+func (s *SQLStore) Claim(at time.Time, limit int, until time.Time) ([]Message, error) {
+	due, err := s.Due(at, limit)
+	if err != nil {
+		return nil, err
+	}
+	claimed := due[:0]
+	for _, m := range due {
+		res, err := s.db.Exec(s.db.Dialect.Rebind(`UPDATE outbox_messages SET next_attempt_at = ?
+			WHERE id = ? AND status = ? AND next_attempt_at <= ?`), until.UTC(), m.ID, string(Pending), at.UTC())
+		if err != nil {
+			return nil, fmt.Errorf("claim outbox message: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 1 {
+			m.NextAttemptAt = until
+			claimed = append(claimed, m)
+		}
+	}
+	return claimed, nil
+}
+
+// MarkDelivered records a successful delivery.
+func (s *SQLStore) MarkDelivered(id string, at time.Time) error {
+	return s.exec(`UPDATE outbox_messages SET status = ?, delivered_at = ? WHERE id = ?`,
+		string(Delivered), at.UTC(), id)
+}
+
+// RecordFailure records a failed attempt and schedules the next one, if any.
+func (s *SQLStore) RecordFailure(id string, attempts int, lastError string, retryAt *time.Time) error {
+	if retryAt == nil {
+		return s.exec(`UPDATE outbox_messages SET status = ?, attempts = ?, last_error = ? WHERE id = ?`,
+			string(Failed), attempts, lastError, id)
+	}
+	return s.exec(`UPDATE outbox_messages SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		attempts, lastError, retryAt.UTC(), id)
+}
+
+// Retry puts a failed message back in the queue, due at the given time.
+func (s *SQLStore) Retry(id string, at time.Time) (Message, error) {
+	res, err := s.db.Exec(s.db.Dialect.Rebind(`UPDATE outbox_messages SET status = ?, attempts = 0, next_attempt_at = ?
+		WHERE id = ? AND status = ?`), string(Pending), at.UTC(), id, string(Failed))
+	if err != nil {
+		return Message{}, fmt.Errorf("retry outbox message: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return Message{}, err
+	} else if n == 0 {
+		if _, err := s.Get(id); err != nil {
+			return Message{}, err
+		}
+		return Message{}, ErrNotFailed
+	}
+	return s.Get(id)
+}
+
+// Get returns a message by ID.
+func (s *SQLStore) Get(id string) (Message, error) {
+	messages, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return Message{}, err
+	}
+	if len(messages) == 0 {
+		return Message{}, ErrMessageNotFound
+	}
+	return messages[0], nil
+}
+
+// List returns the messages with the given status, or all of them if status is empty, oldest first.
+func (s *SQLStore) List(status Status) ([]Message, error) {
+	if status == "" {
+		return s.query(`ORDER BY created_at, id`)
+	}
+	return s.query(`WHERE status = ? ORDER BY created_at, id`, string(status))
+}
+
+func (s *SQLStore) exec(query string, args ...any) error {
+	res, err := s.db.Exec(s.db.Dialect.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("update outbox message: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) query(clause string, args ...any) ([]Message, error) {
+	rows, err := s.db.Query(s.db.Dialect.Rebind(`SELECT id, kind, payload, status, attempts, last_error,
+		next_attempt_at, created_at, delivered_at FROM outbox_messages `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query outbox messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	messages := []Message{}
+	for rows.Next() {
+		var (
+			m               Message
+			payload, status string
+			deliveredAt     sql.NullTime
+		)
+		if err := rows.Scan(&m.ID, &m.Kind, &payload, &status, &m.Attempts, &m.LastError,
+			&m.NextAttemptAt, &m.CreatedAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("scan outbox message: %w", err)
+		}
+		m.Payload = []byte(payload)
+		m.Status = Status(status)
+		if deliveredAt.Valid {
+			t := deliveredAt.Time
+			m.DeliveredAt = &t
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
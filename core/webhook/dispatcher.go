@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loan-service/core/outbox"
+)
+
+// Dispatcher periodically sends due deliveries.
+//
+// A delivery succeeds when the subscriber answers with a 2xx status. Otherwise it is retried after
+// an exponentially growing delay until the maximum number of attempts is reached, after which it is
+// marked failed. Deliveries of deleted subscriptions fail straight away. Deliveries are claimed
+// before they are sent, so several dispatchers can share a repository (see WithLease).
+type Dispatcher struct {
+	repo      Repository
+	interval  time.Duration
+	batchSize int
+	settings
+}
+
+// NewDispatcher creates a dispatcher that looks for due deliveries every interval.
+func NewDispatcher(repo Repository, interval time.Duration, opts ...Option) *Dispatcher {
+	return &Dispatcher{repo: repo, interval: interval, batchSize: 100, settings: newSettings(opts)}
+}
+
+// Run sends due deliveries immediately and then on every tick until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchDue(); err != nil {
+			log.Printf("[WEBHOOK] %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims the deliveries that are due, makes one attempt for each and returns how many succeeded.
+func (d *Dispatcher) DispatchDue() (int, error) {
+	now := d.now()
+	due, err := d.repo.ClaimDeliveries(now, d.batchSize, now.Add(d.lease))
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		if err := d.attempt(&delivery); err != nil {
+			return delivered, err
+		}
+		if delivery.Status == outbox.Delivered {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// attempt sends a delivery once and stores the outcome.
+func (d *Dispatcher) attempt(delivery *Delivery) error {
+	delivery.Attempts++
+	sub, err := d.repo.GetSubscription(delivery.SubscriptionID)
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound):
+		delivery.Status = outbox.Failed
+		delivery.LastError = "subscription was deleted"
+		return d.repo.UpdateDelivery(*delivery)
+	case err != nil:
+		return err
+	}
+
+	delivery.ResponseCode, err = d.send(sub, *delivery)
+	now := d.now()
+	switch {
+	case err == nil:
+		delivery.Status = outbox.Delivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.maxAttempts:
+		log.Printf("[WEBHOOK] Giving up on delivery %s to %s after %d attempts: %v", delivery.ID, sub.URL, delivery.Attempts, err)
+		delivery.Status = outbox.Failed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(outbox.Backoff(d.baseDelay, d.maxDelay, delivery.Attempts))
+	}
+	return d.repo.UpdateDelivery(*delivery)
+}
+
+// send POSTs the delivery's payload to the subscription and returns the response status.
+func (d *Dispatcher) send(sub Subscription, delivery Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(sub.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+	"loan-service/database"
+)
+
+// receiver is a subscriber endpoint that checks signatures and answers with queued status codes.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int // Answers for the next requests; 200 once exhausted
+	bodies   []string
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	require.NoError(r.t, err)
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(r.t, err)
+	assert.Equal(r.t, "sha256="+Sign(r.secret, timestamp, body), req.Header.Get(SignatureHeader))
+	assert.Equal(r.t, "application/json", req.Header.Get("Content-Type"))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, string(body))
+	r.headers = append(r.headers, req.Header.Clone())
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestDispatcher_DeliversSignedRequests(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+	repo := NewInMemoryRepository()
+	svc := NewService(repo, clock)
+	rcv := &receiver{t: t, statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	sub, err := svc.Subscribe(server.URL, []loan.EventType{loan.ApprovedEvent})
+	require.NoError(t, err)
+	rcv.secret = sub.Secret
+	require.NoError(t, svc.Publish(loan.Event{ID: "evt-1", Type: loan.ApprovedEvent, Loan: loan.Loan{ID: "LOAN1"}, OccurredAt: now}))
+
+	d := NewDispatcher(repo, time.Second, clock, WithBackoff(time.Second, time.Minute))
+
+	// The first attempt gets a 500 and is retried after the backoff.
+	delivered, err := d.DispatchDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	log, _ := svc.ListDeliveries(sub.ID)
+	require.Len(t, log, 1)
+	assert.Equal(t, outbox.Pending, log[0].Status)
+	assert.Equal(t, 1, log[0].Attempts)
+	assert.Equal(t, 500, log[0].ResponseCode)
+	assert.Equal(t, "unexpected response status 500", log[0].LastError)
+	assert.Equal(t, now.Add(time.Second), log[0].NextAttemptAt)
+
+	delivered, _ = d.DispatchDue()
+	assert.Equal(t, 0, delivered, "not due again yet")
+
+	now = now.Add(time.Second)
+	delivered, err = d.DispatchDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	log, _ = svc.ListDeliveries(sub.ID)
+	assert.Equal(t, outbox.Delivered, log[0].Status)
+	assert.Equal(t, 2, log[0].Attempts)
+	assert.Equal(t, 200, log[0].ResponseCode)
+	assert.Empty(t, log[0].LastError)
+	assert.Equal(t, now, *log[0].DeliveredAt)
+
+	require.Len(t, rcv.bodies, 2)
+	assert.JSONEq(t, string(log[0].Payload), rcv.bodies[1])
+	assert.Equal(t, "loan.approved", rcv.headers[1].Get(EventHeader))
+	assert.Equal(t, log[0].ID, rcv.headers[1].Get(DeliveryHeader))
+
+	// A replay sends the same event again under a new delivery ID.
+	replay, err := svc.Replay(sub.ID, log[0].ID)
+	require.NoError(t, err)
+	delivered, _ = d.DispatchDue()
+	assert.Equal(t, 1, delivered)
+	require.Len(t, rcv.bodies, 3)
+	assert.Equal(t, rcv.bodies[1], rcv.bodies[2])
+	assert.Equal(t, replay.ID, rcv.headers[2].Get(DeliveryHeader))
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	now := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+	repo := NewInMemoryRepository()
+	svc := NewService(repo, clock)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	kept, _ := svc.Subscribe(server.URL, []loan.EventType{loan.FundedEvent})
+	deleted, _ := svc.Subscribe(server.URL, []loan.EventType{loan.FundedEvent})
+	require.NoError(t, svc.Publish(loan.Event{ID: "evt-1", Type: loan.FundedEvent}))
+	require.NoError(t, svc.Unsubscribe(deleted.ID))
+
+	d := NewDispatcher(repo, time.Second, clock, WithMaxAttempts(2), WithBackoff(time.Second, time.Second))
+	for i := 0; i < 3; i++ {
+		_, err := d.DispatchDue()
+		require.NoError(t, err)
+		now = now.Add(time.Second)
+	}
+
+	log, _ := svc.ListDeliveries(kept.ID)
+	require.Len(t, log, 1)
+	assert.Equal(t, outbox.Failed, log[0].Status)
+	assert.Equal(t, 2, log[0].Attempts)
+	assert.Equal(t, 410, log[0].ResponseCode)
+
+	orphan, _ := repo.ListDeliveries(deleted.ID)
+	require.Len(t, orphan, 1)
+	assert.Equal(t, outbox.Failed, orphan[0].Status)
+	assert.Equal(t, "subscription was deleted", orphan[0].LastError)
+	assert.Equal(t, 1, orphan[0].Attempts)
+}
+
+func TestDispatcher_InstancesDoNotShareDeliveries(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "webhooks.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo := NewSQLRepository(db)
+	svc := NewService(repo)
+	rcv := &receiver{t: t}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	sub, err := svc.Subscribe(server.URL, []loan.EventType{loan.ApprovedEvent})
+	require.NoError(t, err)
+	rcv.secret = sub.Secret
+	for i := 0; i < 20; i++ {
+		require.NoError(t, svc.Publish(loan.Event{ID: "evt-" + strconv.Itoa(i), Type: loan.ApprovedEvent}))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := NewDispatcher(repo, time.Second).DispatchDue()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	seen := make(map[string]int)
+	for _, h := range rcv.headers {
+		seen[h.Get(DeliveryHeader)]++
+	}
+	assert.Len(t, seen, 20)
+	for id, n := range seen {
+		assert.Equal(t, 1, n, "delivery %s sent more than once", id)
+	}
+}
@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+)
+
+// settings are shared by Service and Dispatcher and changed with Options.
+type settings struct {
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	lease       time.Duration
+	now         func() time.Time
+}
+
+func newSettings(opts []Option) settings {
+	s := settings{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 8,
+		baseDelay:   time.Second,
+		maxDelay:    time.Hour,
+		lease:       10 * time.Minute,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Option configures a Service or Dispatcher.
+type Option func(*settings)
+
+// WithHTTPClient sets the client deliveries are sent with. Defaults to one with a 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *settings) {
+		s.client = client
+	}
+}
+
+// WithMaxAttempts sets how many requests are made for a delivery before it is marked failed. Defaults to 8.
+func WithMaxAttempts(n int) Option {
+	return func(s *settings) {
+		s.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay after the first failed attempt and the cap for later ones (see outbox.Backoff).
+func WithBackoff(base, max time.Duration) Option {
+	return func(s *settings) {
+		s.baseDelay = base
+		s.maxDelay = max
+	}
+}
+
+// WithLease sets how long a delivery claimed by a Dispatcher is hidden from other dispatchers. It must be
+// longer than sending a whole batch takes. Defaults to 10 minutes.
+func WithLease(lease time.Duration) Option {
+	return func(s *settings) {
+		s.lease = lease
+	}
+}
+
+// WithClock replaces the source of the current time. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *settings) {
+		s.now = now
+	}
+}
+
+// Service manages subscriptions and turns loan events into deliveries.
+// It is the loan.EventPublisher to give the loan service.
+type Service struct {
+	repo Repository
+	settings
+}
+
+// NewService creates a webhook service storing its data in repo.
+func NewService(repo Repository, opts ...Option) *Service {
+	return &Service{repo: repo, settings: newSettings(opts)}
+}
+
+// Subscribe registers a URL for events of the given types. The returned subscription holds the
+// secret deliveries are signed with; it is not shown again.
+func (s *Service) Subscribe(target string, events []loan.EventType) (Subscription, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, fmt.Errorf("invalid webhook URL %q: must be an absolute http or https URL", target)
+	}
+	if len(events) == 0 {
+		return Subscription{}, errors.New("at least one event type is required")
+	}
+	seen := make(map[loan.EventType]bool)
+	var unique []loan.EventType
+	for _, e := range events {
+		if !known(e) {
+			return Subscription{}, fmt.Errorf("unknown event type %q", e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			unique = append(unique, e)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Subscription{}, fmt.Errorf("generate webhook secret: %w", err)
+	}
+	sub := Subscription{
+		ID:        uuid.NewString(),
+		URL:       target,
+		Events:    unique,
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: s.now(),
+	}
+	if err := s.repo.CreateSubscription(sub); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns every subscription, without secrets.
+func (s *Service) ListSubscriptions() ([]Subscription, error) {
+	subs, err := s.repo.ListSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+// Unsubscribe deletes a subscription. Its pending deliveries fail on their next attempt.
+func (s *Service) Unsubscribe(id string) error {
+	return s.repo.DeleteSubscription(id)
+}
+
+// Publish queues a delivery of the event for every subscription that wants it.
+// It implements loan.EventPublisher. Publishing an event again, as the outbox does when an attempt
+// fails, queues no further deliveries for subscriptions that already have one.
+func (s *Service) Publish(event loan.Event) error {
+	subs, err := s.repo.ListSubscriptions()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", event.Type, err)
+	}
+
+	var deliveries []Delivery
+	for _, sub := range subs {
+		if sub.wants(event.Type) {
+			delivery := s.newDelivery(sub.ID, event.ID, event.Type, payload)
+			delivery.ID = event.ID + ":" + sub.ID
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.repo.AddDeliveries(deliveries...)
+}
+
+// ListDeliveries returns the delivery log of a subscription, oldest first.
+func (s *Service) ListDeliveries(subscriptionID string) ([]Delivery, error) {
+	if _, err := s.repo.GetSubscription(subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(subscriptionID)
+}
+
+// Replay queues a new delivery with the same event as an earlier one of the subscription,
+// whatever that delivery's outcome. Receivers can tell replays apart by the unchanged event ID.
+func (s *Service) Replay(subscriptionID, deliveryID string) (Delivery, error) {
+	if _, err := s.repo.GetSubscription(subscriptionID); err != nil {
+		return Delivery{}, err
+	}
+	original, err := s.repo.GetDelivery(deliveryID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if original.SubscriptionID != subscriptionID {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+
+	replay := s.newDelivery(subscriptionID, original.EventID, original.EventType, original.Payload)
+	replay.ReplayOf = original.ID
+	if err := s.repo.AddDeliveries(replay); err != nil {
+		return Delivery{}, err
+	}
+	return replay, nil
+}
+
+// newDelivery returns a pending delivery, due immediately.
+func (s *Service) newDelivery(subscriptionID, eventID string, eventType loan.EventType, payload []byte) Delivery {
+	now := s.now()
+	return Delivery{
+		ID:             uuid.NewString(),
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         outbox.Pending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+}
+
+// known reports whether the loan service publishes events of type t.
+func known(t loan.EventType) bool {
+	for _, e := range loan.EventTypes {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
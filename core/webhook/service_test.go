@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+)
+
+func TestService_Subscribe(t *testing.T) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	svc := NewService(NewInMemoryRepository(), WithClock(func() time.Time { return at }))
+
+	sub, err := svc.Subscribe("https://partner.example.com/hook", []loan.EventType{loan.ApprovedEvent, loan.FundedEvent, loan.ApprovedEvent})
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.ID)
+	assert.Len(t, sub.Secret, 64)
+	assert.Equal(t, []loan.EventType{loan.ApprovedEvent, loan.FundedEvent}, sub.Events, "duplicates are dropped")
+	assert.Equal(t, at, sub.CreatedAt)
+
+	subs, err := svc.ListSubscriptions()
+	require.NoError(t, err)
+	if assert.Len(t, subs, 1) {
+		assert.Equal(t, sub.ID, subs[0].ID)
+		assert.Empty(t, subs[0].Secret, "secrets are only shown once")
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		events  []loan.EventType
+		wantErr string
+	}{
+		{"relative URL", "/hook", []loan.EventType{loan.ApprovedEvent}, `invalid webhook URL "/hook": must be an absolute http or https URL`},
+		{"unsupported scheme", "ftp://partner.example.com", []loan.EventType{loan.ApprovedEvent}, `invalid webhook URL "ftp://partner.example.com": must be an absolute http or https URL`},
+		{"no events", "https://partner.example.com", nil, "at least one event type is required"},
+		{"unknown event", "https://partner.example.com", []loan.EventType{"loan.repaid"}, `unknown event type "loan.repaid"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Subscribe(tt.url, tt.events)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+
+	require.NoError(t, svc.Unsubscribe(sub.ID))
+	assert.ErrorIs(t, svc.Unsubscribe(sub.ID), ErrSubscriptionNotFound)
+}
+
+func TestService_PublishAndReplay(t *testing.T) {
+	svc := NewService(NewInMemoryRepository())
+	approvals, _ := svc.Subscribe("https://a.example.com/hook", []loan.EventType{loan.ApprovedEvent})
+	everything, _ := svc.Subscribe("https://b.example.com/hook", loan.EventTypes)
+
+	event := loan.Event{ID: "evt-1", Type: loan.FundedEvent, Loan: loan.Loan{ID: "LOAN1", State: loan.Invested}, OccurredAt: time.Now()}
+	require.NoError(t, svc.Publish(event))
+
+	deliveries, err := svc.ListDeliveries(approvals.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "not subscribed to loan.funded")
+
+	deliveries, err = svc.ListDeliveries(everything.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	original := deliveries[0]
+	assert.Equal(t, "evt-1", original.EventID)
+	assert.Equal(t, loan.FundedEvent, original.EventType)
+	assert.Equal(t, outbox.Pending, original.Status)
+	var sent loan.Event
+	require.NoError(t, json.Unmarshal(original.Payload, &sent))
+	assert.Equal(t, "LOAN1", sent.Loan.ID)
+
+	require.NoError(t, svc.Publish(event), "the outbox publishes again when an attempt fails")
+	deliveries, _ = svc.ListDeliveries(everything.ID)
+	require.Len(t, deliveries, 1, "the event is delivered once per subscription")
+	assert.Equal(t, original.ID, deliveries[0].ID)
+
+	replay, err := svc.Replay(everything.ID, original.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, original.ID, replay.ID)
+	assert.Equal(t, original.ID, replay.ReplayOf)
+	assert.Equal(t, original.EventID, replay.EventID)
+	assert.JSONEq(t, string(original.Payload), string(replay.Payload))
+
+	deliveries, _ = svc.ListDeliveries(everything.ID)
+	assert.Len(t, deliveries, 2)
+
+	_, err = svc.Replay(approvals.ID, original.ID)
+	assert.ErrorIs(t, err, ErrDeliveryNotFound, "delivery belongs to another subscription")
+	_, err = svc.Replay(everything.ID, "missing")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	_, err = svc.ListDeliveries("missing")
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+}
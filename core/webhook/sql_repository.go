@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+	"loan-service/database"
+)
+
+// SQLRepository stores subscriptions in webhook_subscriptions and deliveries in webhook_deliveries.
+type SQLRepository struct {
+	db *database.DB
+}
+
+// NewSQLRepository creates a repository on top of an already migrated database.
+func NewSQLRepository(db *database.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// CreateSubscription stores a new subscription.
+func (r *SQLRepository) CreateSubscription(sub Subscription) error {
+	if _, err := r.db.Exec(r.db.Dialect.Rebind(`INSERT INTO webhook_subscriptions
+		(id, url, events, secret, created_at) VALUES (?, ?, ?, ?, ?)`),
+		sub.ID, sub.URL, joinEvents(sub.Events), sub.Secret, sub.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("insert webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetSubscription returns a subscription by ID.
+func (r *SQLRepository) GetSubscription(id string) (Subscription, error) {
+	subs, err := r.querySubscriptions(`WHERE id = ?`, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if len(subs) == 0 {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return subs[0], nil
+}
+
+// ListSubscriptions returns every subscription.
+func (r *SQLRepository) ListSubscriptions() ([]Subscription, error) {
+	return r.querySubscriptions(`ORDER BY created_at, id`)
+}
+
+// DeleteSubscription removes a subscription. Its deliveries are kept.
+func (r *SQLRepository) DeleteSubscription(id string) error {
+	res, err := r.db.Exec(r.db.Dialect.Rebind(`DELETE FROM webhook_subscriptions WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// AddDeliveries stores new deliveries in one transaction, skipping those already stored.
+func (r *SQLRepository) AddDeliveries(deliveries ...Delivery) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, d := range deliveries {
+		if _, err := tx.Exec(r.db.Dialect.Rebind(`INSERT INTO webhook_deliveries
+			(id, subscription_id, event_id, event_type, payload, status, attempts, response_code,
+			last_error, next_attempt_at, created_at, delivered_at, replay_of)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+			d.ID, d.SubscriptionID, d.EventID, string(d.EventType), string(d.Payload), string(d.Status),
+			d.Attempts, d.ResponseCode, d.LastError, d.NextAttemptAt.UTC(), d.CreatedAt.UTC(),
+			nullTime(d.DeliveredAt), d.ReplayOf); err != nil {
+			return fmt.Errorf("insert webhook delivery: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDelivery returns a delivery by ID.
+func (r *SQLRepository) GetDelivery(id string) (Delivery, error) {
+	deliveries, err := r.queryDeliveries(`WHERE id = ?`, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return deliveries[0], nil
+}
+
+// ListDeliveries returns the deliveries of a subscription.
+func (r *SQLRepository) ListDeliveries(subscriptionID string) ([]Delivery, error) {
+	return r.queryDeliveries(`WHERE subscription_id = ? ORDER BY created_at, id`, subscriptionID)
+}
+
+// DueDeliveries returns pending deliveries that are ready for another attempt.
+func (r *SQLRepository) DueDeliveries(at time.Time, limit int) ([]Delivery, error) {
+	query := `WHERE status = ? AND next_attempt_at <= ? ORDER BY created_at, id`
+	args := []any{string(outbox.Pending), at.UTC()}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return r.queryDeliveries(query, args...)
+}
+
+// ClaimDeliveries returns pending deliveries that are ready for another attempt and postpones them until the given time.
+// Each delivery is claimed with a conditional update, so of several dispatchers only one gets it.
+func (r *SQLRepository) ClaimDeliveries(at time.Time, limit int, until time.Time) ([]Delivery, error) {
+	due, err := r.DueDeliveries(at, limit)
+	if err != nil {
+		return nil, err
+	}
+	claimed := due[:0]
+	for _, d := range due {
+		res, err := r.db.Exec(r.db.Dialect.Rebind(`UPDATE webhook_deliveries SET next_attempt_at = ?
+			WHERE id = ? AND status = ? AND next_attempt_at <= ?`), until.UTC(), d.ID, string(outbox.Pending), at.UTC())
+		if err != nil {
+			return nil, fmt.Errorf("claim webhook delivery: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 1 {
+			d.NextAttemptAt = until
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, nil
+}
+
+// UpdateDelivery stores the outcome of an attempt.
+func (r *SQLRepository) UpdateDelivery(d Delivery) error {
+	res, err := r.db.Exec(r.db.Dialect.Rebind(`UPDATE webhook_deliveries SET status = ?, attempts = ?,
+		response_code = ?, last_error = ?, next_attempt_at = ?, delivered_at = ? WHERE id = ?`),
+		string(d.Status), d.Attempts, d.ResponseCode, d.LastError, d.NextAttemptAt.UTC(), nullTime(d.DeliveredAt), d.ID)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) querySubscriptions(clause string, args ...any) ([]Subscription, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT id, url, events, secret, created_at
+		FROM webhook_subscriptions `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook subscriptions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		var (
+			sub    Subscription
+			events string
+		)
+		if err := rows.Scan(&sub.ID, &sub.URL, &events, &sub.Secret, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan webhook subscription: %w", err)
+		}
+		for _, e := range strings.Split(events, ",") {
+			sub.Events = append(sub.Events, loan.EventType(e))
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (r *SQLRepository) queryDeliveries(clause string, args ...any) ([]Delivery, error) {
+	rows, err := r.db.Query(r.db.Dialect.Rebind(`SELECT id, subscription_id, event_id, event_type, payload,
+		status, attempts, response_code, last_error, next_attempt_at, created_at, delivered_at, replay_of
+		FROM webhook_deliveries `+clause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var (
+			d                          Delivery
+			eventType, payload, status string
+			deliveredAt                sql.NullTime
+		)
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &eventType, &payload, &status, &d.Attempts,
+			&d.ResponseCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &deliveredAt, &d.ReplayOf); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		d.EventType = loan.EventType(eventType)
+		d.Payload = []byte(payload)
+		d.Status = outbox.Status(status)
+		if deliveredAt.Valid {
+			t := deliveredAt.Time
+			d.DeliveredAt = &t
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// joinEvents stores event types as a comma-separated list.
+func joinEvents(events []loan.EventType) string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = string(e)
+	}
+	return strings.Join(names, ",")
+}
+
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
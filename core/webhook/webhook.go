@@ -0,0 +1,251 @@
+// Package webhook delivers loan events to partner systems.
+//
+// Partners subscribe a URL to some event types (see loan.EventTypes). Every matching event becomes a
+// Delivery: an HTTP POST of the event as JSON, signed with the subscription's secret (see Sign).
+// A Dispatcher sends deliveries in the background, retrying with backoff, and each delivery keeps
+// the outcome of its last attempt so partners and staff can see what was sent and replay it.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+)
+
+// Headers set on every delivery request.
+const (
+	EventHeader     = "X-Webhook-Event"     // Event type, e.g. loan.approved
+	DeliveryHeader  = "X-Webhook-Delivery"  // Delivery ID; the same on every attempt, a replay gets a new one
+	TimestampHeader = "X-Webhook-Timestamp" // Unix time the request was signed at
+	SignatureHeader = "X-Webhook-Signature" // "sha256=" followed by Sign(secret, timestamp, body)
+)
+
+// ErrSubscriptionNotFound is returned when no subscription has the requested ID.
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// ErrDeliveryNotFound is returned when no delivery has the requested ID.
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// Subscription asks for events of the given types to be POSTed to URL.
+type Subscription struct {
+	ID        string           `json:"id"`
+	URL       string           `json:"url"`              // Where deliveries are POSTed
+	Events    []loan.EventType `json:"events"`           // Event types the subscriber wants
+	Secret    string           `json:"secret,omitempty"` // Signing key; only shown when the subscription is created
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// wants reports whether the subscription asked for events of type t.
+func (s Subscription) wants(t loan.EventType) bool {
+	for _, e := range s.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event sent, or to be sent, to one subscription.
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`                // Same for every delivery (and replay) of an event
+	EventType      loan.EventType  `json:"event_type"`              // Type of the event
+	Payload        json.RawMessage `json:"payload"`                 // Request body: the event as JSON
+	Status         outbox.Status   `json:"status"`                  // Pending, delivered or failed
+	Attempts       int             `json:"attempts"`                // Number of requests made so far
+	ResponseCode   int             `json:"response_code,omitempty"` // HTTP status of the last response
+	LastError      string          `json:"last_error,omitempty"`    // Why the last attempt failed
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`         // Earliest time of the next attempt (pending only)
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	ReplayOf       string          `json:"replay_of,omitempty"` // ID of the delivery this one replays
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with secret.
+// Receivers recompute it to check that a request came from us and was not altered or replayed later.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Repository stores subscriptions and their deliveries.
+//
+// Lists are ordered oldest first. DueDeliveries returns up to limit pending deliveries whose next
+// attempt is at or before the given time (all of them if limit is 0). ClaimDeliveries does the same
+// but also moves their next attempt to until, so that other dispatchers skip them in the meantime.
+// AddDeliveries ignores deliveries whose ID is already stored.
+type Repository interface {
+	CreateSubscription(sub Subscription) error
+	GetSubscription(id string) (Subscription, error)
+	ListSubscriptions() ([]Subscription, error)
+	DeleteSubscription(id string) error
+	AddDeliveries(deliveries ...Delivery) error
+	GetDelivery(id string) (Delivery, error)
+	ListDeliveries(subscriptionID string) ([]Delivery, error)
+	DueDeliveries(at time.Time, limit int) ([]Delivery, error)
+	ClaimDeliveries(at time.Time, limit int, until time.Time) ([]Delivery, error)
+	UpdateDelivery(d Delivery) error
+}
+
+// InMemoryRepository is a Repository for tests and development.
+type InMemoryRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	deliveries    map[string]Delivery
+	order         []string // Delivery IDs in insertion order, to break ties between equal CreatedAt
+}
+
+// NewInMemoryRepository creates an empty repository.
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		subscriptions: make(map[string]Subscription),
+		deliveries:    make(map[string]Delivery),
+	}
+}
+
+// CreateSubscription stores a new subscription.
+func (r *InMemoryRepository) CreateSubscription(sub Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub.Events = append([]loan.EventType(nil), sub.Events...)
+	r.subscriptions[sub.ID] = sub
+	return nil
+}
+
+// GetSubscription returns a subscription by ID.
+func (r *InMemoryRepository) GetSubscription(id string) (Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub, ok := r.subscriptions[id]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	sub.Events = append([]loan.EventType(nil), sub.Events...)
+	return sub, nil
+}
+
+// ListSubscriptions returns every subscription.
+func (r *InMemoryRepository) ListSubscriptions() ([]Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subs := make([]Subscription, 0, len(r.subscriptions))
+	for _, sub := range r.subscriptions {
+		sub.Events = append([]loan.EventType(nil), sub.Events...)
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs, nil
+}
+
+// DeleteSubscription removes a subscription. Its deliveries are kept.
+func (r *InMemoryRepository) DeleteSubscription(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscriptions[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(r.subscriptions, id)
+	return nil
+}
+
+// AddDeliveries stores new deliveries, skipping those already stored.
+func (r *InMemoryRepository) AddDeliveries(deliveries ...Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range deliveries {
+		if _, exists := r.deliveries[d.ID]; exists {
+			continue
+		}
+		r.deliveries[d.ID] = d
+		r.order = append(r.order, d.ID)
+	}
+	return nil
+}
+
+// GetDelivery returns a delivery by ID.
+func (r *InMemoryRepository) GetDelivery(id string) (Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.deliveries[id]
+	if !ok {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return d, nil
+}
+
+// ListDeliveries returns the deliveries of a subscription.
+func (r *InMemoryRepository) ListDeliveries(subscriptionID string) ([]Delivery, error) {
+	return r.filter(0, func(d Delivery) bool { return d.SubscriptionID == subscriptionID }), nil
+}
+
+// DueDeliveries returns pending deliveries that are ready for another attempt.
+func (r *InMemoryRepository) DueDeliveries(at time.Time, limit int) ([]Delivery, error) {
+	return r.filter(limit, func(d Delivery) bool {
+		return d.Status == outbox.Pending && !d.NextAttemptAt.After(at)
+	}), nil
+}
+
+// ClaimDeliveries returns pending deliveries that are ready for another attempt and postpones them until the given time.
+func (r *InMemoryRepository) ClaimDeliveries(at time.Time, limit int, until time.Time) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := r.matching(limit, func(d Delivery) bool {
+		return d.Status == outbox.Pending && !d.NextAttemptAt.After(at)
+	})
+	for i := range due {
+		due[i].NextAttemptAt = until
+		r.deliveries[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+// UpdateDelivery stores the outcome of an attempt.
+func (r *InMemoryRepository) UpdateDelivery(d Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deliveries[d.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	r.deliveries[d.ID] = d
+	return nil
+}
+
+// filter returns up to limit matching deliveries, oldest first.
+func (r *InMemoryRepository) filter(limit int, match func(Delivery) bool) []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.matching(limit, match)
+}
+
+// matching is filter for callers that hold r.mu.
+func (r *InMemoryRepository) matching(limit int, match func(Delivery) bool) []Delivery {
+	deliveries := []Delivery{}
+	for _, id := range r.order {
+		if d := r.deliveries[id]; match(d) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt) })
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries
+}
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/loan"
+	"loan-service/core/outbox"
+	"loan-service/database"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"ok":true}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "c1afc7c2df3db0690d7d75954610ed1a1d959ce96355ccb8c0a8bc09fd0cfc27",
+		Sign("secret", 1700000000, []byte(`{"ok":true}`)))
+	assert.NotEqual(t, Sign("secret", 1700000000, []byte(`{"ok":true}`)), Sign("secret", 1700000001, []byte(`{"ok":true}`)))
+	assert.NotEqual(t, Sign("secret", 1700000000, []byte(`{"ok":true}`)), Sign("other", 1700000000, []byte(`{"ok":true}`)))
+}
+
+func TestSubscription_Wants(t *testing.T) {
+	sub := Subscription{Events: []loan.EventType{loan.ApprovedEvent, loan.FundedEvent}}
+	assert.True(t, sub.wants(loan.FundedEvent))
+	assert.False(t, sub.wants(loan.DisbursedEvent))
+}
+
+func TestInMemoryRepository(t *testing.T) {
+	testRepository(t, NewInMemoryRepository())
+}
+
+func TestSQLRepository(t *testing.T) {
+	db, err := database.Open("sqlite3", filepath.Join(t.TempDir(), "webhooks.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testRepository(t, NewSQLRepository(db))
+}
+
+// testRepository is the behaviour every Repository implementation must satisfy.
+func testRepository(t *testing.T, repo Repository) {
+	at := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+	first := Subscription{ID: "sub-1", URL: "https://a.example.com/hook", Events: []loan.EventType{loan.ApprovedEvent, loan.FundedEvent}, Secret: "s1", CreatedAt: at}
+	second := Subscription{ID: "sub-2", URL: "https://b.example.com/hook", Events: []loan.EventType{loan.DisbursedEvent}, Secret: "s2", CreatedAt: at.Add(time.Minute)}
+	require.NoError(t, repo.CreateSubscription(second))
+	require.NoError(t, repo.CreateSubscription(first))
+
+	delivery := func(id, sub string, created time.Time) Delivery {
+		return Delivery{ID: id, SubscriptionID: sub, EventID: "evt-" + id, EventType: loan.ApprovedEvent,
+			Payload: []byte(`{"id":"evt-` + id + `"}`), Status: outbox.Pending, NextAttemptAt: created, CreatedAt: created}
+	}
+	d1, d2, later := delivery("d1", "sub-1", at), delivery("d2", "sub-2", at.Add(time.Second)), delivery("d3", "sub-1", at.Add(time.Hour))
+	require.NoError(t, repo.AddDeliveries(later, d1, d2))
+
+	t.Run("Subscriptions round-trip", func(t *testing.T) {
+		got, err := repo.GetSubscription("sub-1")
+		require.NoError(t, err)
+		assert.Equal(t, first.URL, got.URL)
+		assert.Equal(t, first.Events, got.Events)
+		assert.Equal(t, "s1", got.Secret)
+		assert.True(t, at.Equal(got.CreatedAt))
+
+		subs, err := repo.ListSubscriptions()
+		require.NoError(t, err)
+		require.Len(t, subs, 2)
+		assert.Equal(t, []string{"sub-1", "sub-2"}, []string{subs[0].ID, subs[1].ID}, "oldest first")
+
+		_, err = repo.GetSubscription("missing")
+		assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+	})
+
+	t.Run("Due deliveries oldest first", func(t *testing.T) {
+		due, err := repo.DueDeliveries(at.Add(time.Minute), 0)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+		assert.Equal(t, []string{"d1", "d2"}, []string{due[0].ID, due[1].ID})
+		assert.JSONEq(t, `{"id":"evt-d1"}`, string(due[0].Payload))
+
+		due, err = repo.DueDeliveries(at.Add(time.Minute), 1)
+		require.NoError(t, err)
+		assert.Len(t, due, 1)
+	})
+
+	t.Run("Update stores the outcome of an attempt", func(t *testing.T) {
+		deliveredAt := at.Add(time.Minute)
+		d1.Status, d1.Attempts, d1.ResponseCode, d1.DeliveredAt = outbox.Delivered, 2, 204, &deliveredAt
+		require.NoError(t, repo.UpdateDelivery(d1))
+
+		got, err := repo.GetDelivery("d1")
+		require.NoError(t, err)
+		assert.Equal(t, outbox.Delivered, got.Status)
+		assert.Equal(t, 2, got.Attempts)
+		assert.Equal(t, 204, got.ResponseCode)
+		assert.True(t, deliveredAt.Equal(*got.DeliveredAt))
+
+		due, _ := repo.DueDeliveries(at.Add(2*time.Hour), 0)
+		assert.Equal(t, []string{"d2", "d3"}, []string{due[0].ID, due[1].ID})
+
+		assert.ErrorIs(t, repo.UpdateDelivery(Delivery{ID: "missing"}), ErrDeliveryNotFound)
+		_, err = repo.GetDelivery("missing")
+		assert.ErrorIs(t, err, ErrDeliveryNotFound)
+	})
+
+	t.Run("Deliveries are listed per subscription", func(t *testing.T) {
+		list, err := repo.ListDeliveries("sub-1")
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, []string{"d1", "d3"}, []string{list[0].ID, list[1].ID})
+	})
+
+	t.Run("Adding a stored delivery again is ignored", func(t *testing.T) {
+		again := delivery("d3", "sub-1", at)
+		again.Payload = []byte(`{"id":"changed"}`)
+		require.NoError(t, repo.AddDeliveries(again))
+
+		got, err := repo.GetDelivery("d3")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"evt-d3"}`, string(got.Payload))
+		list, _ := repo.ListDeliveries("sub-1")
+		assert.Len(t, list, 2)
+	})
+
+	t.Run("Claimed deliveries are hidden until the lease ends", func(t *testing.T) {
+		lease := at.Add(3 * time.Hour)
+		claimed, err := repo.ClaimDeliveries(at.Add(2*time.Hour), 0, lease)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+		assert.Equal(t, []string{"d2", "d3"}, []string{claimed[0].ID, claimed[1].ID})
+		assert.True(t, lease.Equal(claimed[0].NextAttemptAt))
+
+		claimed, err = repo.ClaimDeliveries(at.Add(2*time.Hour), 0, lease)
+		require.NoError(t, err)
+		assert.Empty(t, claimed, "already claimed")
+
+		claimed, err = repo.ClaimDeliveries(lease, 1, lease.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Len(t, claimed, 1, "claim lapsed")
+	})
+
+	t.Run("Delete keeps deliveries", func(t *testing.T) {
+		require.NoError(t, repo.DeleteSubscription("sub-2"))
+		assert.ErrorIs(t, repo.DeleteSubscription("sub-2"), ErrSubscriptionNotFound)
+
+		list, err := repo.ListDeliveries("sub-2")
+		require.NoError(t, err)
+		assert.Len(t, list, 1)
+	})
+}
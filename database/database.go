@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL flavour spoken by the underlying database/sql driver.
+type Dialect string
+
+const (
+	// SQLite is used for local development and tests (github.com/mattn/go-sqlite3).
+	SQLite Dialect = "sqlite3"
+
+	// Postgres is used in production (github.com/lib/pq).
+	Postgres Dialect = "postgres"
+)
+
+// ParseDialect maps a driver name to a supported Dialect.
+func ParseDialect(driver string) (Dialect, error) {
+	switch Dialect(driver) {
+	case SQLite, Postgres:
+		return Dialect(driver), nil
+	default:
+		return "", fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// Rebind converts a query written with `?` placeholders into the placeholder style of the dialect.
+// Postgres expects positional `$1, $2, ...` placeholders while SQLite accepts `?` as is.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// DB bundles a connection pool with the dialect it speaks.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open opens a connection pool for the given driver and DSN, verifies it is reachable and applies all pending migrations.
+// The caller must import the matching driver package.
+func Open(driver, dsn string) (*DB, error) {
+	dialect, err := ParseDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	// SQLite allows a single writer at a time; serialising connections avoids "database is locked" errors.
+	if dialect == SQLite {
+		conn.SetMaxOpenConns(1)
+	}
+
+	if err := conn.Ping(); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	db := &DB{DB: conn, Dialect: dialect}
+	if err := Migrate(db); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return db, nil
+}
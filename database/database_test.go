@@ -0,0 +1,67 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialectRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{"SQLite keeps question marks", SQLite, "SELECT * FROM loans WHERE id = ? AND state = ?", "SELECT * FROM loans WHERE id = ? AND state = ?"},
+		{"Postgres numbers placeholders", Postgres, "SELECT * FROM loans WHERE id = ? AND state = ?", "SELECT * FROM loans WHERE id = $1 AND state = $2"},
+		{"No placeholders", Postgres, "SELECT 1", "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.dialect.Rebind(tt.query))
+		})
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	d, err := ParseDialect("postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, Postgres, d)
+
+	_, err = ParseDialect("mysql")
+	assert.Error(t, err)
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "migration versions must be contiguous")
+	}
+}
+
+func TestOpenAppliesMigrationsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := Open("sqlite3", path)
+	require.NoError(t, err)
+
+	var applied int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	migrations, _ := Migrations()
+	assert.Equal(t, len(migrations), applied)
+	require.NoError(t, db.Close())
+
+	// Re-opening must not try to re-apply anything.
+	db, err = Open("sqlite3", path)
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+	require.NoError(t, db.Close())
+}
@@ -0,0 +1,39 @@
+CREATE TABLE loans (
+    id                    TEXT PRIMARY KEY,
+    borrower_id           TEXT NOT NULL,
+    principal_amount      DOUBLE PRECISION NOT NULL,
+    rate                  DOUBLE PRECISION NOT NULL,
+    roi                   DOUBLE PRECISION NOT NULL,
+    agreement_letter_link TEXT NOT NULL DEFAULT '',
+    state                 TEXT NOT NULL,
+    total_invested        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at            TIMESTAMP NOT NULL,
+    updated_at            TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_loans_borrower_id ON loans (borrower_id);
+CREATE INDEX idx_loans_state ON loans (state);
+
+CREATE TABLE loan_approvals (
+    loan_id            TEXT PRIMARY KEY REFERENCES loans (id) ON DELETE CASCADE,
+    photo_proof_url    TEXT NOT NULL,
+    field_validator_id TEXT NOT NULL,
+    approval_date      TIMESTAMP NOT NULL
+);
+
+CREATE TABLE loan_disbursements (
+    loan_id               TEXT PRIMARY KEY REFERENCES loans (id) ON DELETE CASCADE,
+    agreement_letter_file TEXT NOT NULL,
+    field_officer_id      TEXT NOT NULL,
+    disbursement_date     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE loan_investors (
+    loan_id     TEXT NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    investor_id TEXT NOT NULL,
+    amount      DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (loan_id, position)
+);
+
+CREATE INDEX idx_loan_investors_investor_id ON loan_investors (investor_id);
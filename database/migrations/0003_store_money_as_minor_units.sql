@@ -0,0 +1,14 @@
+-- Amounts were stored as floating point. Move them to exact integer minor units plus a currency.
+-- Every existing loan is IDR, whose minor unit is 1/100.
+ALTER TABLE loans ADD COLUMN currency TEXT NOT NULL DEFAULT 'IDR';
+ALTER TABLE loans ADD COLUMN principal_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN total_invested_minor BIGINT NOT NULL DEFAULT 0;
+UPDATE loans SET
+    principal_minor = CAST(ROUND(principal_amount * 100) AS BIGINT),
+    total_invested_minor = CAST(ROUND(total_invested * 100) AS BIGINT);
+ALTER TABLE loans DROP COLUMN principal_amount;
+ALTER TABLE loans DROP COLUMN total_invested;
+
+ALTER TABLE loan_investors ADD COLUMN amount_minor BIGINT NOT NULL DEFAULT 0;
+UPDATE loan_investors SET amount_minor = CAST(ROUND(amount * 100) AS BIGINT);
+ALTER TABLE loan_investors DROP COLUMN amount;
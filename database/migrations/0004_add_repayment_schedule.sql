@@ -0,0 +1,12 @@
+ALTER TABLE loans ADD COLUMN repayment_method TEXT NOT NULL DEFAULT 'flat';
+ALTER TABLE loans ADD COLUMN tenor INTEGER NOT NULL DEFAULT 12;
+
+CREATE TABLE loan_installments (
+    loan_id         TEXT NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    number          INTEGER NOT NULL,
+    due_date        TIMESTAMP NOT NULL,
+    principal_minor BIGINT NOT NULL,
+    interest_minor  BIGINT NOT NULL,
+    balance_minor   BIGINT NOT NULL,
+    PRIMARY KEY (loan_id, number)
+);
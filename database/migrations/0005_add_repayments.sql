@@ -0,0 +1,21 @@
+ALTER TABLE loans ADD COLUMN installment_fee_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN outstanding_fees_minor BIGINT;
+ALTER TABLE loans ADD COLUMN outstanding_interest_minor BIGINT;
+ALTER TABLE loans ADD COLUMN outstanding_principal_minor BIGINT;
+
+ALTER TABLE loan_installments ADD COLUMN fee_minor BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE loan_repayments (
+    id              TEXT PRIMARY KEY,
+    loan_id         TEXT NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    position        INTEGER NOT NULL,
+    amount_minor    BIGINT NOT NULL,
+    fees_minor      BIGINT NOT NULL,
+    interest_minor  BIGINT NOT NULL,
+    principal_minor BIGINT NOT NULL,
+    reference       TEXT NOT NULL DEFAULT '',
+    paid_at         TIMESTAMP NOT NULL,
+    recorded_at     TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_loan_repayments_position ON loan_repayments (loan_id, position);
@@ -0,0 +1,14 @@
+CREATE TABLE investor_payouts (
+    id              TEXT PRIMARY KEY,
+    loan_id         TEXT NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    repayment_id    TEXT NOT NULL,
+    investor_id     TEXT NOT NULL,
+    position        INTEGER NOT NULL,
+    currency        TEXT NOT NULL,
+    principal_minor BIGINT NOT NULL,
+    return_minor    BIGINT NOT NULL,
+    created_at      TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_investor_payouts_loan_id ON investor_payouts (loan_id);
+CREATE INDEX idx_investor_payouts_investor_id ON investor_payouts (investor_id);
@@ -0,0 +1,8 @@
+CREATE TABLE loan_closures (
+    loan_id   TEXT PRIMARY KEY REFERENCES loans (id) ON DELETE CASCADE,
+    reason    TEXT NOT NULL DEFAULT '',
+    staff_id  TEXT NOT NULL DEFAULT '',
+    closed_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE loan_investors ADD COLUMN status TEXT NOT NULL DEFAULT 'committed';
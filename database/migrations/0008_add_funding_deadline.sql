@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN funding_deadline TIMESTAMP;
+
+CREATE INDEX idx_loans_funding_deadline ON loans (state, funding_deadline);
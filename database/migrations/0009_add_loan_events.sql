@@ -0,0 +1,13 @@
+CREATE TABLE loan_events (
+    id             TEXT PRIMARY KEY,
+    loan_id        TEXT NOT NULL,
+    loan_version   BIGINT NOT NULL,
+    action         TEXT NOT NULL,
+    actor          TEXT NOT NULL DEFAULT '',
+    previous_state TEXT NOT NULL DEFAULT '',
+    new_state      TEXT NOT NULL,
+    payload        TEXT NOT NULL DEFAULT '',
+    occurred_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_loan_events_loan_id ON loan_events (loan_id, loan_version);
@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys (
+    scope           TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    fingerprint     TEXT NOT NULL,
+    status          INTEGER NOT NULL DEFAULT 0,
+    header          TEXT NOT NULL DEFAULT '',
+    body            TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMP NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
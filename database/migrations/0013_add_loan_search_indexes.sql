@@ -0,0 +1,2 @@
+CREATE INDEX idx_loans_created_at ON loans (created_at, id);
+CREATE INDEX idx_loans_principal_minor ON loans (principal_minor, id);
@@ -0,0 +1,13 @@
+CREATE TABLE investors (
+    id                 TEXT PRIMARY KEY,
+    name               TEXT NOT NULL,
+    email              TEXT NOT NULL DEFAULT '',
+    kyc_status         TEXT NOT NULL,
+    kyc_reviewed_at    TIMESTAMP,
+    limit_currency     TEXT NOT NULL DEFAULT '',
+    exposure_cap_minor BIGINT,
+    min_ticket_minor   BIGINT,
+    max_ticket_minor   BIGINT,
+    created_at         TIMESTAMP NOT NULL,
+    updated_at         TIMESTAMP NOT NULL
+);
@@ -0,0 +1,12 @@
+CREATE TABLE borrowers (
+    id                    TEXT PRIMARY KEY,
+    identity_number       TEXT NOT NULL UNIQUE,
+    name                  TEXT NOT NULL,
+    email                 TEXT NOT NULL DEFAULT '',
+    phone                 TEXT NOT NULL DEFAULT '',
+    address               TEXT NOT NULL DEFAULT '',
+    credit_limit_minor    BIGINT,
+    credit_limit_currency TEXT NOT NULL DEFAULT '',
+    created_at            TIMESTAMP NOT NULL,
+    updated_at            TIMESTAMP NOT NULL
+);
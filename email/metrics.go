@@ -0,0 +1,59 @@
+package email
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"loan-service/core/loan"
+)
+
+// CountingSender is an EmailSender counting the emails another one fails to send in
+// email_send_failures_total, by template.
+type CountingSender struct {
+	sender   loan.EmailSender
+	failures *prometheus.CounterVec
+}
+
+// CountFailures wraps sender, adding its failure counter to registry.
+func CountFailures(sender loan.EmailSender, registry prometheus.Registerer) *CountingSender {
+	failures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_send_failures_total",
+		Help: "Emails that could not be sent, by template.",
+	}, []string{"template"})
+	registry.MustRegister(failures)
+	for _, template := range []string{loanApprovedTemplate, investmentReceivedTemplate, loanFundedTemplate,
+		loanDisbursedTemplate, fundingExpiredTemplate} {
+		failures.WithLabelValues(template)
+	}
+	return &CountingSender{sender: sender, failures: failures}
+}
+
+// SendLoanApproved implements loan.EmailSender.
+func (s *CountingSender) SendLoanApproved(event loan.LoanApproved) error {
+	return s.count(loanApprovedTemplate, s.sender.SendLoanApproved(event))
+}
+
+// SendInvestmentReceived implements loan.EmailSender.
+func (s *CountingSender) SendInvestmentReceived(event loan.InvestmentReceived) error {
+	return s.count(investmentReceivedTemplate, s.sender.SendInvestmentReceived(event))
+}
+
+// SendLoanFunded implements loan.EmailSender.
+func (s *CountingSender) SendLoanFunded(event loan.LoanFunded) error {
+	return s.count(loanFundedTemplate, s.sender.SendLoanFunded(event))
+}
+
+// SendLoanDisbursed implements loan.EmailSender.
+func (s *CountingSender) SendLoanDisbursed(event loan.LoanDisbursed) error {
+	return s.count(loanDisbursedTemplate, s.sender.SendLoanDisbursed(event))
+}
+
+// SendFundingExpired implements loan.EmailSender.
+func (s *CountingSender) SendFundingExpired(event loan.FundingExpired) error {
+	return s.count(fundingExpiredTemplate, s.sender.SendFundingExpired(event))
+}
+
+func (s *CountingSender) count(template string, err error) error {
+	if err != nil {
+		s.failures.WithLabelValues(template).Inc()
+	}
+	return err
+}
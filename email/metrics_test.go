@@ -0,0 +1,30 @@
+package email
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"loan-service/core/loan"
+)
+
+// failingSender fails to send LoanFunded emails and logs the others.
+type failingSender struct{ *MockEmailSender }
+
+func (*failingSender) SendLoanFunded(loan.LoanFunded) error { return errors.New("connection refused") }
+
+func TestCountFailures(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	sender := CountFailures(&failingSender{NewMockEmailSender()}, registry)
+	event := loan.Notification{Recipient: loan.Recipient{Role: loan.InvestorRecipient, ID: "INV001"}, Loan: fundedLoan()}
+
+	assert.NoError(t, sender.SendLoanApproved(loan.LoanApproved{Notification: event}))
+	assert.EqualError(t, sender.SendLoanFunded(loan.LoanFunded{Notification: event}), "connection refused")
+	assert.Error(t, sender.SendLoanFunded(loan.LoanFunded{Notification: event}))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(sender.failures.WithLabelValues(loanFundedTemplate)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(sender.failures.WithLabelValues(loanApprovedTemplate)))
+	assert.Equal(t, 5, testutil.CollectAndCount(sender.failures), "every template shows up before anything fails")
+}
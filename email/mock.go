@@ -3,24 +3,82 @@ package email
 import (
 	"fmt"
 	"log"
+	"sync"
+
+	"loan-service/core/loan"
 )
 
+// MockCall is one email "sent" by MockEmailSender.
+type MockCall struct {
+	Method    string         // EmailSender method that was called, e.g. "SendLoanApproved"
+	Recipient loan.Recipient // Who the email was for
+	LoanID    string         // Loan the email was about
+	Event     any            // The event passed in, e.g. a loan.LoanApproved
+}
+
 // MockEmailSender simulates sending emails by printing to stdout.
 // Useful for testing and development without real SMTP or API services.
-type MockEmailSender struct{}
+// Every call is recorded, so tests can assert on what would have been sent.
+type MockEmailSender struct {
+	mu    sync.Mutex
+	calls []MockCall
+}
 
 // NewMockEmailSender creates a new instance of MockEmailSender.
 func NewMockEmailSender() *MockEmailSender {
 	return &MockEmailSender{}
 }
 
-// SendInvestorNotification logs an email sent to an investor containing the agreement link.
+// Calls returns the recorded calls in the order they were made.
+func (m *MockEmailSender) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+// SendLoanApproved logs an email telling the borrower their loan was approved.
+func (m *MockEmailSender) SendLoanApproved(event loan.LoanApproved) error {
+	m.record("SendLoanApproved", event.Notification, event, "loan %s was approved", event.Loan.ID)
+	return nil
+}
+
+// SendInvestmentReceived logs an email confirming an investment to the investor.
+func (m *MockEmailSender) SendInvestmentReceived(event loan.InvestmentReceived) error {
+	m.record("SendInvestmentReceived", event.Notification, event, "investment of %s in loan %s was received", event.Amount, event.Loan.ID)
+	return nil
+}
+
+// SendLoanFunded logs an email telling staff or an investor that a loan is fully funded,
+// including the agreement letter link.
 //
 // Example log:
 //
-//	[EMAIL] Sent an agreement link to investor INV001: https://agreement-link.com/doc.pdf
-func (m *MockEmailSender) SendInvestorNotification(investorID, agreementLink string) error {
-	log.Printf("[EMAIL] Sent agreement link to investor %s: %s", investorID, agreementLink)
-	fmt.Printf("[EMAIL] Sent agreement link to investor %s: %s\n", investorID, agreementLink)
+//	[EMAIL] Sent to investor INV001: loan LOAN001 is fully funded, agreement letter: https://agreement-link.com/doc.pdf
+func (m *MockEmailSender) SendLoanFunded(event loan.LoanFunded) error {
+	m.record("SendLoanFunded", event.Notification, event, "loan %s is fully funded, agreement letter: %s", event.Loan.ID, event.Loan.AgreementLetterURL)
+	return nil
+}
+
+// SendLoanDisbursed logs an email telling the borrower or an investor that a loan was disbursed.
+func (m *MockEmailSender) SendLoanDisbursed(event loan.LoanDisbursed) error {
+	m.record("SendLoanDisbursed", event.Notification, event, "loan %s was disbursed", event.Loan.ID)
+	return nil
+}
+
+// SendFundingExpired logs an email telling an investor that a loan expired unfunded
+// and their committed money was released.
+func (m *MockEmailSender) SendFundingExpired(event loan.FundingExpired) error {
+	m.record("SendFundingExpired", event.Notification, event, "loan %s expired unfunded", event.Loan.ID)
 	return nil
 }
+
+// record stores a call and logs a one-line summary of the email.
+func (m *MockEmailSender) record(method string, n loan.Notification, event any, format string, args ...any) {
+	m.mu.Lock()
+	m.calls = append(m.calls, MockCall{Method: method, Recipient: n.Recipient, LoanID: n.Loan.ID, Event: event})
+	m.mu.Unlock()
+
+	line := fmt.Sprintf("[EMAIL] Sent to %s %s: ", n.Recipient.Role, n.Recipient.ID) + fmt.Sprintf(format, args...)
+	log.Print(line)
+	fmt.Println(line)
+}
@@ -4,14 +4,43 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"loan-service/core/loan"
+	"loan-service/core/money"
 )
 
-func TestMockEmailSender_SendInvestorNotification(t *testing.T) {
+func TestMockEmailSender_RecordsCalls(t *testing.T) {
 	sender := NewMockEmailSender()
+	ln := loan.Loan{ID: "LOAN001", BorrowerID: "B001", AgreementLetterURL: "AGREEMENT"}
+	borrower := loan.Recipient{Role: loan.BorrowerRecipient, ID: "B001"}
+	investor := loan.Recipient{Role: loan.InvestorRecipient, ID: "INV001"}
+	staff := loan.Recipient{Role: loan.StaffRecipient, ID: "EMP001"}
 
-	investorID := "INV001"
-	agreementLink := "AGREEMENT"
+	received := loan.InvestmentReceived{
+		Notification: loan.Notification{Recipient: investor, Loan: ln},
+		Amount:       money.New(50000, money.IDR),
+	}
+	assert.NoError(t, sender.SendLoanApproved(loan.LoanApproved{Notification: loan.Notification{Recipient: borrower, Loan: ln}}))
+	assert.NoError(t, sender.SendInvestmentReceived(received))
+	assert.NoError(t, sender.SendLoanFunded(loan.LoanFunded{Notification: loan.Notification{Recipient: staff, Loan: ln}}))
+	assert.NoError(t, sender.SendLoanDisbursed(loan.LoanDisbursed{Notification: loan.Notification{Recipient: investor, Loan: ln}}))
+	assert.NoError(t, sender.SendFundingExpired(loan.FundingExpired{Notification: loan.Notification{Recipient: investor, Loan: ln}}))
 
-	err := sender.SendInvestorNotification(investorID, agreementLink)
-	assert.NoError(t, err)
+	calls := sender.Calls()
+	if assert.Len(t, calls, 5) {
+		assert.Equal(t, []string{"SendLoanApproved", "SendInvestmentReceived", "SendLoanFunded", "SendLoanDisbursed", "SendFundingExpired"},
+			[]string{calls[0].Method, calls[1].Method, calls[2].Method, calls[3].Method, calls[4].Method})
+		assert.Equal(t, borrower, calls[0].Recipient)
+		assert.Equal(t, staff, calls[2].Recipient)
+		assert.Equal(t, "LOAN001", calls[1].LoanID)
+		assert.Equal(t, received, calls[1].Event)
+	}
+}
+
+func TestMockEmailSender_CallsIsACopy(t *testing.T) {
+	sender := NewMockEmailSender()
+	_ = sender.SendLoanApproved(loan.LoanApproved{})
+
+	calls := sender.Calls()
+	calls[0].Method = "changed"
+	assert.Equal(t, "SendLoanApproved", sender.Calls()[0].Method)
 }
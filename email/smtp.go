@@ -0,0 +1,304 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"loan-service/core/loan"
+	"loan-service/core/money"
+)
+
+// SMTPConfig tells SMTPSender how to reach the mail server.
+type SMTPConfig struct {
+	Addr       string        // host:port of the SMTP server
+	Username   string        // Enables PLAIN authentication when set
+	Password   string        // Password for Username
+	From       string        // Sender, e.g. "Loans <no-reply@example.com>"
+	RequireTLS bool          // Refuse to send if the server does not offer STARTTLS
+	TLSConfig  *tls.Config   // Used for STARTTLS; defaults to verifying the server against the host name
+	Timeout    time.Duration // Limit for a whole delivery; defaults to 10 seconds
+}
+
+// AddressBook finds the email address of a notification recipient.
+type AddressBook interface {
+	Address(to loan.Recipient) (string, error)
+}
+
+// AddressFormats is an AddressBook that derives addresses from recipient IDs with a fmt format per
+// role, e.g. {loan.InvestorRecipient: "%s@investors.example.com"}.
+type AddressFormats map[loan.RecipientRole]string
+
+// Address implements AddressBook.
+func (f AddressFormats) Address(to loan.Recipient) (string, error) {
+	format, ok := f[to.Role]
+	if !ok {
+		return "", fmt.Errorf("no address format for %s recipients", to.Role)
+	}
+	return fmt.Sprintf(format, to.ID), nil
+}
+
+// TemplateData is what email templates are rendered with.
+type TemplateData struct {
+	Recipient  loan.Recipient
+	Address    string       // Email address the message is sent to
+	Loan       loan.Loan    // The loan the email is about
+	Investment *money.Money // What the recipient put into the loan; nil unless they are an investor
+	Amount     *money.Money // The amount just invested; only set in investment_received emails
+}
+
+// SMTPSender is the production EmailSender. It renders messages with Templates and delivers them
+// over SMTP, upgrading the connection with STARTTLS when the server offers it.
+type SMTPSender struct {
+	cfg       SMTPConfig
+	from      *mail.Address
+	addresses AddressBook
+	templates *Templates
+	now       func() time.Time
+}
+
+// SMTPOption configures optional parts of an SMTPSender.
+type SMTPOption func(*SMTPSender)
+
+// WithTemplates replaces the built-in templates.
+func WithTemplates(t *Templates) SMTPOption {
+	return func(s *SMTPSender) {
+		s.templates = t
+	}
+}
+
+// NewSMTPSender creates a sender for the given server. Recipients' addresses come from addresses.
+func NewSMTPSender(cfg SMTPConfig, addresses AddressBook, opts ...SMTPOption) (*SMTPSender, error) {
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", cfg.Addr, err)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", cfg.From, err)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	s := &SMTPSender{
+		cfg:       cfg,
+		from:      from,
+		addresses: addresses,
+		templates: DefaultTemplates(),
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// SendLoanApproved emails the borrower that their loan was approved.
+func (s *SMTPSender) SendLoanApproved(event loan.LoanApproved) error {
+	return s.sendEvent(loanApprovedTemplate, event.Notification, nil)
+}
+
+// SendInvestmentReceived emails an investor to confirm their investment.
+func (s *SMTPSender) SendInvestmentReceived(event loan.InvestmentReceived) error {
+	return s.sendEvent(investmentReceivedTemplate, event.Notification, &event.Amount)
+}
+
+// SendLoanFunded emails staff or an investor that a loan is fully funded.
+func (s *SMTPSender) SendLoanFunded(event loan.LoanFunded) error {
+	return s.sendEvent(loanFundedTemplate, event.Notification, nil)
+}
+
+// SendLoanDisbursed emails the borrower or an investor that a loan was disbursed.
+func (s *SMTPSender) SendLoanDisbursed(event loan.LoanDisbursed) error {
+	return s.sendEvent(loanDisbursedTemplate, event.Notification, nil)
+}
+
+// SendFundingExpired emails an investor that a loan expired unfunded and their money was released.
+func (s *SMTPSender) SendFundingExpired(event loan.FundingExpired) error {
+	return s.sendEvent(fundingExpiredTemplate, event.Notification, nil)
+}
+
+// sendEvent renders the named template for the notification's recipient and delivers it.
+func (s *SMTPSender) sendEvent(template string, n loan.Notification, amount *money.Money) error {
+	address, err := s.addresses.Address(n.Recipient)
+	if err != nil {
+		return fmt.Errorf("look up address of %s %s: %w", n.Recipient.Role, n.Recipient.ID, err)
+	}
+
+	data := TemplateData{Recipient: n.Recipient, Address: address, Loan: n.Loan, Amount: amount}
+	if n.Recipient.Role == loan.InvestorRecipient {
+		// Withdrawn entries were handed back before funding ended; everything else is still, or was, at stake.
+		total := money.Zero(n.Loan.PrincipalAmount.Currency())
+		for _, inv := range n.Loan.Investors {
+			if inv.ID == n.Recipient.ID && inv.Status != loan.Withdrawn {
+				total = total.Add(inv.Amount)
+			}
+		}
+		data.Investment = &total
+	}
+
+	rendered, err := s.templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	return s.send(address, rendered)
+}
+
+// send builds a multipart/alternative message and delivers it to a single recipient.
+func (s *SMTPSender) send(to string, r Rendered) error {
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", to, err)
+	}
+	msg, err := s.compose(rcpt, r)
+	if err != nil {
+		return err
+	}
+	return s.deliver(rcpt.Address, msg)
+}
+
+// compose renders the MIME message: headers plus a plain-text and an HTML alternative.
+func (s *SMTPSender) compose(to *mail.Address, r Rendered) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, alt := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", r.Text},
+		{"text/html; charset=utf-8", r.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alt.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(alt.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+	var msg bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", s.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", r.Subject)},
+		{"Date", s.now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + uuid.NewString() + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `multipart/alternative; boundary="` + parts.Boundary() + `"`},
+	} {
+		msg.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Ping checks that the server can be reached and accepts the sender's TLS settings and credentials,
+// without sending anything. The check gives up when ctx is done or after the configured timeout.
+func (s *SMTPSender) Ping(ctx context.Context) error {
+	c, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	if err := c.Noop(); err != nil {
+		return fmt.Errorf("SMTP NOOP: %w", err)
+	}
+	return c.Quit()
+}
+
+// deliver runs one SMTP session and sends msg to a single recipient.
+func (s *SMTPSender) deliver(to string, msg []byte) error {
+	c, err := s.connect(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP RCPT TO %s: %w", to, err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+	return c.Quit()
+}
+
+// connect opens a session ready for commands: STARTTLS if available, AUTH if configured.
+// The whole session must be over by the configured timeout, or by ctx's deadline if that is sooner.
+func (s *SMTPSender) connect(ctx context.Context) (*smtp.Client, error) {
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	host, _, _ := net.SplitHostPort(s.cfg.Addr)
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to SMTP server: %w", err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("start SMTP session: %w", err)
+	}
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConfig := &tls.Config{ServerName: host}
+		if s.cfg.TLSConfig != nil {
+			tlsConfig = s.cfg.TLSConfig.Clone()
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("STARTTLS: %w", err)
+		}
+	} else if s.cfg.RequireTLS {
+		_ = c.Close()
+		return nil, errors.New("SMTP server does not support STARTTLS")
+	}
+
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, host)); err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,394 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/loan"
+	"loan-service/core/money"
+)
+
+// receivedMail is one message accepted by fakeSMTPServer.
+type receivedMail struct {
+	From string
+	To   []string
+	Data []byte
+	TLS  bool   // Whether the session was upgraded with STARTTLS before DATA
+	Auth string // Decoded AUTH PLAIN credentials, "" if the client did not authenticate
+}
+
+// fakeSMTPServer is a minimal in-process SMTP server speaking just enough ESMTP for net/smtp.
+type fakeSMTPServer struct {
+	ln       net.Listener
+	tls      *tls.Config // nil disables STARTTLS
+	mu       sync.Mutex
+	received []receivedMail
+}
+
+func newFakeSMTPServer(t *testing.T, tlsConfig *tls.Config) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTPServer{ln: ln, tls: tlsConfig}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) Addr() string { return s.ln.Addr().String() }
+
+func (s *fakeSMTPServer) Received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.received...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	tp := textproto.NewConn(conn)
+	var (
+		current receivedMail
+		secure  bool
+	)
+	reply := func(format string, args ...any) { _ = tp.PrintfLine(format, args...) }
+
+	reply("220 fake ESMTP ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.tls != nil && !secure {
+				reply("250-fake")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-fake")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 go ahead")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, secure = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			_, initial, _ := strings.Cut(arg, " ")
+			creds, err := base64.StdEncoding.DecodeString(initial)
+			if err != nil {
+				reply("501 bad credentials")
+				continue
+			}
+			current.Auth = string(creds)
+			reply("235 authenticated")
+		case "MAIL":
+			current.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			current.To = append(current.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end with .")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data, current.TLS = data, secure
+			s.mu.Lock()
+			s.received = append(s.received, current)
+			s.mu.Unlock()
+			current = receivedMail{Auth: current.Auth}
+			reply("250 queued")
+		case "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// selfSignedTLS returns a server config with a fresh certificate for 127.0.0.1 and a client
+// config that trusts it.
+func selfSignedTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+	return server, client
+}
+
+// parsedMail is a received message split into headers and decoded alternatives.
+type parsedMail struct {
+	Header mail.Header
+	Parts  map[string]string // Decoded body by media type
+}
+
+func parseMail(t *testing.T, data []byte) parsedMail {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parsed := parsedMail{Header: msg.Header, Parts: make(map[string]string)}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(part) // quoted-printable is decoded by multipart.Reader
+		require.NoError(t, err)
+		parsed.Parts[partType] = string(body)
+	}
+	return parsed
+}
+
+func fundedLoan() loan.Loan {
+	return loan.Loan{
+		ID:                 "LOAN001",
+		BorrowerID:         "BORROWER1",
+		PrincipalAmount:    money.New(100000, money.IDR),
+		Rate:               10,
+		ROI:                8,
+		AgreementLetterURL: "https://example.com/agreements/LOAN001.pdf",
+		Investors: []loan.Investor{
+			{ID: "INV001", Amount: money.New(30000, money.IDR), Status: loan.Committed},
+			{ID: "INV002", Amount: money.New(60000, money.IDR), Status: loan.Committed},
+			{ID: "INV001", Amount: money.New(10000, money.IDR), Status: loan.Committed},
+			{ID: "INV001", Amount: money.New(5000, money.IDR), Status: loan.Withdrawn},
+		},
+	}
+}
+
+var testAddresses = AddressFormats{
+	loan.BorrowerRecipient: "%s@borrowers.example.com",
+	loan.InvestorRecipient: "%s@investors.example.com",
+	loan.StaffRecipient:    "%s@staff.example.com",
+}
+
+func TestSMTPSender_SendLoanFunded(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+	server := newFakeSMTPServer(t, serverTLS)
+
+	sender, err := NewSMTPSender(SMTPConfig{
+		Addr:       server.Addr(),
+		Username:   "mailer",
+		Password:   "secret",
+		From:       "Loans <no-reply@loans.example.com>",
+		RequireTLS: true,
+		TLSConfig:  clientTLS,
+	}, testAddresses)
+	require.NoError(t, err)
+
+	event := loan.LoanFunded{Notification: loan.Notification{
+		Recipient: loan.Recipient{Role: loan.InvestorRecipient, ID: "INV001"},
+		Loan:      fundedLoan(),
+	}}
+	require.NoError(t, sender.SendLoanFunded(event))
+
+	received := server.Received()
+	require.Len(t, received, 1)
+	got := received[0]
+	assert.True(t, got.TLS)
+	assert.Equal(t, "\x00mailer\x00secret", got.Auth)
+	assert.Equal(t, "no-reply@loans.example.com", got.From)
+	assert.Equal(t, []string{"INV001@investors.example.com"}, got.To)
+
+	msg := parseMail(t, got.Data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Loan LOAN001 is fully funded", subject)
+	assert.Equal(t, `"Loans" <no-reply@loans.example.com>`, msg.Header.Get("From"))
+	assert.Equal(t, "<INV001@investors.example.com>", msg.Header.Get("To"))
+	assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@loans.example.com>"))
+	_, err = msg.Header.Date()
+	assert.NoError(t, err)
+
+	text := msg.Parts["text/plain"]
+	assert.Contains(t, text, "Hello INV001")
+	assert.Contains(t, text, "BORROWER1")
+	assert.Contains(t, text, "You invested IDR 400.00 in total.", "withdrawn entries are not counted")
+	assert.Contains(t, text, "https://example.com/agreements/LOAN001.pdf")
+	assert.Contains(t, msg.Parts["text/html"], `<a href="https://example.com/agreements/LOAN001.pdf">`)
+}
+
+func TestSMTPSender_SendsEveryEventToItsRecipient(t *testing.T) {
+	server := newFakeSMTPServer(t, nil)
+	sender, err := NewSMTPSender(SMTPConfig{Addr: server.Addr(), From: "no-reply@loans.example.com"}, testAddresses)
+	require.NoError(t, err)
+
+	ln := fundedLoan()
+	to := func(role loan.RecipientRole, id string) loan.Notification {
+		return loan.Notification{Recipient: loan.Recipient{Role: role, ID: id}, Loan: ln}
+	}
+	require.NoError(t, sender.SendLoanApproved(loan.LoanApproved{Notification: to(loan.BorrowerRecipient, "BORROWER1")}))
+	require.NoError(t, sender.SendInvestmentReceived(loan.InvestmentReceived{Notification: to(loan.InvestorRecipient, "INV002"), Amount: money.New(60000, money.IDR)}))
+	require.NoError(t, sender.SendLoanFunded(loan.LoanFunded{Notification: to(loan.StaffRecipient, "EMP001")}))
+	require.NoError(t, sender.SendLoanDisbursed(loan.LoanDisbursed{Notification: to(loan.BorrowerRecipient, "BORROWER1")}))
+	require.NoError(t, sender.SendFundingExpired(loan.FundingExpired{Notification: to(loan.InvestorRecipient, "INV002")}))
+
+	received := server.Received()
+	require.Len(t, received, 5)
+	var recipients, subjects []string
+	for _, r := range received {
+		assert.False(t, r.TLS)
+		assert.Empty(t, r.Auth)
+		recipients = append(recipients, r.To...)
+		subject, err := new(mime.WordDecoder).DecodeHeader(parseMail(t, r.Data).Header.Get("Subject"))
+		require.NoError(t, err)
+		subjects = append(subjects, subject)
+	}
+	assert.Equal(t, []string{
+		"BORROWER1@borrowers.example.com",
+		"INV002@investors.example.com",
+		"EMP001@staff.example.com",
+		"BORROWER1@borrowers.example.com",
+		"INV002@investors.example.com",
+	}, recipients)
+	assert.Equal(t, []string{
+		"Your loan LOAN001 was approved",
+		"We received your investment in loan LOAN001",
+		"Loan LOAN001 is fully funded",
+		"Loan LOAN001 has been disbursed",
+		"Loan LOAN001 was not funded in time",
+	}, subjects)
+}
+
+func TestSMTPSender_RequireTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, nil)
+
+	sender, err := NewSMTPSender(SMTPConfig{
+		Addr:       server.Addr(),
+		From:       "no-reply@loans.example.com",
+		RequireTLS: true,
+	}, testAddresses)
+	require.NoError(t, err)
+
+	err = sender.SendFundingExpired(loan.FundingExpired{Notification: loan.Notification{
+		Recipient: loan.Recipient{Role: loan.InvestorRecipient, ID: "INV001"},
+		Loan:      fundedLoan(),
+	}})
+	assert.EqualError(t, err, "SMTP server does not support STARTTLS")
+	assert.Empty(t, server.Received())
+}
+
+func TestSMTPSender_Ping(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+	server := newFakeSMTPServer(t, serverTLS)
+	plain := newFakeSMTPServer(t, nil)
+
+	sender, err := NewSMTPSender(SMTPConfig{
+		Addr:       server.Addr(),
+		Username:   "mailer",
+		Password:   "secret",
+		From:       "no-reply@loans.example.com",
+		RequireTLS: true,
+		TLSConfig:  clientTLS,
+	}, testAddresses)
+	require.NoError(t, err)
+	assert.NoError(t, sender.Ping(context.Background()))
+	assert.Empty(t, server.Received())
+
+	insecure, err := NewSMTPSender(SMTPConfig{Addr: plain.Addr(), From: "no-reply@loans.example.com", RequireTLS: true}, testAddresses)
+	require.NoError(t, err)
+	assert.EqualError(t, insecure.Ping(context.Background()), "SMTP server does not support STARTTLS")
+
+	addr := plain.Addr()
+	require.NoError(t, plain.ln.Close())
+	down, err := NewSMTPSender(SMTPConfig{Addr: addr, From: "no-reply@loans.example.com"}, testAddresses)
+	require.NoError(t, err)
+	assert.ErrorContains(t, down.Ping(context.Background()), "connect to SMTP server")
+}
+
+func TestSMTPSender_UnknownRecipientRole(t *testing.T) {
+	server := newFakeSMTPServer(t, nil)
+	sender, err := NewSMTPSender(SMTPConfig{Addr: server.Addr(), From: "no-reply@loans.example.com"},
+		AddressFormats{loan.InvestorRecipient: "%s@investors.example.com"})
+	require.NoError(t, err)
+
+	err = sender.SendLoanApproved(loan.LoanApproved{Notification: loan.Notification{
+		Recipient: loan.Recipient{Role: loan.BorrowerRecipient, ID: "B001"},
+		Loan:      fundedLoan(),
+	}})
+	assert.EqualError(t, err, "look up address of borrower B001: no address format for borrower recipients")
+	assert.Empty(t, server.Received())
+}
+
+func TestNewSMTPSender_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SMTPConfig
+		wantErr string
+	}{
+		{"missing port", SMTPConfig{Addr: "smtp.example.com", From: "a@example.com"}, "invalid SMTP address"},
+		{"invalid sender", SMTPConfig{Addr: "smtp.example.com:587", From: "not an address"}, "invalid sender address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSMTPSender(tt.cfg, testAddresses)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// Names of the templates the SMTP sender renders.
+const (
+	loanApprovedTemplate       = "loan_approved"
+	investmentReceivedTemplate = "investment_received"
+	loanFundedTemplate         = "loan_funded"
+	loanDisbursedTemplate      = "loan_disbursed"
+	fundingExpiredTemplate     = "funding_expired"
+)
+
+// Templates renders email bodies.
+//
+// Every message has a NAME.txt file, a text/template holding the plain-text body that also defines
+// a "subject" template, and a NAME.html file, an html/template holding the HTML body.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// Rendered is the output of Templates.Render.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// DefaultTemplates returns the templates shipped with the package.
+func DefaultTemplates() *Templates {
+	sub, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		panic(err)
+	}
+	t, err := ParseTemplates(sub)
+	if err != nil {
+		panic("email: invalid built-in templates: " + err.Error())
+	}
+	return t
+}
+
+// ParseTemplates loads every NAME.txt / NAME.html pair found at the root of fsys.
+func ParseTemplates(fsys fs.FS) (*Templates, error) {
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	files, err := fs.Glob(fsys, "*.txt")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+
+		text, err := texttemplate.ParseFS(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("parse %s: no subject template defined", file)
+		}
+		html, err := htmltemplate.ParseFS(fsys, name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parse %s.html: %w", name, err)
+		}
+
+		t.text[name] = text
+		t.html[name] = html
+	}
+	return t, nil
+}
+
+// Render executes the named message templates with data.
+func (t *Templates) Render(name string, data any) (Rendered, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("no email template named %q", name)
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, name+".txt", data); err != nil {
+		return Rendered{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := t.html[name].ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Rendered{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+
+	return Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    body.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,140 @@
+package email
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"loan-service/core/loan"
+	"loan-service/core/money"
+)
+
+func TestDefaultTemplates_Render(t *testing.T) {
+	invested := money.New(40000, money.IDR)
+	data := TemplateData{
+		Recipient: loan.Recipient{Role: loan.InvestorRecipient, ID: "INV001"},
+		Loan: loan.Loan{
+			ID:                 "LOAN001",
+			BorrowerID:         "B<1>",
+			PrincipalAmount:    money.New(100000, money.IDR),
+			Rate:               10,
+			ROI:                8,
+			AgreementLetterURL: "https://example.com/a?x=1&y=2",
+		},
+		Investment: &invested,
+	}
+
+	r, err := DefaultTemplates().Render(loanFundedTemplate, data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Loan LOAN001 is fully funded", r.Subject)
+	assert.Contains(t, r.Text, "Hello INV001")
+	assert.Contains(t, r.Text, "you invested in is now fully funded")
+	assert.Contains(t, r.Text, "Borrower:    B<1>")
+	assert.Contains(t, r.Text, "You invested IDR 400.00 in total.")
+	assert.Contains(t, r.Text, "https://example.com/a?x=1&y=2")
+	assert.Contains(t, r.HTML, "B&lt;1&gt;")
+	assert.Contains(t, r.HTML, `href="https://example.com/a?x=1&amp;y=2"`)
+}
+
+func TestDefaultTemplates_RenderEveryEvent(t *testing.T) {
+	deadline := time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)
+	outstanding := loan.Balance{Principal: money.New(100000, money.IDR), Interest: money.New(10000, money.IDR)}
+	amount := money.New(25000, money.IDR)
+	ln := loan.Loan{
+		ID:              "LOAN001",
+		BorrowerID:      "B001",
+		PrincipalAmount: money.New(100000, money.IDR),
+		TotalInvested:   money.New(25000, money.IDR),
+		FundingDeadline: &deadline,
+		Outstanding:     &outstanding,
+	}
+	borrower := loan.Recipient{Role: loan.BorrowerRecipient, ID: "B001"}
+	investor := loan.Recipient{Role: loan.InvestorRecipient, ID: "INV001"}
+	staff := loan.Recipient{Role: loan.StaffRecipient, ID: "EMP001"}
+
+	tests := []struct {
+		template string
+		data     TemplateData
+		subject  string
+		text     string
+	}{
+		{loanApprovedTemplate, TemplateData{Recipient: borrower, Loan: ln}, "Your loan LOAN001 was approved", "fully funded by 30 September 2025"},
+		{investmentReceivedTemplate, TemplateData{Recipient: investor, Loan: ln, Investment: &amount, Amount: &amount}, "We received your investment in loan LOAN001", "Funded so far: IDR 250.00"},
+		{loanFundedTemplate, TemplateData{Recipient: staff, Loan: ln}, "Loan LOAN001 is fully funded", "you approved is now fully funded"},
+		{loanDisbursedTemplate, TemplateData{Recipient: borrower, Loan: ln}, "Loan LOAN001 has been disbursed", "You owe IDR 1100.00 in total"},
+		{loanDisbursedTemplate, TemplateData{Recipient: investor, Loan: ln, Investment: &amount}, "Loan LOAN001 has been disbursed", "returns on your investment of IDR 250.00"},
+		{fundingExpiredTemplate, TemplateData{Recipient: investor, Loan: ln}, "Loan LOAN001 was not funded in time", "Your commitment has been released."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+" to "+string(tt.data.Recipient.Role), func(t *testing.T) {
+			r, err := DefaultTemplates().Render(tt.template, tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.subject, r.Subject)
+			assert.Contains(t, r.Text, tt.text)
+			assert.Contains(t, r.HTML, "LOAN001")
+		})
+	}
+}
+
+func TestTemplates_RenderUnknown(t *testing.T) {
+	_, err := DefaultTemplates().Render("missing", nil)
+	assert.EqualError(t, err, `no email template named "missing"`)
+}
+
+func TestParseTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "valid pair",
+			files: fstest.MapFS{
+				"hello.txt":  {Data: []byte(`{{define "subject"}}Hi{{end}}Hello`)},
+				"hello.html": {Data: []byte(`<p>Hello</p>`)},
+			},
+		},
+		{
+			name: "missing subject",
+			files: fstest.MapFS{
+				"hello.txt":  {Data: []byte(`Hello`)},
+				"hello.html": {Data: []byte(`<p>Hello</p>`)},
+			},
+			wantErr: "parse hello.txt: no subject template defined",
+		},
+		{
+			name: "missing html",
+			files: fstest.MapFS{
+				"hello.txt": {Data: []byte(`{{define "subject"}}Hi{{end}}Hello`)},
+			},
+			wantErr: "parse hello.html",
+		},
+		{
+			name: "syntax error",
+			files: fstest.MapFS{
+				"hello.txt":  {Data: []byte(`{{define "subject"}}Hi{{end}}{{.Oops`)},
+				"hello.html": {Data: []byte(`<p>Hello</p>`)},
+			},
+			wantErr: "parse hello.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplates(tt.files)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			r, err := tmpl.Render("hello", nil)
+			require.NoError(t, err)
+			assert.Equal(t, Rendered{Subject: "Hi", Text: "Hello", HTML: "<p>Hello</p>"}, r)
+		})
+	}
+}